@@ -14,7 +14,6 @@ type User = {
     id: string;
     name: string;
     username: string;
-    md5Password: string;
     picture: string;
 };
 
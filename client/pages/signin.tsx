@@ -2,7 +2,6 @@ import * as React from 'react';
 import Typography from '@mui/material/Typography';
 import { Alert, Box, Button, Card, CardContent, CardMedia, Checkbox, FormControlLabel, FormGroup, Link, TextField } from '@mui/material';
 import theme from '../src/theme';
-import { md5 } from 'js-md5';
 import { GetServerSideProps } from 'next';
 import fetch from 'isomorphic-fetch';
 import { useRouter } from 'next/router';
@@ -37,7 +36,7 @@ export default function SignIn() {
             },
             body: JSON.stringify({
                 flow: rememberMe ? 'rememberMe' : 'websiteSession',
-                md5Password: md5(password),
+                password: password,
                 username: username,
             }),
         });
@@ -2,6 +2,7 @@ package context
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +14,8 @@ var (
 	CTX_REQUEST_ID    ContextKey = "requestID"
 	CTX_USER_LEVEL    ContextKey = "userLevel"
 	CTX_USER_USERNAME ContextKey = "userUsername"
+	CTX_ACCESS_TOKEN  ContextKey = "accessToken"
+	CTX_USER_CREATED  ContextKey = "userCreated"
 )
 
 // GetRequestID retrieves the request ID from the context.
@@ -39,3 +42,20 @@ func GetUserUsername(ctx context.Context) string {
 	}
 	return ""
 }
+
+// GetAccessToken retrieves the user's access token from the context.
+func GetAccessToken(ctx context.Context) string {
+	if accessToken, ok := ctx.Value(CTX_ACCESS_TOKEN).(string); ok {
+		return accessToken
+	}
+	return ""
+}
+
+// GetUserCreatedAt retrieves the creation time of the user's account from the context.
+// It returns the zero time if it is unknown.
+func GetUserCreatedAt(ctx context.Context) time.Time {
+	if createdAt, ok := ctx.Value(CTX_USER_CREATED).(time.Time); ok {
+		return createdAt
+	}
+	return time.Time{}
+}
@@ -41,3 +41,7 @@ func String(key, val string) zap.Field {
 func Error(err error) zap.Field {
 	return zap.Error(err)
 }
+
+func Int(key string, val int) zap.Field {
+	return zap.Int(key, val)
+}
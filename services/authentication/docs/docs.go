@@ -280,6 +280,65 @@ const docTemplate = `{
                 }
             }
         },
+        "/revoke/{username}": {
+            "post": {
+                "description": "Revokes all the tokens of a logged-in user, e.g. when their account is deleted. Allowed to the user themselves and admins, or to the other services with the service key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "authentication"
+                ],
+                "summary": "Revoke all the sessions of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization header with Bearer token, unless the service key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service key of the other services of the system",
+                        "name": "X-Service-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username of the user",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    }
+                }
+            }
+        },
         "/validate": {
             "post": {
                 "description": "Validates the provided access token",
@@ -356,6 +415,10 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "createdAt": {
+                    "description": "account creation time",
+                    "type": "integer"
+                },
                 "exp": {
                     "description": "the ` + "`" + `exp` + "`" + ` (Expiration Time) claim. See https://datatracker.ietf.org/doc/html/rfc7519#section-4.1.4",
                     "allOf": [
@@ -485,7 +548,7 @@ const docTemplate = `{
                 "flow": {
                     "$ref": "#/definitions/domain.FlowType"
                 },
-                "md5Password": {
+                "password": {
                     "type": "string"
                 },
                 "username": {
@@ -70,6 +70,7 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 			domain.LongRefreshTokenExpiration:  time.Duration(cfg.AuthenticationService.Claims.LongRefreshTokenExpiration) * time.Second,
 		},
 		privateKey,
+		cfg.AuthenticationService.ServiceKey,
 	)
 
 	server := http.Server{
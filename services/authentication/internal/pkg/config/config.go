@@ -14,6 +14,7 @@ type Config struct {
 		LogLevel    string `mapstructure:"log_level"`
 		PrivateKey  string `mapstructure:"private_key"`
 		PublicKey   string `mapstructure:"public_key"`
+		ServiceKey  string `mapstructure:"service_key"`
 		Claims      struct {
 			Issuer                      string        `mapstructure:"issuer"`
 			AccessTokenExpiration       time.Duration `mapstructure:"access_token_expiration"`
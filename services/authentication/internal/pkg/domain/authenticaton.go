@@ -12,6 +12,12 @@ type Authenticator interface {
 	GenerateUserTokens(username, password string, flow FlowType) (*Tokens, error)
 	// Revoke revokes an access token registered for a given refresh token.
 	Revoke(accessToken string) error
+	// RevokeUser revokes all the tokens of a logged-in user on behalf of the user themselves or an admin,
+	// returning how many sessions were revoked.
+	RevokeUser(accessToken, username string) (int, error)
+	// RevokeUserAsService revokes all the tokens of a user on behalf of another service holding the service key,
+	// returning how many sessions were revoked.
+	RevokeUserAsService(serviceKey, username string) (int, error)
 	// Refresh refreshes an user authentication tokens.
 	Refresh(refreshToken string) (*Tokens, error)
 	// ValidateAccessToken checks if logged-in user authentication token exists.
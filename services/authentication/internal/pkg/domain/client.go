@@ -3,8 +3,11 @@ package domain
 import (
 	"context"
 	"crypto/rsa"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -22,12 +25,14 @@ type Client struct {
 	issuer                 string
 	expiration             map[string]time.Duration
 	jwtKey                 *rsa.PrivateKey
+	serviceKey             string
 }
 
 // Claims represents JWT claims data structure.
 type Claims struct {
-	Name  string `json:"name,omitempty"`
-	Level Level  `json:"level"`
+	Name      string           `json:"name,omitempty"`
+	Level     Level            `json:"level"`
+	CreatedAt *jwt.NumericDate `json:"createdAt,omitempty" swaggertype:"integer"` // account creation time
 	jwt.RegisteredClaims
 }
 
@@ -65,6 +70,7 @@ func NewClient(
 	issuer string,
 	expiration map[string]time.Duration,
 	jwtKey *rsa.PrivateKey,
+	serviceKey string,
 ) *Client {
 	return &Client{
 		logger:                 logger,
@@ -75,6 +81,7 @@ func NewClient(
 		issuer:                 issuer,
 		expiration:             expiration,
 		jwtKey:                 jwtKey,
+		serviceKey:             serviceKey,
 	}
 }
 
@@ -108,6 +115,9 @@ func (c *Client) GenerateUserTokens(username, password string, flow FlowType) (*
 			Subject: username,
 		},
 	}
+	if !user.CreatedAt.IsZero() {
+		claims.CreatedAt = jwt.NewNumericDate(user.CreatedAt)
+	}
 
 	return c.generateTokens(claims, flow)
 }
@@ -236,6 +246,82 @@ func (c *Client) Revoke(accessToken string) error {
 	return nil
 }
 
+// RevokeUser revokes all the tokens of a logged-in user on behalf of the user themselves or an admin,
+// e.g. when their account is deleted. Revoking the tokens of a user without any is not an error.
+func (c *Client) RevokeUser(accessToken, username string) (int, error) {
+	claims, err := c.ValidateAccessToken(accessToken)
+	if err != nil {
+		return 0, err
+	}
+	if claims.Level != AdminLevel && (claims.Level == AnonymousLevel || claims.Subject != username) {
+		return 0, ErrUnauthorized
+	}
+
+	return c.revokeUser(username)
+}
+
+// RevokeUserAsService revokes all the tokens of a user on behalf of another service of the system holding the
+// service key, e.g. after the user reset their password without being logged in.
+// It is never allowed when there is no service key.
+func (c *Client) RevokeUserAsService(serviceKey, username string) (int, error) {
+	if c.serviceKey == "" || subtle.ConstantTimeCompare([]byte(serviceKey), []byte(c.serviceKey)) != 1 {
+		return 0, ErrUnauthorized
+	}
+
+	return c.revokeUser(username)
+}
+
+// revokeUser revokes all the tokens of a user, returning how many sessions were revoked.
+func (c *Client) revokeUser(username string) (int, error) {
+	ctx := context.Background()
+
+	// refresh token keys are made of the username and the issue time of the access token
+	keys, err := c.refreshTokenRepository.Keys(ctx, username)
+	if err != nil {
+		c.logger.Error("failed to list refresh token repository keys", log.String("username", username), log.Error(err))
+		return 0, ErrInternalError
+	}
+
+	revoked := 0
+	for _, refreshTokenKey := range keys {
+		// the keys of usernames starting with the username and a dash match too
+		if !isRefreshTokenKeyOf(refreshTokenKey, username) {
+			continue
+		}
+
+		refreshToken, err := c.refreshTokenRepository.Get(ctx, refreshTokenKey)
+		if err == nil {
+			err = c.accessTokenRepository.Del(ctx, refreshToken)
+			if err != nil {
+				c.logger.Warn("failed to delete from access token repository", log.String("key", refreshToken), log.Error(err))
+			}
+		}
+		// the session is revoked once its refresh token is gone, as access tokens are validated against it
+		err = c.refreshTokenRepository.Del(ctx, refreshTokenKey)
+		if err != nil {
+			c.logger.Error("failed to delete from refresh token repository", log.String("key", refreshTokenKey), log.Error(err))
+			return revoked, ErrInternalError
+		}
+		err = c.flowRepository.Del(ctx, refreshTokenKey)
+		if err != nil {
+			c.logger.Warn("failed to delete from flow repository", log.String("key", refreshTokenKey), log.Error(err))
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// isRefreshTokenKeyOf checks if key is a refresh token key of the given username.
+func isRefreshTokenKeyOf(key, username string) bool {
+	issuedAt, found := strings.CutPrefix(key, username+"-")
+	if !found {
+		return false
+	}
+	_, err := strconv.ParseInt(issuedAt, 10, 64)
+	return err == nil
+}
+
 // Refresh refreshes logged-in/anonymous user authentication token.
 func (c *Client) Refresh(refreshToken string) (*Tokens, error) {
 	ctx := context.Background()
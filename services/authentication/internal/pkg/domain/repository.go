@@ -7,7 +7,8 @@ import (
 
 // Repository is the interface for the authenticator storage repository.
 type Repository interface {
-	// Keys retrieves keys matching the specified pattern.
+	// Keys retrieves the keys made of the specified pattern followed by a dash and anything else.
+	// It returns an empty list if there is none.
 	Keys(ctx context.Context, pattern string) ([]string, error)
 	// Get retrieves the value associated with the specified key.
 	Get(ctx context.Context, key string) (string, error)
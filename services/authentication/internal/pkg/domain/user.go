@@ -14,15 +14,16 @@ import (
 
 // User represents a user entity.
 type User struct {
-	Username string `json:"username"`
-	Name     string `json:"name"`
-	Level    Level  `json:"level"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	Level     Level     `json:"level"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // UserServiceClient is an interface for interacting with the user service.
 type UserServiceClient interface {
 	// CheckCredentials checks the credentials of a user.
-	CheckCredentials(username, md5Password string) (*User, error)
+	CheckCredentials(username, password string) (*User, error)
 }
 
 var (
@@ -37,8 +38,8 @@ type userServiceClient struct {
 }
 
 type payload struct {
-	Username    string `json:"username"`
-	MD5Password string `json:"md5Password"`
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 type userResponse struct {
@@ -57,8 +58,8 @@ func NewUserServiceClient(timeout time.Duration, endpoint string, logger *log.Lo
 }
 
 // CheckCredentials checks the credentials of a user using the user service.
-func (c *userServiceClient) CheckCredentials(username, md5Password string) (*User, error) {
-	p := payload{username, md5Password}
+func (c *userServiceClient) CheckCredentials(username, password string) (*User, error) {
+	p := payload{username, password}
 	b, err := json.Marshal(p)
 	if err != nil {
 		c.logger.Error("failed to parse request body", log.Error(err))
@@ -8,12 +8,16 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/victorspringer/backend-coding-challenge/lib/context"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
 	"github.com/victorspringer/backend-coding-challenge/services/authentication/internal/pkg/domain"
 )
 
+// serviceKeyHeader is the header the other services of the system send the service key in.
+const serviceKeyHeader = "X-Service-Key"
+
 func (rt *router) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
 }
@@ -90,7 +94,7 @@ func (rt *router) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := rt.authenticator.GenerateUserTokens(body.Username, body.MD5Password, body.Flow)
+	tokens, err := rt.authenticator.GenerateUserTokens(body.Username, body.Password, body.Flow)
 	if err != nil {
 		rt.logger.Error("failed to generate user tokens", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
 		if err == domain.ErrUnauthorized {
@@ -188,6 +192,53 @@ func (rt *router) refresh(w http.ResponseWriter, r *http.Request) {
 	rt.respond(w, r, tokens, http.StatusOK)
 }
 
+// @Summary Revoke all the sessions of a user
+// @Description Revokes all the tokens of a logged-in user, e.g. when their account is deleted. Allowed to the user themselves and admins, or to the other services with the service key
+// @Tags authentication
+// @Produce json
+// @Param Authorization header string false "Authorization header with Bearer token, unless the service key is given"
+// @Param X-Service-Key header string false "Service key of the other services of the system"
+// @Param username path string true "Username of the user"
+// @Success 200 {object} response
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /revoke/{username} [post]
+func (rt *router) revokeUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	username := chi.URLParam(r, "username")
+
+	var revoked int
+	var err error
+	if serviceKey := r.Header.Get(serviceKeyHeader); serviceKey != "" {
+		revoked, err = rt.authenticator.RevokeUserAsService(serviceKey, username)
+	} else {
+		accessToken := r.Header.Get("Authorization")
+		if accessToken == "" {
+			rt.logger.Info("Authorization header is not set")
+			rt.respond(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		accessToken = strings.Replace(accessToken, "Bearer ", "", 1)
+
+		revoked, err = rt.authenticator.RevokeUser(accessToken, username)
+	}
+	if err != nil {
+		rt.logger.Error("failed to revoke user tokens", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		if err == domain.ErrUnauthorized {
+			rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		rt.respond(w, r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rt.logger.Info("user tokens revoked", log.String("username", username), log.Int("sessions", revoked))
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
 // @Summary Logout
 // @Description Revokes the access token
 // @Tags authentication
@@ -3,9 +3,9 @@ package router
 import "github.com/victorspringer/backend-coding-challenge/services/authentication/internal/pkg/domain"
 
 type loginPayload struct {
-	Username    string          `json:"username"`
-	MD5Password string          `json:"md5Password"`
-	Flow        domain.FlowType `json:"flow"`
+	Username string          `json:"username"`
+	Password string          `json:"password"`
+	Flow     domain.FlowType `json:"flow"`
 }
 
 type refreshPayload struct {
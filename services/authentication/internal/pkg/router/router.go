@@ -64,6 +64,7 @@ func (rt *router) GetHandler() http.Handler {
 	r.Post("/login", rt.login)
 	r.Post("/refresh", rt.refresh)
 	r.Post("/logout", rt.logout)
+	r.Post("/revoke/{username}", rt.revokeUser)
 	r.Post("/validate", rt.validateAccessToken)
 	r.Get("/.well-known/jwks.json", rt.jwks)
 
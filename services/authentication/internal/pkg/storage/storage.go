@@ -27,7 +27,7 @@ func NewRedisRepository(redisPrefix string, redisPrimaryClient, redisReaderClien
 	}
 }
 
-// Keys retrieves keys matching the specified pattern.
+// Keys retrieves the keys made of the specified pattern followed by a dash and anything else.
 func (r *redisRepository) Keys(ctx context.Context, pattern string) ([]string, error) {
 	var (
 		keys   []string = make([]string, 0)
@@ -40,7 +40,7 @@ func (r *redisRepository) Keys(ctx context.Context, pattern string) ([]string, e
 		kk, cursor, err = r.redisReaderClient.Scan(ctx, cursor, `\`+r.redisPrefix+pattern+`-*`, 0).Result()
 		if err != nil {
 			if err.Error() == errRedisNotFound {
-				return []string{}, fmt.Errorf("no keys found for pattern %s", pattern)
+				return []string{}, nil
 			}
 			return []string{}, err
 		}
@@ -50,10 +50,6 @@ func (r *redisRepository) Keys(ctx context.Context, pattern string) ([]string, e
 		}
 	}
 
-	if len(keys) == 0 {
-		return []string{}, fmt.Errorf("no keys found for pattern %s", pattern)
-	}
-
 	for i := range keys {
 		keys[i] = strings.Replace(keys[i], r.redisPrefix, "", 1)
 	}
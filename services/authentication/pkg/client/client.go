@@ -4,14 +4,29 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
 )
 
+// Access levels of the claims, see Claims. They match the levels the service issues the tokens with.
+const (
+	AdminLevel     = "admin"
+	UserLevel      = "user"
+	AnonymousLevel = "anonymous" // of the tokens generated without an account
+)
+
+// Common errors.
+var (
+	ErrUnauthorized = errors.New("not allowed by the authentication service")
+	ErrUnavailable  = errors.New("authentication service unavailable")
+)
+
 // Client is a struct representing the authentication service client.
 type Client struct {
 	baseURL    string
@@ -47,8 +62,9 @@ type Tokens struct {
 
 // Claims is a struct representing the claims returned by the authentication service.
 type Claims struct {
-	Name  string `json:"name,omitempty"`
-	Level string `json:"level"`
+	Name      string           `json:"name,omitempty"`
+	Level     string           `json:"level"`
+	CreatedAt *jwt.NumericDate `json:"createdAt,omitempty" swaggertype:"integer"` // account creation time
 	jwt.RegisteredClaims
 }
 
@@ -151,3 +167,44 @@ func (c *Client) ValidateAccessToken(ctx context.Context, payload ValidationPayl
 
 	return &result.Response, nil
 }
+
+// RevokeUser revokes all the sessions of a user, on behalf of the user themselves or an admin the access token belongs to.
+// Revoking the sessions of the user the access token belongs to revokes it too.
+// It returns ErrUnauthorized if the access token is not allowed to and ErrUnavailable if the authentication service failed.
+func (c *Client) RevokeUser(ctx context.Context, username, accessToken string) error {
+	return c.revokeUser(ctx, username, "Authorization", "Bearer "+accessToken)
+}
+
+// RevokeUserAsService revokes all the sessions of a user on behalf of another service of the system holding the
+// service key, e.g. after the user reset their password without being logged in.
+// It returns ErrUnauthorized if the service key is wrong and ErrUnavailable if the authentication service failed.
+func (c *Client) RevokeUserAsService(ctx context.Context, username, serviceKey string) error {
+	return c.revokeUser(ctx, username, "X-Service-Key", serviceKey)
+}
+
+// revokeUser revokes all the sessions of a user, authorized by the given header.
+func (c *Client) revokeUser(ctx context.Context, username, header, value string) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/revoke/%s", c.baseURL, url.PathEscape(username)), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", log.Error(err))
+		return err
+	}
+	r.Header.Set(header, value)
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		c.logger.Error("error from authentication service", log.Error(err))
+		return ErrUnavailable
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	default:
+		c.logger.Error("unexpected status code from authentication service", log.Int("statusCode", resp.StatusCode))
+		return ErrUnavailable
+	}
+}
@@ -87,6 +87,10 @@ func (c *Client) Middleware(next http.Handler) http.Handler {
 
 			ctx = context.WithValue(ctx, libCtx.CTX_USER_USERNAME, claims.Subject)
 			ctx = context.WithValue(ctx, libCtx.CTX_USER_LEVEL, claims.Level)
+			ctx = context.WithValue(ctx, libCtx.CTX_ACCESS_TOKEN, authToken)
+			if claims.CreatedAt != nil {
+				ctx = context.WithValue(ctx, libCtx.CTX_USER_CREATED, claims.CreatedAt.Time)
+			}
 		}
 
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -148,12 +149,24 @@ func main() {
 
 			genres := strings.Split(record[headerMap["genres"]], ", ")
 
+			// release year and IMDb ID are optional, they are only used to look movies up
+			var year int
+			if i, ok := headerMap["release_date"]; ok && len(record[i]) >= 4 {
+				year, _ = strconv.Atoi(record[i][:4])
+			}
+			var imdbID string
+			if i, ok := headerMap["imdb_id"]; ok {
+				imdbID = record[i]
+			}
+
 			movie := domain.Movie{
 				ID:            record[headerMap["id"]],
 				Title:         record[headerMap["title"]],
 				OriginalTitle: record[headerMap["original_title"]],
 				Poster:        "https://image.tmdb.org/t/p/w220_and_h330_face" + record[headerMap["poster_path"]],
 				Genres:        genres,
+				Year:          year,
+				IMDbID:        imdbID,
 				CreatedAt:     time.Now(),
 				UpdatedAt:     time.Now(),
 			}
@@ -94,6 +94,94 @@ const docTemplate = `{
                 }
             }
         },
+        "/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Look movies up by IMDb ID, or by title (ignoring case) and optionally release year",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Search movies",
+                "operationId": "search-movies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IMDb ID of the movie, e.g. tt0133093",
+                        "name": "imdbId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Title or original title of the movie",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Release year of the movie",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Insert your access token, unless the service key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service key of the other services of the system",
+                        "name": "X-Service-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/router.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "response": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.Movie"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.response"
+                        }
+                    }
+                }
+            }
+        },
         "/{id}": {
             "get": {
                 "security": [
@@ -117,10 +205,15 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Insert your access token",
+                        "description": "Insert your access token, unless the service key is given",
                         "name": "Authorization",
-                        "in": "header",
-                        "required": true
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service key of the other services of the system",
+                        "name": "X-Service-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -180,6 +273,9 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
+                "imdbId": {
+                    "type": "string"
+                },
                 "originalTitle": {
                     "type": "string"
                 },
@@ -191,6 +287,9 @@ const docTemplate = `{
                 },
                 "updatedAt": {
                     "type": "string"
+                },
+                "year": {
+                    "type": "integer"
                 }
             }
         },
@@ -203,6 +302,9 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "imdbId": {
+                    "type": "string"
+                },
                 "originalTitle": {
                     "type": "string"
                 },
@@ -211,6 +313,9 @@ const docTemplate = `{
                 },
                 "title": {
                     "type": "string"
+                },
+                "year": {
+                    "type": "integer"
                 }
             }
         },
@@ -50,7 +50,7 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 
 	server := http.Server{
 		Addr:         cfg.MovieService.Server.Port,
-		Handler:      router.New(db, logger, ac).GetHandler(),
+		Handler:      router.New(db, logger, ac, cfg.MovieService.ServiceKey).GetHandler(),
 		ReadTimeout:  cfg.MovieService.Server.ReadTimeout * time.Second,
 		WriteTimeout: cfg.MovieService.Server.WriteTimeout * time.Second,
 		IdleTimeout:  cfg.MovieService.Server.IdleTimeout * time.Second,
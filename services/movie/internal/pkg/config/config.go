@@ -12,6 +12,7 @@ type Config struct {
 	MovieService struct {
 		Environment string `mapstructure:"environment"`
 		LogLevel    string `mapstructure:"log_level"`
+		ServiceKey  string `mapstructure:"service_key"`
 		Server      struct {
 			Port         string        `mapstructure:"port"`
 			ReadTimeout  time.Duration `mapstructure:"read_timeout"`
@@ -3,7 +3,6 @@ package database
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
@@ -13,6 +12,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// titleCollation compares titles ignoring case and diacritics.
+var titleCollation = &options.Collation{Locale: "en", Strength: 1}
+
 type database struct {
 	logger     *log.Logger
 	client     *mongo.Client
@@ -48,6 +50,26 @@ func New(ctx context.Context, logger *log.Logger, uri, name, collection string,
 		return nil, err
 	}
 
+	// create indexes to look movies up by IMDb ID and by title (case insensitive) and year
+	lookupIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "imdbId", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "title", Value: 1}, {Key: "year", Value: 1}},
+			Options: options.Index().SetCollation(titleCollation),
+		},
+		{
+			Keys:    bson.D{{Key: "originalTitle", Value: 1}, {Key: "year", Value: 1}},
+			Options: options.Index().SetCollation(titleCollation),
+		},
+	}
+	_, err = coll.Indexes().CreateMany(ctx, lookupIndexes)
+	if err != nil {
+		return nil, err
+	}
+
 	return &database{
 		logger:     logger,
 		client:     client,
@@ -93,10 +115,61 @@ func (db *database) FindByID(ctx context.Context, id string) (*domain.Movie, err
 
 	if err := db.collection.FindOne(ctx, filter).Decode(&m); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("movie with id %s doesn't exist", id)
+			return nil, domain.ErrMovieNotFound
 		}
 		return nil, err
 	}
 
 	return &m, nil
 }
+
+// FindByIMDbID implements domain.Repository interface's FindByIMDbID method.
+func (db *database) FindByIMDbID(ctx context.Context, imdbID string) (*domain.Movie, error) {
+	filter := bson.D{{Key: "imdbId", Value: imdbID}}
+
+	var m domain.Movie
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if err := db.collection.FindOne(ctx, filter).Decode(&m); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrMovieNotFound
+		}
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// FindByTitle implements domain.Repository interface's FindByTitle method.
+func (db *database) FindByTitle(ctx context.Context, title string, year, limit int) ([]*domain.Movie, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"title": title},
+			bson.M{"originalTitle": title},
+		},
+	}
+	if year > 0 {
+		filter["year"] = year
+	}
+
+	findOptions := options.Find().
+		SetCollation(titleCollation).
+		SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*domain.Movie{}
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,18 +17,31 @@ type Movie struct {
 	OriginalTitle string    `json:"originalTitle" bson:"originalTitle"`
 	Poster        string    `json:"poster" bson:"poster"`
 	Genres        []string  `json:"genres" bson:"genres"`
+	Year          int       `json:"year,omitempty" bson:"year,omitempty"`
+	IMDbID        string    `json:"imdbId,omitempty" bson:"imdbId,omitempty"`
 	CreatedAt     time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt" bson:"updatedAt"`
 }
 
+// minYear is the year of the oldest known motion pictures.
+const minYear = 1870
+
+var imdbIDRegexp = regexp.MustCompile(`^tt\d{7,}$`)
+
+// ErrMovieNotFound is returned when looking up a Movie that doesn't exist.
+var ErrMovieNotFound = errors.New("movie doesn't exist")
+
 // NewMovie returns an instance of the Movie entity.
-func NewMovie(title, originalTitle, poster string, genres []string) *Movie {
+// year and imdbID are optional, zero values mean they are unknown.
+func NewMovie(title, originalTitle, poster string, genres []string, year int, imdbID string) *Movie {
 	return &Movie{
 		ID:            uuid.New().String(),
 		Title:         title,
 		OriginalTitle: originalTitle,
 		Poster:        poster,
 		Genres:        genres,
+		Year:          year,
+		IMDbID:        imdbID,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
@@ -53,6 +68,12 @@ func (m *Movie) validate(validateImageContent ...bool) error {
 	if len(m.Genres) == 0 {
 		return errors.New("at least one genre is required")
 	}
+	if maxYear := time.Now().Year() + 10; m.Year != 0 && (m.Year < minYear || m.Year > maxYear) {
+		return fmt.Errorf("year must be from %d to %d", minYear, maxYear)
+	}
+	if m.IMDbID != "" && !imdbIDRegexp.MatchString(m.IMDbID) {
+		return errors.New("imdbId is invalid")
+	}
 	if m.CreatedAt.After(m.UpdatedAt) {
 		return errors.New("created_at must be before updated_at")
 	}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -13,8 +14,10 @@ func TestNewMovie(t *testing.T) {
 	originalTitle := "Original Movie Title"
 	poster := "https://example.com/poster.jpg"
 	genres := []string{"Action", "Adventure"}
+	year := 1999
+	imdbID := "tt0133093"
 
-	movie := NewMovie(title, originalTitle, poster, genres)
+	movie := NewMovie(title, originalTitle, poster, genres, year, imdbID)
 
 	assert.NotNil(t, movie)
 	assert.NotEmpty(t, movie.ID)
@@ -22,6 +25,8 @@ func TestNewMovie(t *testing.T) {
 	assert.Equal(t, originalTitle, movie.OriginalTitle)
 	assert.Equal(t, poster, movie.Poster)
 	assert.Equal(t, genres, movie.Genres)
+	assert.Equal(t, year, movie.Year)
+	assert.Equal(t, imdbID, movie.IMDbID)
 	assert.True(t, movie.CreatedAt.Before(time.Now()))
 	assert.True(t, movie.UpdatedAt.Before(time.Now()))
 }
@@ -119,6 +124,49 @@ func TestValidateMovie(t *testing.T) {
 			},
 			err: errors.New("at least one genre is required"),
 		},
+		{
+			name: "ValidMovie_WithYearAndIMDbID",
+			movie: &Movie{
+				ID:            "123",
+				Title:         "Movie Title",
+				OriginalTitle: "Original Movie Title",
+				Poster:        "https://example.com/poster.jpg",
+				Genres:        []string{"Action", "Adventure"},
+				Year:          1999,
+				IMDbID:        "tt0133093",
+				CreatedAt:     time.Now().Add(-time.Hour),
+				UpdatedAt:     time.Now(),
+			},
+			err: nil,
+		},
+		{
+			name: "InvalidMovie_InvalidYear",
+			movie: &Movie{
+				ID:            "123",
+				Title:         "Movie Title",
+				OriginalTitle: "Original Movie Title",
+				Poster:        "https://example.com/poster.jpg",
+				Genres:        []string{"Action", "Adventure"},
+				Year:          1800,
+				CreatedAt:     time.Now().Add(-time.Hour),
+				UpdatedAt:     time.Now(),
+			},
+			err: fmt.Errorf("year must be from 1870 to %d", time.Now().Year()+10),
+		},
+		{
+			name: "InvalidMovie_InvalidIMDbID",
+			movie: &Movie{
+				ID:            "123",
+				Title:         "Movie Title",
+				OriginalTitle: "Original Movie Title",
+				Poster:        "https://example.com/poster.jpg",
+				Genres:        []string{"Action", "Adventure"},
+				IMDbID:        "0133093",
+				CreatedAt:     time.Now().Add(-time.Hour),
+				UpdatedAt:     time.Now(),
+			},
+			err: errors.New("imdbId is invalid"),
+		},
 		{
 			name: "InvalidMovie_CreatedAtAfterUpdatedAt",
 			movie: &Movie{
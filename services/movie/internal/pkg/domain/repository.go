@@ -6,8 +6,13 @@ import "context"
 type Repository interface {
 	// Create receives a validated input and creates a new Movie.
 	Create(ctx context.Context, movie *ValidatedMovie) (*Movie, error)
-	// FindById retrieves a Movie by a given unique ID.
+	// FindById retrieves a Movie by a given unique ID, returning ErrMovieNotFound if it doesn't exist.
 	FindByID(ctx context.Context, id string) (*Movie, error)
+	// FindByIMDbID retrieves a Movie by its IMDb ID, returning ErrMovieNotFound if it doesn't exist.
+	FindByIMDbID(ctx context.Context, imdbID string) (*Movie, error)
+	// FindByTitle retrieves up to limit Movie whose title or original title is the given one, ignoring case.
+	// A year greater than zero narrows the search down to the movies released that year.
+	FindByTitle(ctx context.Context, title string, year, limit int) ([]*Movie, error)
 	// Close disconnects the database connection pool.
 	Close(ctx context.Context) error
 }
@@ -1,9 +1,11 @@
 package router
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/victorspringer/backend-coding-challenge/lib/context"
@@ -11,16 +13,33 @@ import (
 	"github.com/victorspringer/backend-coding-challenge/services/movie/internal/pkg/domain"
 )
 
+// searchLimit is the maximum number of movies returned by a search.
+const searchLimit = 10
+
+// serviceKeyHeader is the header the other services of the system send the service key in.
+const serviceKeyHeader = "X-Service-Key"
+
 func (rt *router) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
 }
 
+// canRead checks if the request comes from a logged in user, or from another service of the system sending
+// the service key, e.g. when importing ratings in the background, after the requester's access token expired.
+// Nobody is allowed with an empty service key.
+func (rt *router) canRead(r *http.Request) bool {
+	if serviceKey := r.Header.Get(serviceKeyHeader); serviceKey != "" {
+		return rt.serviceKey != "" && subtle.ConstantTimeCompare([]byte(serviceKey), []byte(rt.serviceKey)) == 1
+	}
+	return context.GetUserLevel(r.Context()) != "anonymous"
+}
+
 // @Summary Get movie by ID
 // @Description Get movie information by ID
 // @ID get-movie-by-id
 // @Param id path string true "ID of the movie"
 // @Security ApiKeyAuth
-// @Param Authorization header string true "Insert your access token"
+// @Param Authorization header string false "Insert your access token, unless the service key is given"
+// @Param X-Service-Key header string false "Service key of the other services of the system"
 // @Produce json
 // @Success 200 {object} response{response=domain.Movie}
 // @Failure 401 {object} response
@@ -30,7 +49,7 @@ func (rt *router) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 func (rt *router) findHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	if level := context.GetUserLevel(ctx); level == "anonymous" {
+	if !rt.canRead(r) {
 		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -39,14 +58,84 @@ func (rt *router) findHandler(w http.ResponseWriter, r *http.Request) {
 
 	m, err := rt.repository.FindByID(ctx, id)
 	if err != nil {
-		rt.logger.Error("movie not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
-		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		if err == domain.ErrMovieNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("failed to find movie", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	rt.respond(w, r, m, http.StatusOK)
 }
 
+// @Summary Search movies
+// @Description Look movies up by IMDb ID, or by title (ignoring case) and optionally release year
+// @ID search-movies
+// @Param imdbId query string false "IMDb ID of the movie, e.g. tt0133093"
+// @Param title query string false "Title or original title of the movie"
+// @Param year query int false "Release year of the movie"
+// @Security ApiKeyAuth
+// @Param Authorization header string false "Insert your access token, unless the service key is given"
+// @Param X-Service-Key header string false "Service key of the other services of the system"
+// @Produce json
+// @Success 200 {object} response{response=[]domain.Movie}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /search [get]
+func (rt *router) searchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !rt.canRead(r) {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if imdbID := query.Get("imdbId"); imdbID != "" {
+		// a movie that doesn't exist makes an empty result, as when searching by title
+		list := []*domain.Movie{}
+		m, err := rt.repository.FindByIMDbID(ctx, imdbID)
+		switch {
+		case err == nil:
+			list = append(list, m)
+		case err != domain.ErrMovieNotFound:
+			rt.logger.Error("failed to search movies", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rt.respond(w, r, list, http.StatusOK)
+		return
+	}
+
+	title := query.Get("title")
+	if title == "" {
+		rt.respond(w, r, "either imdbId or title is required", http.StatusBadRequest)
+		return
+	}
+
+	year := 0
+	if v := query.Get("year"); v != "" {
+		var err error
+		if year, err = strconv.Atoi(v); err != nil || year < 0 {
+			rt.respond(w, r, "year must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := rt.repository.FindByTitle(ctx, title, year, searchLimit)
+	if err != nil {
+		rt.logger.Error("failed to search movies", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, list, http.StatusOK)
+}
+
 // @Summary Create a new movie
 // @Description Create a new movie
 // @ID create-movie
@@ -85,7 +174,7 @@ func (rt *router) createHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	m := domain.NewMovie(p.Title, p.OriginalTitle, p.Poster, p.Genres)
+	m := domain.NewMovie(p.Title, p.OriginalTitle, p.Poster, p.Genres, p.Year, p.IMDbID)
 
 	vm, err := domain.NewValidatedMovie(m)
 	if err != nil {
@@ -5,4 +5,6 @@ type createPayload struct {
 	OriginalTitle string   `json:"originalTitle"`
 	Poster        string   `json:"poster"`
 	Genres        []string `json:"genres"`
+	Year          int      `json:"year"`
+	IMDbID        string   `json:"imdbId"`
 }
@@ -33,11 +33,13 @@ type router struct {
 	repository      domain.Repository
 	logger          *log.Logger
 	ac              *authClient.Client
+	serviceKey      string
 	cacheMiddleware func(next http.Handler) http.Handler
 }
 
 // New returns a new instance of Router.
-func New(repo domain.Repository, logger *log.Logger, ac *authClient.Client) Router {
+// The other services of the system can look movies up with the service key instead of an access token.
+func New(repo domain.Repository, logger *log.Logger, ac *authClient.Client, serviceKey string) Router {
 	memcached, err := memory.NewAdapter(
 		memory.AdapterWithAlgorithm(memory.LRU),
 		memory.AdapterWithCapacity(10000000),
@@ -54,7 +56,7 @@ func New(repo domain.Repository, logger *log.Logger, ac *authClient.Client) Rout
 		logger.Fatal(err.Error())
 	}
 
-	return &router{repo, logger, ac, cacheClient.Middleware}
+	return &router{repo, logger, ac, serviceKey, cacheClient.Middleware}
 }
 
 // GetHandler returns the router's http handler.
@@ -64,7 +66,7 @@ func (rt *router) GetHandler() http.Handler {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "X-Forwarded-Proto"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "X-Forwarded-Proto", "X-Service-Key"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -92,6 +94,7 @@ func (rt *router) GetHandler() http.Handler {
 	r.Route("/", func(r chi.Router) {
 		r.Use(rt.cacheMiddleware)
 
+		r.Get("/search", rt.searchHandler)
 		r.Get("/{id}", rt.findHandler)
 	})
 
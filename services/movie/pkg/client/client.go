@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+)
+
+// maxCacheEntries bounds the number of movies kept in the client's local cache.
+const maxCacheEntries = 100000
+
+// Common errors.
+var (
+	ErrMovieNotFound = errors.New("movie not found")
+	ErrUnavailable   = errors.New("movie service unavailable")
+)
+
+// Client is a struct representing the movie service client.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	logger     *log.Logger
+	cacheTTL   time.Duration
+	mu         sync.RWMutex
+	cache      map[string]cacheEntry
+}
+
+// Movie is a struct representing a movie returned by the movie service.
+type Movie struct {
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	OriginalTitle string    `json:"originalTitle"`
+	Poster        string    `json:"poster"`
+	Genres        []string  `json:"genres"`
+	Year          int       `json:"year,omitempty"`
+	IMDbID        string    `json:"imdbId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type movieResponse struct {
+	Response Movie `json:"response"`
+}
+
+type moviesResponse struct {
+	Response []*Movie `json:"response"`
+}
+
+type cacheEntry struct {
+	movie     *Movie
+	expiresAt time.Time
+}
+
+// NewClient creates a new instance of the movie service client.
+// Movies found are kept in a local cache for cacheTTL.
+func NewClient(baseURL string, timeout, cacheTTL time.Duration, logger *log.Logger) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		logger:   logger,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]cacheEntry),
+	}
+}
+
+// FindByID retrieves a movie by its ID, on behalf of the user the access token belongs to.
+// It returns ErrMovieNotFound if the movie doesn't exist and ErrUnavailable if the movie service can't tell.
+func (c *Client) FindByID(ctx context.Context, id, accessToken string) (*Movie, error) {
+	if m := c.cached(id); m != nil {
+		return m, nil
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", c.baseURL, url.PathEscape(id)), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", log.Error(err))
+		return nil, err
+	}
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		c.logger.Error("error from movie service", log.Error(err))
+		return nil, ErrUnavailable
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrMovieNotFound
+	case resp.StatusCode != http.StatusOK:
+		c.logger.Error("unexpected status code from movie service", log.Int("statusCode", resp.StatusCode))
+		return nil, ErrUnavailable
+	}
+
+	var result movieResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		c.logger.Error("failed to parse response", log.Error(err))
+		return nil, ErrUnavailable
+	}
+
+	c.store(&result.Response)
+
+	return &result.Response, nil
+}
+
+// Search looks movies up by IMDb ID if given, or else by title and release year (0 means any year),
+// on behalf of the user the access token belongs to. It returns ErrUnavailable if the movie service can't tell.
+func (c *Client) Search(ctx context.Context, imdbID, title string, year int, accessToken string) ([]*Movie, error) {
+	return c.search(ctx, imdbID, title, year, "Authorization", "Bearer "+accessToken)
+}
+
+// SearchAsService is Search on behalf of another service of the system holding the service key,
+// e.g. when importing ratings in the background, after the requester's access token expired.
+func (c *Client) SearchAsService(ctx context.Context, imdbID, title string, year int, serviceKey string) ([]*Movie, error) {
+	return c.search(ctx, imdbID, title, year, "X-Service-Key", serviceKey)
+}
+
+// search looks movies up, authorized by the given header.
+func (c *Client) search(ctx context.Context, imdbID, title string, year int, header, value string) ([]*Movie, error) {
+	query := url.Values{}
+	if imdbID != "" {
+		query.Set("imdbId", imdbID)
+	} else {
+		query.Set("title", title)
+		if year > 0 {
+			query.Set("year", strconv.Itoa(year))
+		}
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/search?%s", c.baseURL, query.Encode()), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", log.Error(err))
+		return nil, err
+	}
+	r.Header.Set(header, value)
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		c.logger.Error("error from movie service", log.Error(err))
+		return nil, ErrUnavailable
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code from movie service", log.Int("statusCode", resp.StatusCode))
+		return nil, ErrUnavailable
+	}
+
+	var result moviesResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		c.logger.Error("failed to parse response", log.Error(err))
+		return nil, ErrUnavailable
+	}
+
+	for _, m := range result.Response {
+		c.store(m)
+	}
+
+	return result.Response, nil
+}
+
+func (c *Client) cached(id string) *Movie {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if e, ok := c.cache[id]; ok && time.Now().Before(e.expiresAt) {
+		return e.movie
+	}
+	return nil
+}
+
+func (c *Client) store(m *Movie) {
+	if c.cacheTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.cache) >= maxCacheEntries {
+		for id, e := range c.cache {
+			if now.After(e.expiresAt) {
+				delete(c.cache, id)
+			}
+		}
+		if len(c.cache) >= maxCacheEntries {
+			return
+		}
+	}
+
+	c.cache[m.ID] = cacheEntry{movie: m, expiresAt: now.Add(c.cacheTTL)}
+}
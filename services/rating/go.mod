@@ -4,15 +4,20 @@ go 1.22.3
 
 replace github.com/victorspringer/backend-coding-challenge/lib/context v0.0.0 => ../../lib/context
 
+replace github.com/victorspringer/backend-coding-challenge/lib/image v0.0.0 => ../../lib/image
+
 replace github.com/victorspringer/backend-coding-challenge/lib/log v0.0.0 => ../../lib/log
 
 replace github.com/victorspringer/backend-coding-challenge/services/authentication v0.0.0 => ../authentication
 
+replace github.com/victorspringer/backend-coding-challenge/services/movie v0.0.0 => ../movie
+
 require (
 	github.com/go-chi/chi/v5 v5.0.12
 	github.com/go-chi/cors v1.2.1
 	github.com/google/uuid v1.6.0
 	github.com/pkg/errors v0.9.1
+	github.com/redis/go-redis/v9 v9.5.2
 	github.com/spf13/viper v1.19.0
 	github.com/stretchr/testify v1.9.0
 	github.com/swaggo/http-swagger v1.3.4
@@ -20,12 +25,16 @@ require (
 	github.com/victorspringer/backend-coding-challenge/lib/context v0.0.0
 	github.com/victorspringer/backend-coding-challenge/lib/log v0.0.0
 	github.com/victorspringer/backend-coding-challenge/services/authentication v0.0.0
+	github.com/victorspringer/backend-coding-challenge/services/movie v0.0.0
+	github.com/victorspringer/http-cache v0.0.0-20240523143319-7d9f48f8ab91
 	go.mongodb.org/mongo-driver v1.15.0
 )
 
 require (
 	github.com/KyleBanks/depth v1.2.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/go-openapi/jsonpointer v0.19.5 // indirect
 	github.com/go-openapi/jsonreference v0.20.0 // indirect
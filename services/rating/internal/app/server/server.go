@@ -9,11 +9,20 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
 	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
+	movieClient "github.com/victorspringer/backend-coding-challenge/services/movie/pkg/client"
 	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/config"
 	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/database"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/importer"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/leaderboard"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/live"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/moderation"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/outbox"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/recommendation"
 	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/router"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
 )
 
 // Init starts the application server.
@@ -25,7 +34,19 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 		logger,
 		cfg.MongoDB.URI,
 		cfg.MongoDB.DBName,
-		cfg.MongoDB.Collection,
+		database.Collections{
+			Ratings:      cfg.MongoDB.Collection,
+			Votes:        cfg.MongoDB.VotesCollection,
+			Similarities: cfg.MongoDB.SimilaritiesCollection,
+			Genres:       cfg.MongoDB.GenresCollection,
+			Leaderboards: cfg.MongoDB.LeaderboardsCollection,
+			Outbox:       cfg.MongoDB.OutboxCollection,
+			Imports:      cfg.MongoDB.ImportsCollection,
+			Watchlist:    cfg.MongoDB.WatchlistCollection,
+			Diary:        cfg.MongoDB.DiaryCollection,
+			Stats:        cfg.MongoDB.StatsCollection,
+			Velocity:     cfg.MongoDB.VelocityCollection,
+		},
 		cfg.MongoDB.Timeout*time.Second,
 	)
 	if err != nil {
@@ -42,20 +63,108 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 
 	logger.Debug("database connected")
 
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: cfg.Redis.Addr,
+	})
+
+	defer func() {
+		if err := redisClient.Close(); err != nil {
+			logger.Error("failed to close redis connection pool", log.Error(err))
+		} else {
+			logger.Debug("redis connection closed")
+		}
+	}()
+
+	logger.Debug("redis connected")
+
+	// background jobs run until the server stops
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+
+	go recommendation.NewBuilder(
+		db,
+		logger,
+		cfg.Recommendation.Interval*time.Second,
+		cfg.Recommendation.MaxRatingsPerUser,
+		cfg.Recommendation.MaxNeighbors,
+		cfg.Recommendation.MinSupport,
+	).Run(jobsCtx)
+
+	go leaderboard.NewBuilder(
+		db,
+		logger,
+		cfg.Leaderboard.Interval*time.Second,
+		cfg.Leaderboard.Size,
+		cfg.Leaderboard.MinRatings,
+		cfg.Leaderboard.TrendingWindow*time.Second,
+		cfg.Leaderboard.TrendingHalfLife*time.Second,
+	).Run(jobsCtx)
+
+	go outbox.NewRelay(
+		db,
+		events.NewPublisher(redisClient, cfg.Events.Stream, cfg.Events.MaxLen),
+		logger,
+		cfg.Events.RelayInterval*time.Second,
+		cfg.Events.BatchSize,
+		cfg.Events.Lease*time.Second,
+	).Run(jobsCtx)
+
 	ac := authClient.NewClient(
 		cfg.AuthenticationService.URL,
 		cfg.AuthenticationService.Timeout*time.Second,
 		logger,
 	)
 
+	mc := movieClient.NewClient(
+		cfg.MovieService.URL,
+		cfg.MovieService.Timeout*time.Second,
+		cfg.MovieService.CacheTTL*time.Second,
+		logger,
+	)
+
+	if cfg.MovieService.ServiceKey == "" {
+		return errors.New("movie service key is required")
+	}
+
+	im := importer.NewImporter(
+		db,
+		mc,
+		logger,
+		cfg.MovieService.ServiceKey,
+		cfg.Import.Workers,
+		cfg.Import.QueueSize,
+		cfg.Import.MaxFileSize,
+		cfg.Import.MaxRows,
+	)
+	go im.Run(jobsCtx)
+
+	md := moderation.NewDetector(
+		cfg.Moderation.Window*time.Second,
+		cfg.Moderation.NewAccountAge*time.Second,
+		cfg.Moderation.MaxNewAccountRatings,
+	)
+
+	hub := live.NewHub(
+		events.NewSubscriber(redisClient, cfg.Events.Stream, logger),
+		db,
+		logger,
+		cfg.Live.Heartbeat*time.Second,
+		cfg.Live.BufferSize,
+		cfg.Live.MaxReplay,
+	)
+	go hub.Run(jobsCtx)
+
 	server := http.Server{
 		Addr:         cfg.RatingService.Server.Port,
-		Handler:      router.New(db, logger, ac).GetHandler(),
+		Handler:      router.New(db, logger, ac, mc, im, md, hub, cfg.RatingService.ServiceKey).GetHandler(),
 		ReadTimeout:  cfg.RatingService.Server.ReadTimeout * time.Second,
 		WriteTimeout: cfg.RatingService.Server.WriteTimeout * time.Second,
 		IdleTimeout:  cfg.RatingService.Server.IdleTimeout * time.Second,
 	}
 
+	// streaming connections never finish on their own, so they are closed for the shutdown to complete
+	server.RegisterOnShutdown(hub.Close)
+
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("server failed to listen and serve", log.Error(err))
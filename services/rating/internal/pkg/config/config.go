@@ -12,6 +12,7 @@ type Config struct {
 	RatingService struct {
 		Environment string `mapstructure:"environment"`
 		LogLevel    string `mapstructure:"log_level"`
+		ServiceKey  string `mapstructure:"service_key"`
 		Server      struct {
 			Port         string        `mapstructure:"port"`
 			ReadTimeout  time.Duration `mapstructure:"read_timeout"`
@@ -20,15 +21,70 @@ type Config struct {
 		} `mapstructure:"server"`
 	} `mapstructure:"rating_service"`
 	MongoDB struct {
-		URI        string        `mapstructure:"uri"`
-		DBName     string        `mapstructure:"db_name"`
-		Collection string        `mapstructure:"collection"`
-		Timeout    time.Duration `mapstructure:"timeout"`
+		URI                    string        `mapstructure:"uri"`
+		DBName                 string        `mapstructure:"db_name"`
+		Collection             string        `mapstructure:"collection"`
+		VotesCollection        string        `mapstructure:"votes_collection"`
+		SimilaritiesCollection string        `mapstructure:"similarities_collection"`
+		GenresCollection       string        `mapstructure:"genres_collection"`
+		LeaderboardsCollection string        `mapstructure:"leaderboards_collection"`
+		OutboxCollection       string        `mapstructure:"outbox_collection"`
+		ImportsCollection      string        `mapstructure:"imports_collection"`
+		WatchlistCollection    string        `mapstructure:"watchlist_collection"`
+		DiaryCollection        string        `mapstructure:"diary_collection"`
+		StatsCollection        string        `mapstructure:"stats_collection"`
+		VelocityCollection     string        `mapstructure:"velocity_collection"`
+		Timeout                time.Duration `mapstructure:"timeout"`
 	} `mapstructure:"mongodb"`
+	Recommendation struct {
+		Interval          time.Duration `mapstructure:"interval"`
+		MaxRatingsPerUser int           `mapstructure:"max_ratings_per_user"`
+		MaxNeighbors      int           `mapstructure:"max_neighbors"`
+		MinSupport        int           `mapstructure:"min_support"`
+	} `mapstructure:"recommendation"`
+	Leaderboard struct {
+		Interval         time.Duration `mapstructure:"interval"`
+		Size             int           `mapstructure:"size"`
+		MinRatings       int           `mapstructure:"min_ratings"`
+		TrendingWindow   time.Duration `mapstructure:"trending_window"`
+		TrendingHalfLife time.Duration `mapstructure:"trending_half_life"`
+	} `mapstructure:"leaderboard"`
+	Events struct {
+		Stream        string        `mapstructure:"stream"`
+		MaxLen        int64         `mapstructure:"max_len"`
+		RelayInterval time.Duration `mapstructure:"relay_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+		Lease         time.Duration `mapstructure:"lease"`
+	} `mapstructure:"events"`
+	Live struct {
+		Heartbeat  time.Duration `mapstructure:"heartbeat"`
+		BufferSize int           `mapstructure:"buffer_size"`
+		MaxReplay  int           `mapstructure:"max_replay"`
+	} `mapstructure:"live"`
+	Import struct {
+		MaxFileSize int64 `mapstructure:"max_file_size"`
+		MaxRows     int   `mapstructure:"max_rows"`
+		Workers     int   `mapstructure:"workers"`
+		QueueSize   int   `mapstructure:"queue_size"`
+	} `mapstructure:"import"`
+	Moderation struct {
+		Window               time.Duration `mapstructure:"window"`
+		NewAccountAge        time.Duration `mapstructure:"new_account_age"`
+		MaxNewAccountRatings int           `mapstructure:"max_new_account_ratings"`
+	} `mapstructure:"moderation"`
 	AuthenticationService struct {
 		URL     string        `mapstructure:"url"`
 		Timeout time.Duration `mapstructure:"timeout"`
 	} `mapstructure:"authentication_service"`
+	MovieService struct {
+		URL        string        `mapstructure:"url"`
+		Timeout    time.Duration `mapstructure:"timeout"`
+		CacheTTL   time.Duration `mapstructure:"cache_ttl"`
+		ServiceKey string        `mapstructure:"service_key"`
+	} `mapstructure:"movie_service"`
+	Redis struct {
+		Addr string `mapstructure:"addr"`
+	} `mapstructure:"redis"`
 }
 
 // New returns a new instance of Config.
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
@@ -13,15 +14,40 @@ import (
 )
 
 type database struct {
-	logger     *log.Logger
-	client     *mongo.Client
-	name       string
-	collection *mongo.Collection
-	timeout    time.Duration
+	logger       *log.Logger
+	client       *mongo.Client
+	name         string
+	collection   *mongo.Collection
+	votes        *mongo.Collection
+	similarities *mongo.Collection
+	genres       *mongo.Collection
+	leaderboards *mongo.Collection
+	outbox       *mongo.Collection
+	imports      *mongo.Collection
+	watchlist    *mongo.Collection
+	diary        *mongo.Collection
+	stats        *mongo.Collection
+	velocity     *mongo.Collection
+	timeout      time.Duration
+}
+
+// Collections contains the names of the collections used by the database.
+type Collections struct {
+	Ratings      string
+	Votes        string
+	Similarities string
+	Genres       string
+	Leaderboards string
+	Outbox       string
+	Imports      string
+	Watchlist    string
+	Diary        string
+	Stats        string
+	Velocity     string
 }
 
 // New returns a new instance of database.
-func New(ctx context.Context, logger *log.Logger, uri, name, collection string, timeout time.Duration) (domain.Repository, error) {
+func New(ctx context.Context, logger *log.Logger, uri, name string, collections Collections, timeout time.Duration) (domain.Repository, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -35,7 +61,7 @@ func New(ctx context.Context, logger *log.Logger, uri, name, collection string,
 		return nil, err
 	}
 
-	coll := client.Database(name).Collection(collection)
+	coll := client.Database(name).Collection(collections.Ratings)
 
 	// create unique compound index on the "userId" and "movieId" fields
 	// this ensures that a user will have only one rating for a movie
@@ -70,12 +96,265 @@ func New(ctx context.Context, logger *log.Logger, uri, name, collection string,
 		return nil, err
 	}
 
+	// create index on the "updatedAt" field to find recent ratings
+	updatedAtIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "updatedAt", Value: -1}},
+		Options: options.Index(),
+	}
+	_, err = coll.Indexes().CreateOne(ctx, updatedAtIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// create partial index to list the ratings held for moderation
+	heldIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "moderation", Value: 1},
+			{Key: "updatedAt", Value: 1},
+		},
+		Options: options.Index().SetPartialFilterExpression(bson.D{{Key: "moderation", Value: bson.D{{Key: "$exists", Value: true}}}}),
+	}
+	_, err = coll.Indexes().CreateOne(ctx, heldIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// create partial compound indexes to list a movie's reviews by the supported sort orders
+	reviewPartialFilter := bson.D{{Key: "review", Value: bson.D{{Key: "$exists", Value: true}}}}
+	reviewIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "movieId", Value: 1},
+				{Key: "review.createdAt", Value: -1},
+			},
+			Options: options.Index().SetPartialFilterExpression(reviewPartialFilter),
+		},
+		{
+			Keys: bson.D{
+				{Key: "movieId", Value: 1},
+				{Key: "review.helpfulVotes", Value: -1},
+				{Key: "review.createdAt", Value: -1},
+			},
+			Options: options.Index().SetPartialFilterExpression(reviewPartialFilter),
+		},
+	}
+	_, err = coll.Indexes().CreateMany(ctx, reviewIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := client.Database(name).Collection(collections.Votes)
+
+	// create unique compound index on the "userId", "movieId" and "voterId" fields
+	// this ensures that a user will have only one vote for a review
+	voteIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "userId", Value: 1},
+			{Key: "movieId", Value: 1},
+			{Key: "voterId", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = votes.Indexes().CreateOne(ctx, voteIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	similarities := client.Database(name).Collection(collections.Similarities)
+
+	// create unique index on the "movieId" field
+	similarityIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "movieId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = similarities.Indexes().CreateOne(ctx, similarityIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	genres := client.Database(name).Collection(collections.Genres)
+
+	// create unique index on the "movieId" field
+	genreIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "movieId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = genres.Indexes().CreateOne(ctx, genreIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboards := client.Database(name).Collection(collections.Leaderboards)
+
+	// create unique index on the "name" field
+	leaderboardIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = leaderboards.Indexes().CreateOne(ctx, leaderboardIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := client.Database(name).Collection(collections.Imports)
+
+	// create unique index on the "id" field
+	importIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = imports.Indexes().CreateOne(ctx, importIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist := client.Database(name).Collection(collections.Watchlist)
+
+	// create unique index to keep a movie once per watchlist, and indexes to list a watchlist in order,
+	// optionally filtered by genre (case insensitive)
+	watchlistIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "movieId", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "position", Value: 1},
+			},
+			Options: options.Index(),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "genres", Value: 1},
+				{Key: "position", Value: 1},
+			},
+			Options: options.Index().SetCollation(genreCollation),
+		},
+	}
+	_, err = watchlist.Indexes().CreateMany(ctx, watchlistIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	diary := client.Database(name).Collection(collections.Diary)
+
+	// create unique index on the "id" field, and indexes to list a user's diary by date
+	// and to find the latest rated entry of a movie
+	diaryIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "watchedOn", Value: -1},
+				{Key: "createdAt", Value: -1},
+			},
+			Options: options.Index(),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "movieId", Value: 1},
+				{Key: "watchedOn", Value: -1},
+				{Key: "createdAt", Value: -1},
+			},
+			Options: options.Index(),
+		},
+	}
+	_, err = diary.Indexes().CreateMany(ctx, diaryIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := client.Database(name).Collection(collections.Stats)
+
+	// create unique index on the "userId" field, and a TTL index to expire the cached stats
+	statsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "computedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(userStatsRetention.Seconds())),
+		},
+	}
+	_, err = stats.Indexes().CreateMany(ctx, statsIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	velocity := client.Database(name).Collection(collections.Velocity)
+
+	// create unique index to record a new account's rating of a movie once,
+	// and a TTL index to clean up the entries out of the window
+	velocityIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "movieId", Value: 1},
+				{Key: "userId", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = velocity.Indexes().CreateMany(ctx, velocityIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	outbox := client.Database(name).Collection(collections.Outbox)
+
+	// create index to claim the unpublished events in the order they occurred, unique index to mark them
+	// as published by ID, and a TTL index to clean up the published ones
+	outboxIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "publishedAt", Value: 1},
+				{Key: "occurredAt", Value: 1},
+			},
+			Options: options.Index(),
+		},
+		{
+			Keys:    bson.D{{Key: "publishedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(outboxRetention.Seconds())),
+		},
+	}
+	_, err = outbox.Indexes().CreateMany(ctx, outboxIndexes)
+	if err != nil {
+		return nil, err
+	}
+
 	return &database{
-		logger:     logger,
-		client:     client,
-		name:       name,
-		collection: coll,
-		timeout:    timeout,
+		logger:       logger,
+		client:       client,
+		name:         name,
+		collection:   coll,
+		votes:        votes,
+		similarities: similarities,
+		genres:       genres,
+		leaderboards: leaderboards,
+		outbox:       outbox,
+		imports:      imports,
+		watchlist:    watchlist,
+		diary:        diary,
+		stats:        stats,
+		velocity:     velocity,
+		timeout:      timeout,
 	}, nil
 }
 
@@ -87,34 +366,156 @@ func (db *database) Close(ctx context.Context) error {
 	return nil
 }
 
-// Upsert implements domain.Repository interface's Upsert method.
-func (db *database) Upsert(ctx context.Context, rating *domain.ValidatedRating) (*domain.Rating, error) {
-	if rating.IsValid() {
-		filter := bson.M{
-			"userId":  rating.Rating.UserID,
-			"movieId": rating.Rating.MovieID,
-		}
+// withTransaction runs fn in a transaction, retrying it on transient errors.
+func (db *database) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := db.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
 
-		update := bson.M{
-			"$set": rating.Rating,
-		}
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
 
-		updateOptions := options.Update().SetUpsert(true)
+	return err
+}
 
+// Upsert implements domain.Repository interface's Upsert method.
+func (db *database) Upsert(ctx context.Context, rating *domain.ValidatedRating, burst *domain.BurstPolicy) (*domain.Rating, error) {
+	if rating.IsValid() {
 		ctx, cancel := context.WithTimeout(ctx, db.timeout)
 		defer cancel()
 
-		_, err := db.collection.UpdateOne(ctx, filter, update, updateOptions)
+		var r *domain.Rating
+		err := db.withEvents(ctx, func(sc mongo.SessionContext) ([]*domain.Event, error) {
+			var list []*domain.Event
+			var err error
+			r, list, err = db.upsert(sc, rating, burst)
+			return list, err
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		return &rating.Rating, nil
+		return r, nil
 	}
 
 	return nil, errors.New("invalid rating data")
 }
 
+// upsert writes a rating within a transaction, returning the stored rating and the events recording the changes:
+// the ones of the other ratings held along with it if it makes a burst, followed by its own.
+func (db *database) upsert(
+	sc mongo.SessionContext,
+	rating *domain.ValidatedRating,
+	burst *domain.BurstPolicy,
+) (*domain.Rating, []*domain.Event, error) {
+	filter := bson.M{
+		"userId":  rating.Rating.UserID,
+		"movieId": rating.Rating.MovieID,
+	}
+
+	// a re-rated movie keeps the ID and creation time of its first rating, the optional fields left out are kept too
+	in := rating.Rating
+	set := bson.M{
+		"value":     in.Value,
+		"updatedAt": in.UpdatedAt,
+	}
+	if in.Dimensions != nil {
+		set["dimensions"] = in.Dimensions
+	}
+	if in.Review != nil {
+		set["review"] = in.Review
+	}
+	if in.Moderation != "" {
+		set["moderation"] = in.Moderation
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"id":        in.ID,
+			"createdAt": in.CreatedAt,
+		},
+	}
+
+	updateOptions := options.Update().SetUpsert(true)
+
+	res, err := db.collection.UpdateOne(sc, filter, update, updateOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// a rating of a burst is held before its event is recorded, so consumers never see it as visible
+	var list []*domain.Event
+	if burst != nil {
+		list, err = db.checkBurst(sc, rating.Rating.UserID, rating.Rating.MovieID, burst)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// the stored rating may have a review, which is part of the event
+	var r domain.Rating
+	if err := db.collection.FindOne(sc, filter).Decode(&r); err != nil {
+		return nil, nil, err
+	}
+
+	// a rated movie has been watched, so it leaves the user's watchlist
+	if _, err := db.removeFromWatchlist(sc, r.UserID, r.MovieID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.invalidateUserStats(sc, r.UserID); err != nil {
+		return nil, nil, err
+	}
+
+	if res.UpsertedCount > 0 {
+		return &r, append(list, domain.NewEvent(domain.RatingCreated, &r)), nil
+	}
+	return &r, append(list, domain.NewEvent(domain.RatingUpdated, &r)), nil
+}
+
+// errRatingNotFound is returned when there is no rating to delete.
+var errRatingNotFound = errors.New("rating not found")
+
+// Delete implements domain.Repository interface's Delete method.
+func (db *database) Delete(ctx context.Context, userID, movieID string) error {
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	return db.delete(ctx, filter)
+}
+
+// delete removes the rating matching the filter along with the votes on its review, recording a RatingDeleted event.
+func (db *database) delete(ctx context.Context, filter bson.M) error {
+	return db.withEvent(ctx, func(sc mongo.SessionContext) (*domain.Event, error) {
+		var r domain.Rating
+		if err := db.collection.FindOneAndDelete(sc, filter).Decode(&r); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errRatingNotFound
+			}
+			return nil, err
+		}
+
+		if _, err := db.votes.DeleteMany(sc, bson.M{"userId": r.UserID, "movieId": r.MovieID}); err != nil {
+			return nil, err
+		}
+
+		if err := db.invalidateUserStats(sc, r.UserID); err != nil {
+			return nil, err
+		}
+
+		return domain.NewEvent(domain.RatingDeleted, &r), nil
+	})
+}
+
 // FindByUserID implements domain.Repository interface's FindByUserID method.
 func (db *database) FindByUserID(ctx context.Context, userID string) ([]*domain.Rating, error) {
 	filter := bson.D{{Key: "userId", Value: userID}}
@@ -174,3 +575,649 @@ func (db *database) FindByMovieID(ctx context.Context, movieID string) ([]*domai
 
 	return list, nil
 }
+
+// FindByUserAndMovieID implements domain.Repository interface's FindByUserAndMovieID method.
+func (db *database) FindByUserAndMovieID(ctx context.Context, userID, movieID string) (*domain.Rating, error) {
+	filter := bson.D{{Key: "userId", Value: userID}, {Key: "movieId", Value: movieID}}
+
+	var r domain.Rating
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if err := db.collection.FindOne(ctx, filter).Decode(&r); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("rating not found")
+		}
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// FindByUserAndMovieIDs implements domain.Repository interface's FindByUserAndMovieIDs method.
+func (db *database) FindByUserAndMovieIDs(ctx context.Context, userID string, movieIDs []string) ([]*domain.Rating, error) {
+	// a single query on the unique compound index
+	filter := bson.D{
+		{Key: "userId", Value: userID},
+		{Key: "movieId", Value: bson.D{{Key: "$in", Value: movieIDs}}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*domain.Rating{}
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// FindReviewsByMovieID implements domain.Repository interface's FindReviewsByMovieID method.
+func (db *database) FindReviewsByMovieID(ctx context.Context, movieID string, sort domain.ReviewSort, page, limit int) ([]*domain.Rating, error) {
+	filter := bson.D{
+		{Key: "movieId", Value: movieID},
+		{Key: "review", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+
+	sortBy := bson.D{{Key: "review.createdAt", Value: -1}}
+	if sort == domain.ReviewSortHelpful {
+		sortBy = bson.D{{Key: "review.helpfulVotes", Value: -1}, {Key: "review.createdAt", Value: -1}}
+	}
+
+	findOptions := options.Find().
+		SetSort(sortBy).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	var list []*domain.Rating
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var r domain.Rating
+		if err = cursor.Decode(&r); err != nil {
+			return nil, err
+		}
+		list = append(list, &r)
+	}
+
+	if len(list) == 0 {
+		return nil, errors.New("movie has no reviews")
+	}
+
+	return list, nil
+}
+
+// UpdateReview implements domain.Repository interface's UpdateReview method.
+func (db *database) UpdateReview(ctx context.Context, rating *domain.ValidatedRating) (*domain.Rating, error) {
+	if !rating.IsValid() || rating.Rating.Review == nil {
+		return nil, errors.New("invalid rating data")
+	}
+
+	filter := bson.M{
+		"userId":  rating.Rating.UserID,
+		"movieId": rating.Rating.MovieID,
+	}
+
+	// helpful votes are not set here so concurrent votes are not overwritten
+	update := bson.M{
+		"$set": bson.M{
+			"review.title":     rating.Rating.Review.Title,
+			"review.body":      rating.Rating.Review.Body,
+			"review.spoiler":   rating.Rating.Review.Spoiler,
+			"review.language":  rating.Rating.Review.Language,
+			"review.createdAt": rating.Rating.Review.CreatedAt,
+			"review.updatedAt": rating.Rating.Review.UpdatedAt,
+			"updatedAt":        rating.Rating.UpdatedAt,
+		},
+	}
+
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var r domain.Rating
+	err := db.withEvent(ctx, func(sc mongo.SessionContext) (*domain.Event, error) {
+		if err := db.collection.FindOneAndUpdate(sc, filter, update, updateOptions).Decode(&r); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.New("rating not found")
+			}
+			return nil, err
+		}
+
+		return domain.NewEvent(domain.RatingUpdated, &r), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// DeleteReview implements domain.Repository interface's DeleteReview method.
+func (db *database) DeleteReview(ctx context.Context, userID, movieID string) error {
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+		"review":  bson.M{"$exists": true},
+	}
+
+	update := bson.M{
+		"$unset": bson.M{"review": ""},
+		"$set":   bson.M{"updatedAt": time.Now()},
+	}
+
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	return db.withEvent(ctx, func(sc mongo.SessionContext) (*domain.Event, error) {
+		var r domain.Rating
+		if err := db.collection.FindOneAndUpdate(sc, filter, update, updateOptions).Decode(&r); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, domain.ErrReviewNotFound
+			}
+			return nil, err
+		}
+
+		// votes belong to the removed review, so a new review starts from scratch
+		_, err := db.votes.DeleteMany(sc, bson.M{"userId": userID, "movieId": movieID})
+		if err != nil {
+			return nil, err
+		}
+
+		return domain.NewEvent(domain.RatingUpdated, &r), nil
+	})
+}
+
+// Vote implements domain.Repository interface's Vote method.
+func (db *database) Vote(ctx context.Context, vote *domain.ValidatedVote) (*domain.Vote, error) {
+	if !vote.IsValid() {
+		return nil, errors.New("invalid vote data")
+	}
+
+	filter := bson.M{
+		"userId":  vote.Vote.UserID,
+		"movieId": vote.Vote.MovieID,
+		"voterId": vote.Vote.VoterID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"helpful":   vote.Vote.Helpful,
+			"updatedAt": vote.Vote.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"id":        vote.Vote.ID,
+			"createdAt": vote.Vote.CreatedAt,
+		},
+	}
+
+	updateOptions := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.Before)
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	// the vote and the review's counts change together, or not at all
+	v := &vote.Vote
+	err := db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		inc := bson.M{voteCountField(vote.Vote.Helpful): 1}
+
+		var prev domain.Vote
+		err := db.votes.FindOneAndUpdate(sc, filter, update, updateOptions).Decode(&prev)
+		switch {
+		case err == mongo.ErrNoDocuments:
+			// first vote of this user on the review
+		case err != nil:
+			return err
+		case prev.Helpful == vote.Vote.Helpful:
+			// same vote again, counts stay the same
+			prev.UpdatedAt = vote.Vote.UpdatedAt
+			v = &prev
+			return nil
+		default:
+			// vote changed sides
+			inc[voteCountField(prev.Helpful)] = -1
+			vote.Vote.ID = prev.ID
+			vote.Vote.CreatedAt = prev.CreatedAt
+		}
+
+		return db.incrementVoteCounts(sc, vote.Vote.UserID, vote.Vote.MovieID, inc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// DeleteVote implements domain.Repository interface's DeleteVote method.
+func (db *database) DeleteVote(ctx context.Context, userID, movieID, voterID string) error {
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+		"voterId": voterID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	return db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var prev domain.Vote
+		if err := db.votes.FindOneAndDelete(sc, filter).Decode(&prev); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errors.New("vote not found")
+			}
+			return err
+		}
+
+		return db.incrementVoteCounts(sc, userID, movieID, bson.M{voteCountField(prev.Helpful): -1})
+	})
+}
+
+// incrementVoteCounts changes the vote counts of a review within a transaction.
+// It returns domain.ErrReviewNotFound if the rating has no review, so the vote is not kept either.
+func (db *database) incrementVoteCounts(sc mongo.SessionContext, userID, movieID string, inc bson.M) error {
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+		"review":  bson.M{"$exists": true},
+	}
+
+	res, err := db.collection.UpdateOne(sc, filter, bson.M{"$inc": inc})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrReviewNotFound
+	}
+
+	return nil
+}
+
+func voteCountField(helpful bool) string {
+	if helpful {
+		return "review.helpfulVotes"
+	}
+	return "review.unhelpfulVotes"
+}
+
+// ForEachUserRatings implements domain.Repository interface's ForEachUserRatings method.
+func (db *database) ForEachUserRatings(ctx context.Context, fn func(userID string, ratings []*domain.Rating) error) error {
+	// the whole collection is scanned, thus no timeout other than the given context's
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "userId", Value: 1}}).
+		SetProjection(bson.D{
+			{Key: "userId", Value: 1},
+			{Key: "movieId", Value: 1},
+			{Key: "value", Value: 1},
+			{Key: "updatedAt", Value: 1},
+		}).
+		SetBatchSize(10000)
+
+	// held ratings are left out of the recommendations
+	filter := bson.M{"moderation": notHeld}
+
+	cursor, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		userID string
+		list   []*domain.Rating
+	)
+
+	for cursor.Next(ctx) {
+		var r domain.Rating
+		if err = cursor.Decode(&r); err != nil {
+			return err
+		}
+		if r.UserID != userID && len(list) > 0 {
+			if err = fn(userID, list); err != nil {
+				return err
+			}
+			list = nil
+		}
+		userID = r.UserID
+		list = append(list, &r)
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(list) > 0 {
+		return fn(userID, list)
+	}
+
+	return nil
+}
+
+// ReplaceSimilarities implements domain.Repository interface's ReplaceSimilarities method.
+func (db *database) ReplaceSimilarities(ctx context.Context, similarities []*domain.Similarity) error {
+	const batchSize = 1000
+
+	// MongoDB stores dates with millisecond precision
+	startedAt := time.Now().Truncate(time.Millisecond)
+
+	for i := 0; i < len(similarities); i += batchSize {
+		end := i + batchSize
+		if end > len(similarities) {
+			end = len(similarities)
+		}
+
+		models := make([]mongo.WriteModel, 0, end-i)
+		for _, s := range similarities[i:end] {
+			// every document of this build shares the same timestamp, so older ones can be told apart
+			s.UpdatedAt = startedAt
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"movieId": s.MovieID}).
+				SetReplacement(s).
+				SetUpsert(true))
+		}
+
+		bulkCtx, cancel := context.WithTimeout(ctx, db.timeout)
+		_, err := db.similarities.BulkWrite(bulkCtx, models, options.BulkWrite().SetOrdered(false))
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	// movies which are not similar to any other anymore
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.similarities.DeleteMany(ctx, bson.M{"updatedAt": bson.M{"$lt": startedAt}})
+	return err
+}
+
+// FindSimilarities implements domain.Repository interface's FindSimilarities method.
+func (db *database) FindSimilarities(ctx context.Context, movieIDs []string) ([]*domain.Similarity, error) {
+	filter := bson.M{"movieId": bson.M{"$in": movieIDs}}
+
+	var list []*domain.Similarity
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.similarities.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var s domain.Similarity
+		if err = cursor.Decode(&s); err != nil {
+			return nil, err
+		}
+		list = append(list, &s)
+	}
+
+	return list, nil
+}
+
+// SaveMovieGenres implements domain.Repository interface's SaveMovieGenres method.
+func (db *database) SaveMovieGenres(ctx context.Context, movieID string, genres []string) error {
+	filter := bson.M{"movieId": movieID}
+
+	update := bson.M{
+		"$set": bson.M{
+			"genres":    genres,
+			"updatedAt": time.Now(),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.genres.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// FindMovieGenres implements domain.Repository interface's FindMovieGenres method.
+func (db *database) FindMovieGenres(ctx context.Context, movieIDs []string) (map[string][]string, error) {
+	filter := bson.M{"movieId": bson.M{"$in": movieIDs}}
+
+	genres := make(map[string][]string, len(movieIDs))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.genres.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var mg struct {
+			MovieID string   `bson:"movieId"`
+			Genres  []string `bson:"genres"`
+		}
+		if err = cursor.Decode(&mg); err != nil {
+			return nil, err
+		}
+		genres[mg.MovieID] = mg.Genres
+	}
+
+	return genres, nil
+}
+
+// ForEachMovieStats implements domain.Repository interface's ForEachMovieStats method.
+func (db *database) ForEachMovieStats(ctx context.Context, fn func(stats domain.MovieStats) error) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"moderation": notHeld}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$movieId"},
+			{Key: "ratings", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "sum", Value: bson.D{{Key: "$sum", Value: "$value"}}},
+		}}},
+	}
+
+	// the whole collection is aggregated, thus no timeout other than the given context's
+	cursor, err := db.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var ms domain.MovieStats
+		if err = cursor.Decode(&ms); err != nil {
+			return err
+		}
+		if err = fn(ms); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// ForEachRatingSince implements domain.Repository interface's ForEachRatingSince method.
+func (db *database) ForEachRatingSince(ctx context.Context, since time.Time, fn func(rating *domain.Rating) error) error {
+	filter := bson.M{
+		"updatedAt":  bson.M{"$gte": since},
+		"moderation": notHeld,
+	}
+
+	findOptions := options.Find().
+		SetProjection(bson.D{
+			{Key: "userId", Value: 1},
+			{Key: "movieId", Value: 1},
+			{Key: "value", Value: 1},
+			{Key: "updatedAt", Value: 1},
+		}).
+		SetBatchSize(10000)
+
+	cursor, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var r domain.Rating
+		if err = cursor.Decode(&r); err != nil {
+			return err
+		}
+		if err = fn(&r); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// ReplaceLeaderboards implements domain.Repository interface's ReplaceLeaderboards method.
+func (db *database) ReplaceLeaderboards(ctx context.Context, leaderboards []*domain.Leaderboard) error {
+	// MongoDB stores dates with millisecond precision
+	startedAt := time.Now().Truncate(time.Millisecond)
+
+	models := make([]mongo.WriteModel, 0, len(leaderboards))
+	for _, l := range leaderboards {
+		// every document of this build shares the same timestamp, so older ones can be told apart
+		l.UpdatedAt = startedAt
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"name": l.Name}).
+			SetReplacement(l).
+			SetUpsert(true))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if len(models) > 0 {
+		_, err := db.leaderboards.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return err
+		}
+	}
+
+	// genres which have no rated movies anymore
+	_, err := db.leaderboards.DeleteMany(ctx, bson.M{"updatedAt": bson.M{"$lt": startedAt}})
+	return err
+}
+
+// FindLeaderboard implements domain.Repository interface's FindLeaderboard method.
+func (db *database) FindLeaderboard(ctx context.Context, name string) (*domain.Leaderboard, error) {
+	filter := bson.D{{Key: "name", Value: name}}
+
+	var l domain.Leaderboard
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if err := db.leaderboards.FindOne(ctx, filter).Decode(&l); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("leaderboard %s doesn't exist", name)
+		}
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// CreateImport implements domain.Repository interface's CreateImport method.
+func (db *database) CreateImport(ctx context.Context, imp *domain.Import) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.imports.InsertOne(ctx, imp)
+	return err
+}
+
+// UpdateImport implements domain.Repository interface's UpdateImport method.
+func (db *database) UpdateImport(ctx context.Context, imp *domain.Import) error {
+	filter := bson.D{{Key: "id", Value: imp.ID}}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	res, err := db.imports.ReplaceOne(ctx, filter, imp)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("import %s doesn't exist", imp.ID)
+	}
+
+	return nil
+}
+
+// FindImport implements domain.Repository interface's FindImport method.
+func (db *database) FindImport(ctx context.Context, id string) (*domain.Import, error) {
+	filter := bson.D{{Key: "id", Value: id}}
+
+	var imp domain.Import
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if err := db.imports.FindOne(ctx, filter).Decode(&imp); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("import %s doesn't exist", id)
+		}
+		return nil, err
+	}
+
+	return &imp, nil
+}
+
+// FindRatingSummary implements domain.Repository interface's FindRatingSummary method.
+func (db *database) FindRatingSummary(ctx context.Context, movieID string) (*domain.RatingSummary, error) {
+	group := bson.M{
+		"_id":     "$value",
+		"ratings": bson.M{"$sum": 1},
+	}
+	dimensions := bson.M{}
+	for _, d := range domain.Dimensions {
+		field := "$dimensions." + d
+		group[d+"Sum"] = bson.M{"$sum": field}
+		group[d+"Count"] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{field, false}}, 1, 0}}}
+		dimensions[d] = bson.M{"sum": "$" + d + "Sum", "count": "$" + d + "Count"}
+	}
+
+	// ratings are grouped by value, which also gives their distribution
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"movieId": movieID, "moderation": notHeld}}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$project", Value: bson.M{"ratings": 1, "dimensions": dimensions}}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []domain.ValueStats
+	if err = cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	return domain.SummarizeRatings(movieID, stats), nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LogDiaryEntry implements domain.Repository interface's LogDiaryEntry method.
+func (db *database) LogDiaryEntry(
+	ctx context.Context,
+	entry *domain.ValidatedDiaryEntry,
+	burst *domain.BurstPolicy,
+) (*domain.DiaryEntry, error) {
+	if !entry.IsValid() {
+		return nil, errors.New("invalid diary entry data")
+	}
+
+	e := entry.DiaryEntry
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	err := db.withEvents(ctx, func(sc mongo.SessionContext) ([]*domain.Event, error) {
+		if _, err := db.diary.InsertOne(sc, e); err != nil {
+			return nil, err
+		}
+
+		// a watched movie leaves the user's watchlist, even if it was not rated
+		if _, err := db.removeFromWatchlist(sc, e.UserID, e.MovieID); err != nil {
+			return nil, err
+		}
+
+		if e.Value == nil {
+			return nil, nil
+		}
+
+		// backfilled entries don't override a more recent opinion, be it logged in the diary or rated directly
+		latest, err := db.latestRatedDiaryEntry(sc, e.UserID, e.MovieID)
+		if err != nil || latest == nil || latest.ID != e.ID {
+			return nil, err
+		}
+
+		var current domain.Rating
+		err = db.collection.FindOne(sc, bson.M{"userId": e.UserID, "movieId": e.MovieID}).Decode(&current)
+		switch {
+		case err == nil:
+			if latest.Predates(&current) {
+				return nil, nil
+			}
+		case err != mongo.ErrNoDocuments:
+			return nil, err
+		}
+
+		return db.rateFromDiary(sc, latest, burst)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// DeleteDiaryEntry implements domain.Repository interface's DeleteDiaryEntry method.
+func (db *database) DeleteDiaryEntry(ctx context.Context, userID, id string) error {
+	filter := bson.M{
+		"id":     id,
+		"userId": userID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	return db.withEvents(ctx, func(sc mongo.SessionContext) ([]*domain.Event, error) {
+		var deleted domain.DiaryEntry
+		if err := db.diary.FindOneAndDelete(sc, filter).Decode(&deleted); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.New("diary entry not found")
+			}
+			return nil, err
+		}
+
+		if deleted.Value == nil {
+			return nil, nil
+		}
+
+		// only the removal of the latest rated entry changes the user's opinion,
+		// which falls back to the previous rated entry, if any
+		latest, err := db.latestRatedDiaryEntry(sc, userID, deleted.MovieID)
+		if err != nil || latest == nil || latest.After(&deleted) {
+			return nil, err
+		}
+
+		// falling back to a former opinion is not a new rating, which the burst detection would count
+		return db.rateFromDiary(sc, latest, nil)
+	})
+}
+
+// FindDiary implements domain.Repository interface's FindDiary method.
+func (db *database) FindDiary(ctx context.Context, userID string, year, page, limit int) ([]*domain.DiaryEntry, error) {
+	filter := bson.M{"userId": userID}
+	if year > 0 {
+		filter["watchedOn"] = bson.M{
+			"$gte": time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+			"$lt":  time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "watchedOn", Value: -1}, {Key: "createdAt", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.diary.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*domain.DiaryEntry{}
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// latestRatedDiaryEntry returns the most recently watched entry of a movie that has a rating value, if any.
+func (db *database) latestRatedDiaryEntry(sc mongo.SessionContext, userID, movieID string) (*domain.DiaryEntry, error) {
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+		"value":   bson.M{"$exists": true},
+	}
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "watchedOn", Value: -1}, {Key: "createdAt", Value: -1}})
+
+	var e domain.DiaryEntry
+	if err := db.diary.FindOne(sc, filter, findOptions).Decode(&e); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// rateFromDiary sets the user's rating of the movie to the value of a diary entry,
+// returning the events recording the changes, if there were any.
+func (db *database) rateFromDiary(
+	sc mongo.SessionContext,
+	entry *domain.DiaryEntry,
+	burst *domain.BurstPolicy,
+) ([]*domain.Event, error) {
+	rat := domain.NewRating(entry.UserID, entry.MovieID, *entry.Value)
+
+	var current domain.Rating
+	err := db.collection.FindOne(sc, bson.M{"userId": entry.UserID, "movieId": entry.MovieID}).Decode(&current)
+	switch {
+	case err == nil:
+		if current.Value == *entry.Value {
+			return nil, nil
+		}
+		rat.ID, rat.CreatedAt = current.ID, current.CreatedAt
+	case err != mongo.ErrNoDocuments:
+		return nil, err
+	}
+
+	vr, err := domain.NewValidatedRating(rat)
+	if err != nil {
+		return nil, err
+	}
+
+	_, list, err := db.upsert(sc, vr, burst)
+	return list, err
+}
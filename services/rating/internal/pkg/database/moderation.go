@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// notHeld matches the ratings that count towards the aggregates and leaderboards.
+var notHeld = bson.M{"$ne": domain.ModerationHeld}
+
+// checkBurst records that a new account rated a movie and, if the new accounts that rated it within the window make
+// a burst, holds all their ratings. It returns the events recording the ratings held but the one of the given user,
+// whose event is recorded by the caller.
+func (db *database) checkBurst(sc mongo.SessionContext, userID, movieID string, burst *domain.BurstPolicy) ([]*domain.Event, error) {
+	userIDs, err := db.recordNewAccountRating(sc, userID, movieID, burst.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	if !burst.IsBurst(len(userIDs)) {
+		return nil, nil
+	}
+
+	return db.holdRatings(sc, userID, movieID, userIDs)
+}
+
+// recordNewAccountRating records that a new account rated a movie, returning the IDs of all the new accounts
+// that rated it within the window.
+func (db *database) recordNewAccountRating(sc mongo.SessionContext, userID, movieID string, window time.Duration) ([]string, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"movieId": movieID,
+		"userId":  userID,
+	}
+
+	// entries expire once they are out of the window, so the collection only holds the recent activity
+	update := bson.M{
+		"$set": bson.M{
+			"ratedAt":   now,
+			"expiresAt": now.Add(window),
+		},
+	}
+
+	_, err := db.velocity.UpdateOne(sc, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+
+	filter = bson.M{
+		"movieId": movieID,
+		"ratedAt": bson.M{"$gte": now.Add(-window)},
+	}
+
+	cursor, err := db.velocity.Find(sc, filter, options.Find().SetProjection(bson.M{"userId": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		UserID string `bson:"userId"`
+	}
+	if err = cursor.All(sc, &entries); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		userIDs = append(userIDs, e.UserID)
+	}
+
+	return userIDs, nil
+}
+
+// holdRatings holds the not yet moderated ratings given to a movie by the given users,
+// returning a RatingUpdated event per rating held but the one of the rating user.
+func (db *database) holdRatings(sc mongo.SessionContext, ratingUserID, movieID string, userIDs []string) ([]*domain.Event, error) {
+	filter := bson.M{
+		"movieId":    movieID,
+		"userId":     bson.M{"$in": userIDs},
+		"moderation": bson.M{"$exists": false},
+	}
+
+	cursor, err := db.collection.Find(sc, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var held []*domain.Rating
+	if err = cursor.All(sc, &held); err != nil {
+		return nil, err
+	}
+	if len(held) == 0 {
+		return nil, nil
+	}
+
+	update := bson.M{
+		"$set": bson.M{"moderation": domain.ModerationHeld},
+	}
+
+	if _, err := db.collection.UpdateMany(sc, filter, update); err != nil {
+		return nil, err
+	}
+
+	list := []*domain.Event{}
+	for _, r := range held {
+		if r.UserID == ratingUserID {
+			continue
+		}
+		r.Moderation = domain.ModerationHeld
+		list = append(list, domain.NewEvent(domain.RatingUpdated, r))
+	}
+
+	return list, nil
+}
+
+// FindHeldRatings implements domain.Repository interface's FindHeldRatings method.
+func (db *database) FindHeldRatings(ctx context.Context, page, limit int) ([]*domain.Rating, error) {
+	filter := bson.M{"moderation": domain.ModerationHeld}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*domain.Rating{}
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ApproveRating implements domain.Repository interface's ApproveRating method.
+func (db *database) ApproveRating(ctx context.Context, userID, movieID string) (*domain.Rating, error) {
+	filter := bson.M{
+		"userId":     userID,
+		"movieId":    movieID,
+		"moderation": domain.ModerationHeld,
+	}
+
+	update := bson.M{
+		"$set": bson.M{"moderation": domain.ModerationApproved},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var r domain.Rating
+	err := db.withEvent(ctx, func(sc mongo.SessionContext) (*domain.Event, error) {
+		findOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := db.collection.FindOneAndUpdate(sc, filter, update, findOptions).Decode(&r); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.New("held rating not found")
+			}
+			return nil, err
+		}
+
+		// the approved rating shows up in the feeds and live updates from now on
+		return domain.NewEvent(domain.RatingUpdated, &r), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// RejectRating implements domain.Repository interface's RejectRating method.
+func (db *database) RejectRating(ctx context.Context, userID, movieID string) error {
+	filter := bson.M{
+		"userId":     userID,
+		"movieId":    movieID,
+		"moderation": domain.ModerationHeld,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	err := db.delete(ctx, filter)
+	if err == errRatingNotFound {
+		return errors.New("held rating not found")
+	}
+	return err
+}
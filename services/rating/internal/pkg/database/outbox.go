@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// outboxRetention is how long published events are kept in the outbox.
+const outboxRetention = 7 * 24 * time.Hour
+
+// withEvent runs fn in a transaction, writing the event it returns to the outbox in the same transaction.
+// fn returns a nil event when no rating changed.
+func (db *database) withEvent(ctx context.Context, fn func(sc mongo.SessionContext) (*domain.Event, error)) error {
+	return db.withEvents(ctx, func(sc mongo.SessionContext) ([]*domain.Event, error) {
+		event, err := fn(sc)
+		if err != nil || event == nil {
+			return nil, err
+		}
+		return []*domain.Event{event}, nil
+	})
+}
+
+// withEvents is withEvent for changes of several ratings at once, e.g. when a burst is held.
+func (db *database) withEvents(ctx context.Context, fn func(sc mongo.SessionContext) ([]*domain.Event, error)) error {
+	return db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		list, err := fn(sc)
+		if err != nil || len(list) == 0 {
+			return err
+		}
+
+		docs := make([]interface{}, 0, len(list))
+		for _, event := range list {
+			docs = append(docs, event)
+		}
+
+		_, err = db.outbox.InsertMany(sc, docs)
+		return err
+	})
+}
+
+// ClaimEvents implements domain.Repository interface's ClaimEvents method.
+func (db *database) ClaimEvents(ctx context.Context, relayID string, lease time.Duration, limit int) ([]*domain.Event, error) {
+	now := time.Now()
+
+	// unpublished events which are not locked by a relay, or whose lease expired
+	claimable := bson.M{
+		"publishedAt": nil,
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.outbox.Find(ctx, claimable, options.Find().
+		SetSort(bson.D{{Key: "publishedAt", Value: 1}, {Key: "occurredAt", Value: 1}}).
+		SetProjection(bson.M{"id": 1}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []struct {
+		ID string `bson:"id"`
+	}
+	if err = cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+
+	// another relay may claim some of the candidates first, the filter keeps it from stealing them
+	claimable["id"] = bson.M{"$in": ids}
+	_, err = db.outbox.UpdateMany(ctx, claimable, bson.M{
+		"$set": bson.M{
+			"lockedBy":    relayID,
+			"lockedUntil": now.Add(lease),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err = db.outbox.Find(ctx, bson.M{
+		"id":          bson.M{"$in": ids},
+		"lockedBy":    relayID,
+		"publishedAt": nil,
+	}, options.Find().SetSort(bson.D{{Key: "occurredAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*domain.Event
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// MarkEventsPublished implements domain.Repository interface's MarkEventsPublished method.
+func (db *database) MarkEventsPublished(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.outbox.UpdateMany(ctx, bson.M{"id": bson.M{"$in": ids}}, bson.M{
+		"$set":   bson.M{"publishedAt": time.Now()},
+		"$unset": bson.M{"lockedBy": "", "lockedUntil": ""},
+	})
+	return err
+}
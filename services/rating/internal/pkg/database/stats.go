@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userStatsRetention is how long cached user stats are kept at most.
+// They are dropped whenever the user's ratings change, the expiration only bounds how long they are kept unread.
+const userStatsRetention = time.Hour
+
+// cachedUserStats is how the UserStats are stored. The version counts the changes of the user's ratings,
+// so stats computed while a rating was being changed are not cached, and stale stats were dropped by such a change.
+type cachedUserStats struct {
+	domain.UserStats `bson:",inline"`
+	Version          int64 `bson:"version"`
+	Stale            bool  `bson:"stale,omitempty"`
+}
+
+// FindUserStats implements domain.Repository interface's FindUserStats method.
+func (db *database) FindUserStats(ctx context.Context, userID string) (*domain.UserStats, int64, error) {
+	filter := bson.M{"userId": userID}
+
+	var s cachedUserStats
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if err := db.stats.FindOne(ctx, filter).Decode(&s); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, 0, domain.ErrUserStatsNotCached
+		}
+		return nil, 0, err
+	}
+	if s.Stale {
+		return nil, s.Version, domain.ErrUserStatsNotCached
+	}
+
+	return &s.UserStats, s.Version, nil
+}
+
+// SaveUserStats implements domain.Repository interface's SaveUserStats method.
+func (db *database) SaveUserStats(ctx context.Context, stats *domain.UserStats, version int64) error {
+	// matches nothing once the ratings changed, so the unique index rejects the upsert
+	filter := bson.M{"userId": stats.UserID, "version": version}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.stats.ReplaceOne(ctx, filter, cachedUserStats{UserStats: *stats, Version: version}, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// invalidateUserStats drops the cached stats of a user, within the transaction changing their ratings.
+func (db *database) invalidateUserStats(sc mongo.SessionContext, userID string) error {
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+		// the stale stats expire like the cached ones, by then no stats computed before are being saved
+		"$set": bson.M{"stale": true, "computedAt": time.Now()},
+	}
+
+	_, err := db.stats.UpdateOne(sc, bson.M{"userId": userID}, update, options.Update().SetUpsert(true))
+	return err
+}
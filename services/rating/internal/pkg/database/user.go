@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DeleteUser implements domain.Repository interface's DeleteUser method.
+func (db *database) DeleteUser(ctx context.Context, userID string) (int, error) {
+	deleted, err := db.deleteUserRatings(ctx, userID)
+	if err != nil {
+		return deleted, err
+	}
+
+	if err := db.deleteUserVotes(ctx, userID); err != nil {
+		return deleted, err
+	}
+
+	// the rest is only ever read along with the user's own data
+	for _, coll := range []*mongo.Collection{db.watchlist, db.diary, db.stats, db.velocity, db.imports} {
+		if err := db.deleteMany(ctx, coll, bson.M{"userId": userID}); err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// deleteUserRatings removes the ratings of a user one by one, so each records its RatingDeleted event
+// and the aggregates built from the events catch up.
+func (db *database) deleteUserRatings(ctx context.Context, userID string) (int, error) {
+	movieIDs, err := db.distinctStrings(ctx, db.collection, "movieId", bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, movieID := range movieIDs {
+		err := func() error {
+			ctx, cancel := context.WithTimeout(ctx, db.timeout)
+			defer cancel()
+
+			return db.delete(ctx, bson.M{"userId": userID, "movieId": movieID})
+		}()
+		if err == errRatingNotFound {
+			// deleted meanwhile
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// deleteUserVotes removes the votes a user cast on other users' reviews, keeping the reviews' vote counts in sync.
+func (db *database) deleteUserVotes(ctx context.Context, userID string) error {
+	ids, err := db.distinctStrings(ctx, db.votes, "id", bson.M{"voterId": userID})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := func() error {
+			ctx, cancel := context.WithTimeout(ctx, db.timeout)
+			defer cancel()
+
+			return db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+				var v domain.Vote
+				if err := db.votes.FindOneAndDelete(sc, bson.M{"id": id}).Decode(&v); err != nil {
+					if err == mongo.ErrNoDocuments {
+						// deleted meanwhile
+						return nil
+					}
+					return err
+				}
+
+				err := db.incrementVoteCounts(sc, v.UserID, v.MovieID, bson.M{voteCountField(v.Helpful): -1})
+				if err == domain.ErrReviewNotFound {
+					// no counts to keep in sync
+					return nil
+				}
+				return err
+			})
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *database) distinctStrings(ctx context.Context, coll *mongo.Collection, field string, filter bson.M) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	values, err := coll.Distinct(ctx, field, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			list = append(list, s)
+		}
+	}
+
+	return list, nil
+}
+
+func (db *database) deleteMany(ctx context.Context, coll *mongo.Collection, filter bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := coll.DeleteMany(ctx, filter)
+	return err
+}
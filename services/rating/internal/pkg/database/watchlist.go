@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// genreCollation compares genres ignoring case.
+var genreCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// AddToWatchlist implements domain.Repository interface's AddToWatchlist method.
+func (db *database) AddToWatchlist(ctx context.Context, entry *domain.ValidatedWatchlistEntry) (*domain.WatchlistEntry, error) {
+	if !entry.IsValid() {
+		return nil, errors.New("invalid watchlist entry data")
+	}
+
+	e := entry.WatchlistEntry
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	err := db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		// new entries go to the end of the watchlist
+		count, err := db.watchlist.CountDocuments(sc, bson.M{"userId": e.UserID})
+		if err != nil {
+			return err
+		}
+		e.Position = int(count) + 1
+
+		_, err = db.watchlist.InsertOne(sc, e)
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrWatchlistEntryExists
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// UpdateWatchlistNote implements domain.Repository interface's UpdateWatchlistNote method.
+func (db *database) UpdateWatchlistNote(ctx context.Context, userID, movieID, note string) (*domain.WatchlistEntry, error) {
+	if err := domain.ValidateWatchlistNote(note); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"note":      note,
+			"updatedAt": time.Now(),
+		},
+	}
+
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var e domain.WatchlistEntry
+	if err := db.watchlist.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&e); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("movie is not in the watchlist")
+		}
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// MoveWatchlistEntry implements domain.Repository interface's MoveWatchlistEntry method.
+func (db *database) MoveWatchlistEntry(ctx context.Context, userID, movieID string, position int) (*domain.WatchlistEntry, error) {
+	filter := bson.M{
+		"userId":  userID,
+		"movieId": movieID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var e domain.WatchlistEntry
+	err := db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := db.watchlist.FindOne(sc, filter).Decode(&e); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errors.New("movie is not in the watchlist")
+			}
+			return err
+		}
+
+		count, err := db.watchlist.CountDocuments(sc, bson.M{"userId": userID})
+		if err != nil {
+			return err
+		}
+		if position < 1 {
+			position = 1
+		}
+		if position > int(count) {
+			position = int(count)
+		}
+
+		// shift the entries between the old and the new position to make room for the moved one
+		var shifted bson.M
+		var inc int
+		switch {
+		case position < e.Position:
+			shifted, inc = bson.M{"$gte": position, "$lt": e.Position}, 1
+		case position > e.Position:
+			shifted, inc = bson.M{"$gt": e.Position, "$lte": position}, -1
+		default:
+			return nil
+		}
+
+		_, err = db.watchlist.UpdateMany(sc, bson.M{"userId": userID, "position": shifted}, bson.M{"$inc": bson.M{"position": inc}})
+		if err != nil {
+			return err
+		}
+
+		e.Position, e.UpdatedAt = position, time.Now()
+		_, err = db.watchlist.UpdateOne(sc, filter, bson.M{
+			"$set": bson.M{
+				"position":  e.Position,
+				"updatedAt": e.UpdatedAt,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// RemoveFromWatchlist implements domain.Repository interface's RemoveFromWatchlist method.
+func (db *database) RemoveFromWatchlist(ctx context.Context, userID, movieID string) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	return db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		removed, err := db.removeFromWatchlist(sc, userID, movieID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return errors.New("movie is not in the watchlist")
+		}
+		return nil
+	})
+}
+
+// removeFromWatchlist deletes an entry within a transaction, closing the gap it leaves in the positions.
+func (db *database) removeFromWatchlist(sc mongo.SessionContext, userID, movieID string) (bool, error) {
+	var e domain.WatchlistEntry
+	err := db.watchlist.FindOneAndDelete(sc, bson.M{"userId": userID, "movieId": movieID}).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = db.watchlist.UpdateMany(
+		sc,
+		bson.M{"userId": userID, "position": bson.M{"$gt": e.Position}},
+		bson.M{"$inc": bson.M{"position": -1}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// FindWatchlist implements domain.Repository interface's FindWatchlist method.
+func (db *database) FindWatchlist(ctx context.Context, userID, genre string, page, limit int) ([]*domain.WatchlistEntry, error) {
+	filter := bson.M{"userId": userID}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "position", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	if genre != "" {
+		filter["genres"] = genre
+		findOptions.SetCollation(genreCollation)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cursor, err := db.watchlist.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*domain.WatchlistEntry{}
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"math"
+	"sort"
+)
+
+// agreementThreshold is the maximum difference between two ratings for them to be considered in agreement.
+const agreementThreshold = 1
+
+// Comparison represents how much the taste of two users match, based on the movies both of them rated.
+type Comparison struct {
+	UserA        string `json:"userA"`
+	UserB        string `json:"userB"`
+	CommonMovies int    `json:"commonMovies"`
+	// Similarity is the Pearson correlation of the common ratings, from -1 to 1.
+	Similarity float64 `json:"similarity"`
+	// Agreement is the percentage of common movies rated at most one star apart.
+	Agreement     float64           `json:"agreement"`
+	Agreements    []MovieComparison `json:"agreements"`
+	Disagreements []MovieComparison `json:"disagreements"`
+}
+
+// MovieComparison represents the ratings two users gave to the same movie.
+type MovieComparison struct {
+	MovieID    string  `json:"movieId"`
+	ValueA     float32 `json:"valueA"`
+	ValueB     float32 `json:"valueB"`
+	Difference float32 `json:"difference"`
+}
+
+// Compare compares the ratings of two users, listing at most top agreements and disagreements.
+func Compare(userA, userB string, ratingsA, ratingsB []*Rating, top int) *Comparison {
+	c := &Comparison{
+		UserA:         userA,
+		UserB:         userB,
+		Agreements:    []MovieComparison{},
+		Disagreements: []MovieComparison{},
+	}
+
+	valuesB := make(map[string]float32, len(ratingsB))
+	for _, r := range ratingsB {
+		valuesB[r.MovieID] = r.Value
+	}
+
+	var common []MovieComparison
+	for _, r := range ratingsA {
+		vb, ok := valuesB[r.MovieID]
+		if !ok {
+			continue
+		}
+		common = append(common, MovieComparison{
+			MovieID:    r.MovieID,
+			ValueA:     r.Value,
+			ValueB:     vb,
+			Difference: float32(math.Abs(float64(r.Value - vb))),
+		})
+	}
+
+	c.CommonMovies = len(common)
+	if c.CommonMovies == 0 {
+		return c
+	}
+
+	c.Similarity = pearson(common)
+
+	agreed := 0
+	for _, mc := range common {
+		if mc.Difference <= agreementThreshold {
+			agreed++
+		}
+	}
+	c.Agreement = math.Round(float64(agreed)/float64(len(common))*10000) / 100
+
+	// closest ratings first, and among those, the movies both users liked the most
+	sort.Slice(common, func(i, j int) bool {
+		if common[i].Difference == common[j].Difference {
+			return common[i].ValueA+common[i].ValueB > common[j].ValueA+common[j].ValueB
+		}
+		return common[i].Difference < common[j].Difference
+	})
+
+	for _, mc := range common {
+		if len(c.Agreements) == top || mc.Difference > agreementThreshold {
+			break
+		}
+		c.Agreements = append(c.Agreements, mc)
+	}
+
+	for i := len(common) - 1; i >= 0; i-- {
+		if len(c.Disagreements) == top || common[i].Difference <= agreementThreshold {
+			break
+		}
+		c.Disagreements = append(c.Disagreements, common[i])
+	}
+
+	return c
+}
+
+// pearson returns the Pearson correlation of the compared values, or 0 when it is undefined.
+func pearson(list []MovieComparison) float64 {
+	n := float64(len(list))
+
+	var sumA, sumB float64
+	for _, mc := range list {
+		sumA += float64(mc.ValueA)
+		sumB += float64(mc.ValueB)
+	}
+	meanA, meanB := sumA/n, sumB/n
+
+	var cov, varA, varB float64
+	for _, mc := range list {
+		da, db := float64(mc.ValueA)-meanA, float64(mc.ValueB)-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+
+	return cov / math.Sqrt(varA*varB)
+}
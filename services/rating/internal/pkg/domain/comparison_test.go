@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	ratingsA := ratingsOf("alice", map[string]float32{"a": 5, "b": 4, "c": 1, "d": 3, "e": 2})
+	ratingsB := ratingsOf("bob", map[string]float32{"a": 4.5, "b": 4, "c": 5, "d": 2.5, "x": 5})
+
+	c := Compare("alice", "bob", ratingsA, ratingsB, 2)
+
+	assert.Equal(t, "alice", c.UserA)
+	assert.Equal(t, "bob", c.UserB)
+	assert.Equal(t, 4, c.CommonMovies)
+	assert.Equal(t, float64(75), c.Agreement)
+	assert.InDelta(t, -0.18, c.Similarity, 0.01)
+
+	assert.Len(t, c.Agreements, 2)
+	assert.Equal(t, "b", c.Agreements[0].MovieID)
+	assert.Equal(t, "a", c.Agreements[1].MovieID)
+
+	assert.Len(t, c.Disagreements, 1)
+	assert.Equal(t, "c", c.Disagreements[0].MovieID)
+	assert.Equal(t, float32(4), c.Disagreements[0].Difference)
+}
+
+func TestCompare_Identical(t *testing.T) {
+	ratingsA := ratingsOf("alice", map[string]float32{"a": 5, "b": 3, "c": 1})
+	ratingsB := ratingsOf("bob", map[string]float32{"a": 5, "b": 3, "c": 1})
+
+	c := Compare("alice", "bob", ratingsA, ratingsB, 5)
+
+	assert.InDelta(t, 1, c.Similarity, 0.0001)
+	assert.Equal(t, float64(100), c.Agreement)
+	assert.Len(t, c.Agreements, 3)
+	assert.Empty(t, c.Disagreements)
+}
+
+func TestCompare_NoCommonMovies(t *testing.T) {
+	c := Compare(
+		"alice",
+		"bob",
+		ratingsOf("alice", map[string]float32{"a": 5}),
+		ratingsOf("bob", map[string]float32{"b": 5}),
+		5,
+	)
+
+	assert.Zero(t, c.CommonMovies)
+	assert.Zero(t, c.Similarity)
+	assert.Zero(t, c.Agreement)
+	assert.Empty(t, c.Agreements)
+	assert.Empty(t, c.Disagreements)
+}
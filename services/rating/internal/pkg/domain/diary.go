@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxDiaryNotesLength is the maximum number of characters of the notes of a diary entry.
+const maxDiaryNotesLength = 2000
+
+// DiaryEntry entity represents a user watching a movie on a given date.
+// A movie can be logged many times, the latest rated entry being the user's current Rating of the movie.
+type DiaryEntry struct {
+	ID        string    `json:"id" bson:"id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	MovieID   string    `json:"movieId" bson:"movieId"`
+	WatchedOn time.Time `json:"watchedOn" bson:"watchedOn"`
+	Value     *float32  `json:"value,omitempty" bson:"value,omitempty"`
+	Rewatch   bool      `json:"rewatch" bson:"rewatch"`
+	Notes     string    `json:"notes,omitempty" bson:"notes,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// NewDiaryEntry returns an instance of the DiaryEntry entity.
+// The watched date is kept at day precision and value is optional.
+func NewDiaryEntry(userID, movieID string, watchedOn time.Time, value *float32, rewatch bool, notes string) *DiaryEntry {
+	return &DiaryEntry{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		MovieID:   movieID,
+		WatchedOn: time.Date(watchedOn.Year(), watchedOn.Month(), watchedOn.Day(), 0, 0, 0, 0, time.UTC),
+		Value:     value,
+		Rewatch:   rewatch,
+		Notes:     notes,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// After returns true if the entry was watched after the other one, or logged later on the same day.
+func (de *DiaryEntry) After(other *DiaryEntry) bool {
+	if de.WatchedOn.Equal(other.WatchedOn) {
+		return de.CreatedAt.After(other.CreatedAt)
+	}
+	return de.WatchedOn.After(other.WatchedOn)
+}
+
+// Predates returns true if the entry was watched on a day before the rating was last changed,
+// so it is older than the opinion the rating holds.
+func (de *DiaryEntry) Predates(rat *Rating) bool {
+	u := rat.UpdatedAt.UTC()
+	return de.WatchedOn.Before(time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+func (de *DiaryEntry) validate() error {
+	if de.ID == "" {
+		return errors.New("id is required")
+	}
+	if de.UserID == "" {
+		return errors.New("userId is required")
+	}
+	if de.MovieID == "" {
+		return errors.New("movieId is required")
+	}
+	if de.WatchedOn.IsZero() {
+		return errors.New("watchedOn is required")
+	}
+	// a day of margin for the users ahead of UTC
+	if de.WatchedOn.After(time.Now().Add(24 * time.Hour)) {
+		return errors.New("watchedOn cannot be in the future")
+	}
+	if de.Value != nil && (*de.Value > 5 || *de.Value < 0.5) {
+		return errors.New("rating value must be from 0.5 to 5")
+	}
+	if utf8.RuneCountInString(de.Notes) > maxDiaryNotesLength {
+		return errors.New("notes must be up to 2000 characters")
+	}
+	if de.CreatedAt.After(de.UpdatedAt) {
+		return errors.New("created_at must be before updated_at")
+	}
+
+	return nil
+}
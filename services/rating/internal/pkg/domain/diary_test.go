@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDiaryEntry(t *testing.T) {
+	value := float32(4)
+	watchedOn := time.Date(2026, 3, 14, 21, 30, 0, 0, time.UTC)
+
+	entry := NewDiaryEntry("user-123", "movie-456", watchedOn, &value, true, "better the second time")
+
+	assert.NotEmpty(t, entry.ID)
+	assert.Equal(t, "user-123", entry.UserID)
+	assert.Equal(t, "movie-456", entry.MovieID)
+	assert.Equal(t, time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC), entry.WatchedOn)
+	assert.Equal(t, &value, entry.Value)
+	assert.True(t, entry.Rewatch)
+	assert.Equal(t, "better the second time", entry.Notes)
+}
+
+func TestDiaryEntry_After(t *testing.T) {
+	day := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	older := &DiaryEntry{WatchedOn: day.AddDate(0, 0, -1), CreatedAt: time.Now()}
+	first := &DiaryEntry{WatchedOn: day, CreatedAt: time.Now().Add(-time.Hour)}
+	second := &DiaryEntry{WatchedOn: day, CreatedAt: time.Now()}
+
+	assert.True(t, first.After(older))
+	assert.False(t, older.After(first))
+	assert.True(t, second.After(first))
+	assert.False(t, first.After(second))
+}
+
+func TestDiaryEntry_Predates(t *testing.T) {
+	day := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	rat := &Rating{UpdatedAt: day.Add(15 * time.Hour)}
+
+	assert.True(t, (&DiaryEntry{WatchedOn: day.AddDate(0, 0, -1)}).Predates(rat))
+	assert.False(t, (&DiaryEntry{WatchedOn: day}).Predates(rat))
+	assert.False(t, (&DiaryEntry{WatchedOn: day.AddDate(0, 0, 1)}).Predates(rat))
+}
+
+func TestDiaryEntry_Validate(t *testing.T) {
+	valid := float32(3.5)
+	tooHigh := float32(6)
+	yesterday := time.Now().AddDate(0, 0, -1)
+
+	tests := []struct {
+		name      string
+		entry     *DiaryEntry
+		wantError bool
+	}{
+		{
+			name:      "valid entry",
+			entry:     NewDiaryEntry("user-123", "movie-456", yesterday, &valid, false, ""),
+			wantError: false,
+		},
+		{
+			name:      "valid entry without rating",
+			entry:     NewDiaryEntry("user-123", "movie-456", yesterday, nil, false, ""),
+			wantError: false,
+		},
+		{
+			name:      "missing UserID",
+			entry:     NewDiaryEntry("", "movie-456", yesterday, nil, false, ""),
+			wantError: true,
+		},
+		{
+			name:      "missing MovieID",
+			entry:     NewDiaryEntry("user-123", "", yesterday, nil, false, ""),
+			wantError: true,
+		},
+		{
+			name:      "missing watchedOn",
+			entry:     &DiaryEntry{ID: "entry-1", UserID: "user-123", MovieID: "movie-456"},
+			wantError: true,
+		},
+		{
+			name:      "watched in the future",
+			entry:     NewDiaryEntry("user-123", "movie-456", time.Now().AddDate(0, 0, 3), nil, false, ""),
+			wantError: true,
+		},
+		{
+			name:      "rating out of range",
+			entry:     NewDiaryEntry("user-123", "movie-456", yesterday, &tooHigh, false, ""),
+			wantError: true,
+		},
+		{
+			name:      "notes too long",
+			entry:     NewDiaryEntry("user-123", "movie-456", yesterday, nil, false, strings.Repeat("a", 2001)),
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.validate()
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Rating dimensions, the aspects of a movie that can be scored separately from the overall value.
+const (
+	StoryDimension         = "story"
+	ActingDimension        = "acting"
+	VisualsDimension       = "visuals"
+	EntertainmentDimension = "entertainment"
+)
+
+// Dimensions lists all the rating dimensions.
+var Dimensions = []string{StoryDimension, ActingDimension, VisualsDimension, EntertainmentDimension}
+
+// DimensionScores holds the optional per-dimension scores of a Rating.
+type DimensionScores struct {
+	Story         *float32 `json:"story,omitempty" bson:"story,omitempty"`
+	Acting        *float32 `json:"acting,omitempty" bson:"acting,omitempty"`
+	Visuals       *float32 `json:"visuals,omitempty" bson:"visuals,omitempty"`
+	Entertainment *float32 `json:"entertainment,omitempty" bson:"entertainment,omitempty"`
+}
+
+// Scores returns the scores by dimension, nil meaning the dimension was not scored.
+func (ds *DimensionScores) Scores() map[string]*float32 {
+	return map[string]*float32{
+		StoryDimension:         ds.Story,
+		ActingDimension:        ds.Acting,
+		VisualsDimension:       ds.Visuals,
+		EntertainmentDimension: ds.Entertainment,
+	}
+}
+
+func (ds *DimensionScores) validate() error {
+	for _, d := range Dimensions {
+		if v := ds.Scores()[d]; v != nil && (*v > 5 || *v < 0.5) {
+			return fmt.Errorf("%s score must be from 0.5 to 5", d)
+		}
+	}
+	return nil
+}
+
+// Bucket is the number of ratings of a given value.
+type Bucket struct {
+	Value   float32 `json:"value"`
+	Ratings int     `json:"ratings"`
+}
+
+// NewDistribution returns the number of ratings of every value from 0.5 to 5, in half star steps.
+func NewDistribution(counts map[float32]int) []Bucket {
+	list := make([]Bucket, 0, 10)
+	for v := float32(0.5); v <= 5; v += 0.5 {
+		list = append(list, Bucket{Value: v, Ratings: counts[v]})
+	}
+	return list
+}
+
+// RatingSummary is the aggregation of all the ratings of a movie.
+type RatingSummary struct {
+	MovieID      string                      `json:"movieId"`
+	Ratings      int                         `json:"ratings"`
+	Average      float64                     `json:"average"`
+	Distribution []Bucket                    `json:"distribution"`
+	Dimensions   map[string]DimensionSummary `json:"dimensions"`
+}
+
+// DimensionSummary is the aggregation of the scores of a dimension.
+type DimensionSummary struct {
+	Ratings int     `json:"ratings"`
+	Average float64 `json:"average"`
+}
+
+// ValueStats is the aggregation of the ratings of a movie that have the same value.
+type ValueStats struct {
+	Value      float32                   `bson:"_id"`
+	Ratings    int                       `bson:"ratings"`
+	Dimensions map[string]DimensionStats `bson:"dimensions"`
+}
+
+// DimensionStats is the sum and number of the scores of a dimension.
+type DimensionStats struct {
+	Sum   float64 `bson:"sum"`
+	Count int     `bson:"count"`
+}
+
+// SummarizeRatings builds the RatingSummary of a movie from its ratings aggregated by value.
+// Only the dimensions scored at least once appear in the summary.
+func SummarizeRatings(movieID string, stats []ValueStats) *RatingSummary {
+	summary := &RatingSummary{
+		MovieID:    movieID,
+		Dimensions: make(map[string]DimensionSummary),
+	}
+
+	var sum float64
+	counts := make(map[float32]int, len(stats))
+	dimensions := make(map[string]DimensionStats)
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Value < stats[j].Value })
+	for _, s := range stats {
+		summary.Ratings += s.Ratings
+		sum += float64(s.Value) * float64(s.Ratings)
+		counts[s.Value] += s.Ratings
+
+		for d, ds := range s.Dimensions {
+			acc := dimensions[d]
+			acc.Sum += ds.Sum
+			acc.Count += ds.Count
+			dimensions[d] = acc
+		}
+	}
+
+	if summary.Ratings > 0 {
+		summary.Average = sum / float64(summary.Ratings)
+	}
+	summary.Distribution = NewDistribution(counts)
+
+	for d, ds := range dimensions {
+		if ds.Count > 0 {
+			summary.Dimensions[d] = DimensionSummary{Ratings: ds.Count, Average: ds.Sum / float64(ds.Count)}
+		}
+	}
+
+	return summary
+}
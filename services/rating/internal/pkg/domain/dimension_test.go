@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDimensionScores_Validate(t *testing.T) {
+	valid, tooLow := float32(4), float32(0)
+
+	assert.NoError(t, (&DimensionScores{}).validate())
+	assert.NoError(t, (&DimensionScores{Story: &valid, Visuals: &valid}).validate())
+	assert.EqualError(t, (&DimensionScores{Story: &valid, Acting: &tooLow}).validate(), "acting score must be from 0.5 to 5")
+}
+
+func TestNewDistribution(t *testing.T) {
+	distribution := NewDistribution(map[float32]int{0.5: 1, 4.5: 3})
+
+	assert.Len(t, distribution, 10)
+	assert.Equal(t, Bucket{Value: 0.5, Ratings: 1}, distribution[0])
+	assert.Equal(t, Bucket{Value: 2.5, Ratings: 0}, distribution[4])
+	assert.Equal(t, Bucket{Value: 4.5, Ratings: 3}, distribution[8])
+}
+
+func TestSummarizeRatings(t *testing.T) {
+	summary := SummarizeRatings("movie-456", []ValueStats{
+		{
+			Value:   5,
+			Ratings: 1,
+			Dimensions: map[string]DimensionStats{
+				StoryDimension:  {Sum: 5, Count: 1},
+				ActingDimension: {Sum: 0, Count: 0},
+			},
+		},
+		{
+			Value:   3,
+			Ratings: 3,
+			Dimensions: map[string]DimensionStats{
+				StoryDimension: {Sum: 8, Count: 2},
+			},
+		},
+	})
+
+	assert.Equal(t, "movie-456", summary.MovieID)
+	assert.Equal(t, 4, summary.Ratings)
+	assert.Equal(t, 3.5, summary.Average)
+	assert.Equal(t, 3, summary.Distribution[5].Ratings)
+	assert.Equal(t, 1, summary.Distribution[9].Ratings)
+	assert.Equal(t, map[string]DimensionSummary{
+		StoryDimension: {Ratings: 3, Average: 13.0 / 3},
+	}, summary.Dimensions)
+
+	empty := SummarizeRatings("movie-789", nil)
+	assert.Zero(t, empty.Ratings)
+	assert.Zero(t, empty.Average)
+	assert.Empty(t, empty.Dimensions)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType represents the kind of change an Event records.
+type EventType string
+
+// Valid EventType values as constants.
+const (
+	RatingCreated EventType = "RatingCreated"
+	RatingUpdated EventType = "RatingUpdated"
+	RatingDeleted EventType = "RatingDeleted"
+)
+
+// Event records a change of a Rating. It is written to the outbox in the same transaction as the change
+// and later relayed to the event stream.
+type Event struct {
+	ID          string     `json:"id" bson:"id"`
+	Type        EventType  `json:"type" bson:"type"`
+	Rating      Rating     `json:"rating" bson:"rating"`
+	OccurredAt  time.Time  `json:"occurredAt" bson:"occurredAt"`
+	PublishedAt *time.Time `json:"-" bson:"publishedAt"`
+	LockedBy    string     `json:"-" bson:"lockedBy,omitempty"`
+	LockedUntil time.Time  `json:"-" bson:"lockedUntil,omitempty"`
+}
+
+// NewEvent returns an instance of the Event entity.
+func NewEvent(eventType EventType, rating *Rating) *Event {
+	return &Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		Rating:     *rating,
+		OccurredAt: time.Now(),
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEvent(t *testing.T) {
+	rating := NewRating("user-123", "movie-456", 4.5)
+
+	event := NewEvent(RatingCreated, rating)
+
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, RatingCreated, event.Type)
+	assert.Equal(t, *rating, event.Rating)
+	assert.Nil(t, event.PublishedAt)
+	assert.Empty(t, event.LockedBy)
+	assert.WithinDuration(t, time.Now(), event.OccurredAt, time.Second)
+}
@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportSource represents the service a ratings export comes from.
+type ImportSource string
+
+// Valid ImportSource values as constants.
+const (
+	LetterboxdImport ImportSource = "letterboxd"
+	IMDbImport       ImportSource = "imdb"
+)
+
+// ImportStatus represents the progress of an Import.
+type ImportStatus string
+
+// Valid ImportStatus values as constants.
+const (
+	ImportPending   ImportStatus = "pending"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// Import is a bulk import of the ratings a user exported from another service.
+type Import struct {
+	ID            string         `json:"id" bson:"id"`
+	UserID        string         `json:"userId" bson:"userId"`
+	Source        ImportSource   `json:"source" bson:"source"`
+	Status        ImportStatus   `json:"status" bson:"status"`
+	TotalRows     int            `json:"totalRows" bson:"totalRows"`
+	ProcessedRows int            `json:"processedRows" bson:"processedRows"`
+	Imported      int            `json:"imported" bson:"imported"`
+	Skipped       int            `json:"skipped" bson:"skipped"`
+	Unmatched     []UnmatchedRow `json:"unmatched" bson:"unmatched"`
+	Error         string         `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt" bson:"updatedAt"`
+	FinishedAt    *time.Time     `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+// ImportRow is a rating read from an export, not yet matched to a movie.
+type ImportRow struct {
+	Line    int
+	Title   string
+	Year    int
+	IMDbID  string
+	Value   float32
+	RatedAt time.Time
+}
+
+// UnmatchedRow is a row of an export that could not be imported, along with the reason why.
+type UnmatchedRow struct {
+	Line   int    `json:"line" bson:"line"`
+	Title  string `json:"title" bson:"title"`
+	Year   int    `json:"year,omitempty" bson:"year,omitempty"`
+	IMDbID string `json:"imdbId,omitempty" bson:"imdbId,omitempty"`
+	Reason string `json:"reason" bson:"reason"`
+}
+
+// NewImport returns an instance of the Import entity.
+// Rows that could not even be parsed are reported as unmatched from the start.
+func NewImport(userID string, source ImportSource, totalRows int, unmatched []UnmatchedRow) *Import {
+	if unmatched == nil {
+		unmatched = []UnmatchedRow{}
+	}
+
+	return &Import{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Source:        source,
+		Status:        ImportPending,
+		TotalRows:     totalRows,
+		ProcessedRows: len(unmatched),
+		Unmatched:     unmatched,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+}
+
+// Unmatch reports a row as not imported.
+func (i *Import) Unmatch(row ImportRow, reason string) {
+	i.Unmatched = append(i.Unmatched, row.unmatched(reason))
+}
+
+func (row ImportRow) unmatched(reason string) UnmatchedRow {
+	return UnmatchedRow{
+		Line:   row.Line,
+		Title:  row.Title,
+		Year:   row.Year,
+		IMDbID: row.IMDbID,
+		Reason: reason,
+	}
+}
+
+// Columns of the supported exports.
+const (
+	letterboxdName   = "Name"
+	letterboxdYear   = "Year"
+	letterboxdURI    = "Letterboxd URI"
+	letterboxdRating = "Rating"
+	letterboxdDate   = "Date"
+	imdbConst        = "Const"
+	imdbTitle        = "Title"
+	imdbYear         = "Year"
+	imdbRating       = "Your Rating"
+	imdbDate         = "Date Rated"
+)
+
+// ParseImport reads a Letterboxd ratings.csv or an IMDb ratings export, telling them apart by their header.
+// Rows with a missing or out of scale rating are returned as unmatched, the others have their value rescaled to 0.5–5.
+func ParseImport(r io.Reader, maxRows int) (ImportSource, []ImportRow, []UnmatchedRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return "", nil, nil, errors.New("file is empty or not a valid csv")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+
+	var (
+		source                   ImportSource
+		titleCol, yearCol, idCol string
+		ratingCol, dateCol       string
+		scale                    float64
+	)
+
+	switch {
+	case hasColumns(columns, letterboxdName, letterboxdURI, letterboxdRating):
+		source, titleCol, yearCol, ratingCol, dateCol, scale = LetterboxdImport, letterboxdName, letterboxdYear, letterboxdRating, letterboxdDate, 5
+	case hasColumns(columns, imdbConst, imdbRating):
+		source, titleCol, yearCol, idCol, ratingCol, dateCol, scale = IMDbImport, imdbTitle, imdbYear, imdbConst, imdbRating, imdbDate, 10
+	default:
+		return "", nil, nil, errors.New("unrecognized file format, expected a Letterboxd or IMDb ratings export")
+	}
+
+	field := func(record []string, col string) string {
+		i, ok := columns[col]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := []ImportRow{}
+	unmatched := []UnmatchedRow{}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(rows)+len(unmatched) >= maxRows {
+			return "", nil, nil, fmt.Errorf("file has more than %d rows", maxRows)
+		}
+
+		row := ImportRow{
+			Line:   line,
+			Title:  field(record, titleCol),
+			IMDbID: field(record, idCol),
+		}
+		row.Year, _ = strconv.Atoi(field(record, yearCol))
+		row.RatedAt, _ = time.Parse(time.DateOnly, field(record, dateCol))
+
+		if row.Title == "" && row.IMDbID == "" {
+			unmatched = append(unmatched, row.unmatched("missing title"))
+			continue
+		}
+
+		value, err := strconv.ParseFloat(field(record, ratingCol), 64)
+		if err != nil {
+			unmatched = append(unmatched, row.unmatched("missing rating"))
+			continue
+		}
+
+		v, ok := RescaleRating(value, scale)
+		if !ok {
+			unmatched = append(unmatched, row.unmatched("rating out of scale"))
+			continue
+		}
+		row.Value = v
+
+		rows = append(rows, row)
+	}
+
+	return source, rows, unmatched, nil
+}
+
+// RescaleRating converts a rating from a 0–scale range to the 0.5–5 range, in half star steps.
+// It returns false if the value is not within the original range.
+func RescaleRating(value, scale float64) (float32, bool) {
+	if value <= 0 || value > scale {
+		return 0, false
+	}
+
+	v := math.Round(value/scale*5*2) / 2
+	if v < 0.5 {
+		v = 0.5
+	}
+
+	return float32(v), true
+}
+
+func hasColumns(columns map[string]int, names ...string) bool {
+	for _, n := range names {
+		if _, ok := columns[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewImport(t *testing.T) {
+	unmatched := []UnmatchedRow{{Line: 3, Title: "Heat", Reason: "missing rating"}}
+
+	imp := NewImport("user-123", LetterboxdImport, 10, unmatched)
+
+	assert.NotEmpty(t, imp.ID)
+	assert.Equal(t, "user-123", imp.UserID)
+	assert.Equal(t, LetterboxdImport, imp.Source)
+	assert.Equal(t, ImportPending, imp.Status)
+	assert.Equal(t, 10, imp.TotalRows)
+	assert.Equal(t, 1, imp.ProcessedRows)
+	assert.Equal(t, unmatched, imp.Unmatched)
+	assert.NotNil(t, NewImport("user-123", IMDbImport, 0, nil).Unmatched)
+}
+
+func TestRescaleRating(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		scale float64
+		want  float32
+		ok    bool
+	}{
+		{name: "LetterboxdHalfStar", value: 0.5, scale: 5, want: 0.5, ok: true},
+		{name: "LetterboxdFiveStars", value: 5, scale: 5, want: 5, ok: true},
+		{name: "IMDbOne", value: 1, scale: 10, want: 0.5, ok: true},
+		{name: "IMDbSeven", value: 7, scale: 10, want: 3.5, ok: true},
+		{name: "IMDbTen", value: 10, scale: 10, want: 5, ok: true},
+		{name: "Zero", value: 0, scale: 10, ok: false},
+		{name: "AboveScale", value: 11, scale: 10, ok: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, ok := RescaleRating(tc.value, tc.scale)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, v)
+		})
+	}
+}
+
+func TestParseImport(t *testing.T) {
+	t.Run("Letterboxd", func(t *testing.T) {
+		csv := "\ufeffDate,Name,Year,Letterboxd URI,Rating\n" +
+			"2021-03-04,Heat,1995,https://boxd.it/29Vs,4.5\n" +
+			"2021-03-05,\"The Thing\",1982,https://boxd.it/29Vt,\n" +
+			"2021-03-06,Alien,1979,https://boxd.it/29Vu,7\n"
+
+		source, rows, unmatched, err := ParseImport(strings.NewReader(csv), 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, LetterboxdImport, source)
+		assert.Equal(t, []ImportRow{{
+			Line:    2,
+			Title:   "Heat",
+			Year:    1995,
+			Value:   4.5,
+			RatedAt: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		}}, rows)
+		assert.Equal(t, []UnmatchedRow{
+			{Line: 3, Title: "The Thing", Year: 1982, Reason: "missing rating"},
+			{Line: 4, Title: "Alien", Year: 1979, Reason: "rating out of scale"},
+		}, unmatched)
+	})
+
+	t.Run("IMDb", func(t *testing.T) {
+		csv := "Const,Your Rating,Date Rated,Title,URL,Title Type,IMDb Rating,Runtime (mins),Year\n" +
+			"tt0113277,9,2020-01-02,Heat,https://www.imdb.com/title/tt0113277/,movie,8.3,170,1995\n"
+
+		source, rows, unmatched, err := ParseImport(strings.NewReader(csv), 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, IMDbImport, source)
+		assert.Equal(t, []ImportRow{{
+			Line:    2,
+			Title:   "Heat",
+			Year:    1995,
+			IMDbID:  "tt0113277",
+			Value:   4.5,
+			RatedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		}}, rows)
+		assert.Empty(t, unmatched)
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		_, _, _, err := ParseImport(strings.NewReader("a,b\n1,2\n"), 100)
+		assert.Equal(t, errors.New("unrecognized file format, expected a Letterboxd or IMDb ratings export"), err)
+	})
+
+	t.Run("TooManyRows", func(t *testing.T) {
+		csv := "Const,Your Rating\ntt0113277,9\ntt0084787,8\n"
+		_, _, _, err := ParseImport(strings.NewReader(csv), 1)
+		assert.Equal(t, errors.New("file has more than 1 rows"), err)
+	})
+}
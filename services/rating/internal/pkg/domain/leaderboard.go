@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Leaderboard is a precomputed ranking of movies.
+type Leaderboard struct {
+	Name      string             `json:"name" bson:"name"`
+	Entries   []LeaderboardEntry `json:"entries" bson:"entries"`
+	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// LeaderboardEntry is a ranked movie.
+type LeaderboardEntry struct {
+	MovieID string  `json:"movieId" bson:"movieId"`
+	Score   float64 `json:"score" bson:"score"`
+	Ratings int     `json:"ratings" bson:"ratings"`
+	Average float64 `json:"average" bson:"average"`
+}
+
+// Leaderboard names.
+const (
+	TopRatedLeaderboard  = "top-rated"
+	TrendingLeaderboard  = "trending"
+	mostRatedLeaderboard = "most-rated"
+)
+
+// MostRatedLeaderboard returns the name of the most rated leaderboard of a genre.
+func MostRatedLeaderboard(genre string) string {
+	return mostRatedLeaderboard + ":" + strings.ToLower(genre)
+}
+
+// MovieStats is the aggregation of all the ratings of a movie.
+type MovieStats struct {
+	MovieID string  `bson:"_id"`
+	Ratings int     `bson:"ratings"`
+	Sum     float64 `bson:"sum"`
+}
+
+// Average returns the mean rating value of the movie.
+func (ms MovieStats) Average() float64 {
+	if ms.Ratings == 0 {
+		return 0
+	}
+	return ms.Sum / float64(ms.Ratings)
+}
+
+// BayesianAverage pulls the average of a movie towards the global mean,
+// the fewer ratings it has compared to minRatings, the stronger the pull.
+func BayesianAverage(average float64, ratings int, globalMean float64, minRatings int) float64 {
+	v, m := float64(ratings), float64(minRatings)
+	if v+m == 0 {
+		return globalMean
+	}
+	return (v/(v+m))*average + (m/(v+m))*globalMean
+}
+
+// DecayWeight returns the weight of an event that happened age ago, halving every halfLife.
+func DecayWeight(age, halfLife time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, age.Hours()/halfLife.Hours())
+}
+
+// Ranking keeps the highest scored entries pushed into it, up to its size.
+type Ranking struct {
+	size    int
+	entries rankingHeap
+}
+
+// NewRanking returns a new instance of Ranking.
+func NewRanking(size int) *Ranking {
+	return &Ranking{size: size}
+}
+
+// Push adds an entry to the ranking if it scores high enough.
+func (rk *Ranking) Push(e LeaderboardEntry) {
+	if rk.size <= 0 {
+		return
+	}
+	if len(rk.entries) < rk.size {
+		heap.Push(&rk.entries, e)
+		return
+	}
+	if rk.entries[0].Score < e.Score {
+		rk.entries[0] = e
+		heap.Fix(&rk.entries, 0)
+	}
+}
+
+// Entries returns the ranked entries, highest score first.
+func (rk *Ranking) Entries() []LeaderboardEntry {
+	list := make([]LeaderboardEntry, len(rk.entries))
+	copy(list, rk.entries)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	return list
+}
+
+// rankingHeap is a min-heap of entries, so the lowest score is the first to be replaced.
+type rankingHeap []LeaderboardEntry
+
+func (h rankingHeap) Len() int           { return len(h) }
+func (h rankingHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h rankingHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *rankingHeap) Push(x any) { *h = append(*h, x.(LeaderboardEntry)) }
+
+func (h *rankingHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMostRatedLeaderboard(t *testing.T) {
+	assert.Equal(t, "most-rated:science fiction", MostRatedLeaderboard("Science Fiction"))
+}
+
+func TestMovieStats_Average(t *testing.T) {
+	assert.Equal(t, 3.5, MovieStats{MovieID: "a", Ratings: 2, Sum: 7}.Average())
+	assert.Zero(t, MovieStats{MovieID: "a"}.Average())
+}
+
+func TestBayesianAverage(t *testing.T) {
+	// a single 5 star rating barely moves a movie away from the global mean
+	single := BayesianAverage(5, 1, 3, 10)
+	assert.InDelta(t, 3.18, single, 0.01)
+
+	// many ratings make the movie's own average prevail
+	many := BayesianAverage(4.5, 1000, 3, 10)
+	assert.InDelta(t, 4.49, many, 0.01)
+	assert.Greater(t, many, single)
+
+	assert.Equal(t, float64(3), BayesianAverage(0, 0, 3, 0))
+}
+
+func TestDecayWeight(t *testing.T) {
+	halfLife := 24 * time.Hour
+
+	assert.Equal(t, float64(1), DecayWeight(0, halfLife))
+	assert.Equal(t, float64(1), DecayWeight(-time.Hour, halfLife))
+	assert.InDelta(t, 0.5, DecayWeight(24*time.Hour, halfLife), 0.0001)
+	assert.InDelta(t, 0.25, DecayWeight(48*time.Hour, halfLife), 0.0001)
+}
+
+func TestRanking(t *testing.T) {
+	rk := NewRanking(3)
+
+	for i, score := range []float64{1, 5, 3, 4, 2} {
+		rk.Push(LeaderboardEntry{MovieID: string(rune('a' + i)), Score: score})
+	}
+
+	entries := rk.Entries()
+
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "b", entries[0].MovieID)
+	assert.Equal(t, "d", entries[1].MovieID)
+	assert.Equal(t, "c", entries[2].MovieID)
+
+	assert.Empty(t, NewRanking(0).Entries())
+}
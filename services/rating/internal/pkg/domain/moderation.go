@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// ModerationStatus represents the outcome of the moderation of a Rating.
+type ModerationStatus string
+
+// Valid ModerationStatus values as constants.
+const (
+	// ModerationHeld ratings are held out of the aggregates and leaderboards until an admin reviews them.
+	ModerationHeld ModerationStatus = "held"
+	// ModerationApproved ratings were reviewed by an admin and are not held again.
+	ModerationApproved ModerationStatus = "approved"
+)
+
+// IsValid checks if the ModerationStatus is one of the valid values.
+func (s ModerationStatus) IsValid() bool {
+	return s == ModerationHeld || s == ModerationApproved
+}
+
+// BurstPolicy tells which ratings of a movie make a suspicious burst:
+// more than MaxNewAccountRatings ratings given within Window by accounts younger than NewAccountAge.
+type BurstPolicy struct {
+	Window               time.Duration
+	NewAccountAge        time.Duration
+	MaxNewAccountRatings int
+}
+
+// IsNewAccount checks if an account created at the given time is young enough to take part in a burst.
+// Accounts of unknown age are not.
+func (p BurstPolicy) IsNewAccount(createdAt, now time.Time) bool {
+	return !createdAt.IsZero() && now.Sub(createdAt) < p.NewAccountAge
+}
+
+// IsBurst checks if the given number of ratings given to a movie by new accounts within the window is suspicious.
+func (p BurstPolicy) IsBurst(newAccountRatings int) bool {
+	return newAccountRatings > p.MaxNewAccountRatings
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerationStatus_IsValid(t *testing.T) {
+	assert.True(t, ModerationHeld.IsValid())
+	assert.True(t, ModerationApproved.IsValid())
+	assert.False(t, ModerationStatus("rejected").IsValid())
+	assert.False(t, ModerationStatus("").IsValid())
+}
+
+func TestBurstPolicy(t *testing.T) {
+	p := BurstPolicy{Window: time.Hour, NewAccountAge: 7 * 24 * time.Hour, MaxNewAccountRatings: 20}
+	now := time.Now()
+
+	assert.True(t, p.IsNewAccount(now.Add(-time.Hour), now))
+	assert.False(t, p.IsNewAccount(now.Add(-30*24*time.Hour), now))
+	assert.False(t, p.IsNewAccount(time.Time{}, now))
+
+	assert.False(t, p.IsBurst(20))
+	assert.True(t, p.IsBurst(21))
+}
@@ -9,12 +9,15 @@ import (
 
 // Rating entity.
 type Rating struct {
-	ID        string    `json:"id" bson:"id"`
-	UserID    string    `json:"userId" bson:"userId"`
-	MovieID   string    `json:"movieId" bson:"movieId"`
-	Value     float32   `json:"value" bson:"value"`
-	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	ID         string           `json:"id" bson:"id"`
+	UserID     string           `json:"userId" bson:"userId"`
+	MovieID    string           `json:"movieId" bson:"movieId"`
+	Value      float32          `json:"value" bson:"value"`
+	Dimensions *DimensionScores `json:"dimensions,omitempty" bson:"dimensions,omitempty"`
+	Review     *Review          `json:"review,omitempty" bson:"review,omitempty"`
+	Moderation ModerationStatus `json:"moderation,omitempty" bson:"moderation,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time        `json:"updatedAt" bson:"updatedAt"`
 }
 
 // NewRating returns an instance of the Rating entity.
@@ -45,6 +48,19 @@ func (r *Rating) validate() error {
 	if r.CreatedAt.After(r.UpdatedAt) {
 		return errors.New("created_at must be before updated_at")
 	}
+	if r.Moderation != "" && !r.Moderation.IsValid() {
+		return errors.New("invalid moderation status")
+	}
+	if r.Dimensions != nil {
+		if err := r.Dimensions.validate(); err != nil {
+			return err
+		}
+	}
+	if r.Review != nil {
+		if err := r.Review.validate(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
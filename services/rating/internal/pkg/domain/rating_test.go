@@ -98,6 +98,58 @@ func TestRating_Validate(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "valid rating with review",
+			rating: &Rating{
+				ID:        uuid.New().String(),
+				UserID:    "user-123",
+				MovieID:   "movie-456",
+				Value:     4.5,
+				Review:    NewReview("A masterpiece", "Loved every minute of it.", false, "en"),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid review",
+			rating: &Rating{
+				ID:        uuid.New().String(),
+				UserID:    "user-123",
+				MovieID:   "movie-456",
+				Value:     4.5,
+				Review:    NewReview("A masterpiece", "", false, "en"),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			wantError: true,
+		},
+		{
+			name: "valid rating with dimensions",
+			rating: &Rating{
+				ID:         uuid.New().String(),
+				UserID:     "user-123",
+				MovieID:    "movie-456",
+				Value:      4.5,
+				Dimensions: &DimensionScores{Story: &[]float32{5}[0]},
+				CreatedAt:  time.Now(),
+				UpdatedAt:  time.Now(),
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid dimension score",
+			rating: &Rating{
+				ID:         uuid.New().String(),
+				UserID:     "user-123",
+				MovieID:    "movie-456",
+				Value:      4.5,
+				Dimensions: &DimensionScores{Visuals: &[]float32{5.5}[0]},
+				CreatedAt:  time.Now(),
+				UpdatedAt:  time.Now(),
+			},
+			wantError: true,
+		},
 		{
 			name: "created_at after updated_at",
 			rating: &Rating{
@@ -110,6 +162,19 @@ func TestRating_Validate(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "invalid moderation status",
+			rating: &Rating{
+				ID:         uuid.New().String(),
+				UserID:     "user-123",
+				MovieID:    "movie-456",
+				Value:      0.5,
+				Moderation: "rejected",
+				CreatedAt:  time.Now(),
+				UpdatedAt:  time.Now(),
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
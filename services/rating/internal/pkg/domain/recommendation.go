@@ -0,0 +1,69 @@
+package domain
+
+import "sort"
+
+// Recommendation is a movie the user has not rated yet, along with its predicted rating value.
+type Recommendation struct {
+	MovieID string  `json:"movieId"`
+	Score   float64 `json:"score"`
+}
+
+// Recommend predicts the user's rating for the neighbors of the movies they rated
+// and returns the top limit movies they have not rated yet.
+func Recommend(ratings []*Rating, similarities []*Similarity, limit int) []*Recommendation {
+	if len(ratings) == 0 || limit <= 0 {
+		return []*Recommendation{}
+	}
+
+	mean := meanValue(ratings)
+
+	rated := make(map[string]float64, len(ratings))
+	for _, r := range ratings {
+		rated[r.MovieID] = float64(r.Value) - mean
+	}
+
+	numerator := make(map[string]float64)
+	denominator := make(map[string]float64)
+	for _, s := range similarities {
+		deviation, ok := rated[s.MovieID]
+		if !ok {
+			continue
+		}
+		for _, n := range s.Neighbors {
+			if _, seen := rated[n.MovieID]; seen {
+				continue
+			}
+			numerator[n.MovieID] += n.Score * deviation
+			denominator[n.MovieID] += n.Score
+		}
+	}
+
+	list := make([]*Recommendation, 0, len(denominator))
+	for movieID, den := range denominator {
+		if den == 0 {
+			continue
+		}
+		score := mean + numerator[movieID]/den
+		if score > 5 {
+			score = 5
+		}
+		if score < 0.5 {
+			score = 0.5
+		}
+		list = append(list, &Recommendation{MovieID: movieID, Score: score})
+	}
+
+	// movies supported by more similar neighbors win ties
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score == list[j].Score {
+			return denominator[list[i].MovieID] > denominator[list[j].MovieID]
+		}
+		return list[i].Score > list[j].Score
+	})
+
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list
+}
@@ -1,15 +1,106 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Repository is the interface for the domain's repository (e.g. some database).
 type Repository interface {
-	// Upsert receives a validated input and upserts a Rating.
-	Upsert(ctx context.Context, rating *ValidatedRating) (*Rating, error)
+	// Upsert receives a validated input and upserts a Rating, recording a RatingCreated or RatingUpdated Event.
+	// The rated movie is removed from the user's watchlist and the user's cached UserStats are dropped.
+	// The ratings of new accounts are given a BurstPolicy: if they make a burst, all its ratings are held
+	// before the Event is recorded, recording a RatingUpdated Event per other rating held. burst is nil otherwise.
+	Upsert(ctx context.Context, rating *ValidatedRating, burst *BurstPolicy) (*Rating, error)
+	// Delete removes the Rating given by a user to a movie, recording a RatingDeleted Event.
+	// The user's cached UserStats are dropped.
+	Delete(ctx context.Context, userID, movieID string) error
 	// FindByUserID retrieves a list of Rating by a given user ID.
 	FindByUserID(ctx context.Context, userID string) ([]*Rating, error)
 	// FindByMovieID retrieves a list of Rating by a given movie ID.
 	FindByMovieID(ctx context.Context, movieID string) ([]*Rating, error)
+	// FindRatingSummary aggregates all the Rating of a movie, including the averages of their dimension scores.
+	// Held ratings are left out.
+	FindRatingSummary(ctx context.Context, movieID string) (*RatingSummary, error)
+	// FindByUserAndMovieID retrieves the Rating given by a user to a movie.
+	FindByUserAndMovieID(ctx context.Context, userID, movieID string) (*Rating, error)
+	// FindByUserAndMovieIDs retrieves the Rating given by a user to any of the given movies.
+	FindByUserAndMovieIDs(ctx context.Context, userID string, movieIDs []string) ([]*Rating, error)
+	// FindReviewsByMovieID retrieves a page of reviewed Rating by a given movie ID.
+	FindReviewsByMovieID(ctx context.Context, movieID string, sort ReviewSort, page, limit int) ([]*Rating, error)
+	// UpdateReview receives a validated input and sets the review of an existing Rating, recording a RatingUpdated Event.
+	UpdateReview(ctx context.Context, rating *ValidatedRating) (*Rating, error)
+	// DeleteReview removes the review of an existing Rating and its votes, recording a RatingUpdated Event.
+	DeleteReview(ctx context.Context, userID, movieID string) error
+	// Vote receives a validated input and creates or changes a vote, keeping the review's vote counts in sync.
+	// It returns ErrReviewNotFound if the Rating has no review.
+	Vote(ctx context.Context, vote *ValidatedVote) (*Vote, error)
+	// DeleteVote removes a user's vote on a review, keeping the review's vote counts in sync.
+	DeleteVote(ctx context.Context, userID, movieID, voterID string) error
+	// ForEachUserRatings streams all the ratings but the held ones grouped by user, calling fn once per user.
+	ForEachUserRatings(ctx context.Context, fn func(userID string, ratings []*Rating) error) error
+	// ReplaceSimilarities stores a freshly built set of Similarity, removing the outdated ones.
+	ReplaceSimilarities(ctx context.Context, similarities []*Similarity) error
+	// FindSimilarities retrieves the Similarity of the given movie IDs.
+	FindSimilarities(ctx context.Context, movieIDs []string) ([]*Similarity, error)
+	// SaveMovieGenres stores the genres of a movie, so ratings can be grouped by genre.
+	SaveMovieGenres(ctx context.Context, movieID string, genres []string) error
+	// FindMovieGenres retrieves the known genres of the given movie IDs, by movie ID.
+	FindMovieGenres(ctx context.Context, movieIDs []string) (map[string][]string, error)
+	// ForEachMovieStats streams the aggregated ratings but the held ones of every rated movie, calling fn once per movie.
+	ForEachMovieStats(ctx context.Context, fn func(stats MovieStats) error) error
+	// ForEachRatingSince streams the ratings but the held ones created or updated since a given time.
+	ForEachRatingSince(ctx context.Context, since time.Time, fn func(rating *Rating) error) error
+	// ReplaceLeaderboards stores a freshly built set of Leaderboard, removing the outdated ones.
+	ReplaceLeaderboards(ctx context.Context, leaderboards []*Leaderboard) error
+	// FindLeaderboard retrieves a Leaderboard by its name.
+	FindLeaderboard(ctx context.Context, name string) (*Leaderboard, error)
+	// ClaimEvents locks up to limit unpublished Event for a relay, for the lease duration, in the order they occurred.
+	ClaimEvents(ctx context.Context, relayID string, lease time.Duration, limit int) ([]*Event, error)
+	// MarkEventsPublished flags the given Event IDs as published.
+	MarkEventsPublished(ctx context.Context, ids []string) error
+	// CreateImport stores a new Import.
+	CreateImport(ctx context.Context, imp *Import) error
+	// UpdateImport stores the progress of an existing Import.
+	UpdateImport(ctx context.Context, imp *Import) error
+	// FindImport retrieves an Import by its ID.
+	FindImport(ctx context.Context, id string) (*Import, error)
+	// AddToWatchlist receives a validated input and adds a WatchlistEntry to the end of the user's watchlist.
+	// It returns ErrWatchlistEntryExists if the movie is already in it.
+	AddToWatchlist(ctx context.Context, entry *ValidatedWatchlistEntry) (*WatchlistEntry, error)
+	// UpdateWatchlistNote sets the note of a WatchlistEntry.
+	UpdateWatchlistNote(ctx context.Context, userID, movieID, note string) (*WatchlistEntry, error)
+	// MoveWatchlistEntry moves a WatchlistEntry to the given position, shifting the entries in between.
+	MoveWatchlistEntry(ctx context.Context, userID, movieID string, position int) (*WatchlistEntry, error)
+	// RemoveFromWatchlist removes a WatchlistEntry, shifting the entries after it.
+	RemoveFromWatchlist(ctx context.Context, userID, movieID string) error
+	// FindWatchlist retrieves a page of the user's watchlist in order, optionally filtered by genre.
+	FindWatchlist(ctx context.Context, userID, genre string, page, limit int) ([]*WatchlistEntry, error)
+	// LogDiaryEntry receives a validated input and stores a DiaryEntry, removing the movie from the user's watchlist.
+	// If it is the latest rated entry of the movie, the user's Rating is set to its value, recording an Event.
+	// The burst detection applies to that Rating as in Upsert.
+	LogDiaryEntry(ctx context.Context, entry *ValidatedDiaryEntry, burst *BurstPolicy) (*DiaryEntry, error)
+	// DeleteDiaryEntry removes a user's DiaryEntry. If it was the latest rated entry of the movie,
+	// the user's Rating falls back to the value of the previous rated entry, recording an Event.
+	DeleteDiaryEntry(ctx context.Context, userID, id string) error
+	// FindDiary retrieves a page of a user's DiaryEntry, most recently watched first, optionally of a single year.
+	FindDiary(ctx context.Context, userID string, year, page, limit int) ([]*DiaryEntry, error)
+	// FindUserStats retrieves the cached UserStats of a user, along with the version of the user's ratings.
+	// It returns ErrUserStatsNotCached if they were never computed or the user's ratings changed since.
+	FindUserStats(ctx context.Context, userID string) (*UserStats, int64, error)
+	// SaveUserStats caches the UserStats of a user computed from the given version of the user's ratings,
+	// see FindUserStats, until they change. Nothing is cached if they changed meanwhile.
+	SaveUserStats(ctx context.Context, stats *UserStats, version int64) error
+	// FindHeldRatings retrieves a page of the held Rating, the longest held first.
+	FindHeldRatings(ctx context.Context, page, limit int) ([]*Rating, error)
+	// ApproveRating releases a held Rating, which won't be held again, recording a RatingUpdated Event.
+	ApproveRating(ctx context.Context, userID, movieID string) (*Rating, error)
+	// RejectRating removes a held Rating, recording a RatingDeleted Event.
+	RejectRating(ctx context.Context, userID, movieID string) error
+	// DeleteUser removes everything a user left, recording a RatingDeleted Event per rating, and returns how many
+	// ratings were removed. The votes the user cast are removed too, keeping the reviews' vote counts in sync.
+	// It can be called again after a failure, as removed data is skipped.
+	DeleteUser(ctx context.Context, userID string) (int, error)
 	// Close disconnects the database connection pool.
 	Close(ctx context.Context) error
 }
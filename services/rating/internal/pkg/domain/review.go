@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrReviewNotFound is returned when a Rating has no review, e.g. when voting on it.
+var ErrReviewNotFound = errors.New("review not found")
+
+// Review is the optional written review attached to a Rating.
+type Review struct {
+	Title          string    `json:"title" bson:"title"`
+	Body           string    `json:"body" bson:"body"`
+	Spoiler        bool      `json:"spoiler" bson:"spoiler"`
+	Language       string    `json:"language" bson:"language"`
+	HelpfulVotes   int       `json:"helpfulVotes" bson:"helpfulVotes"`
+	UnhelpfulVotes int       `json:"unhelpfulVotes" bson:"unhelpfulVotes"`
+	CreatedAt      time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ReviewSort represents the sorting order of a review listing.
+type ReviewSort string
+
+// Valid ReviewSort values as constants.
+const (
+	ReviewSortNewest  ReviewSort = "newest"
+	ReviewSortHelpful ReviewSort = "helpful"
+)
+
+const (
+	maxReviewTitleLength = 120
+	maxReviewBodyLength  = 5000
+)
+
+// languageRegexp matches ISO 639-1 language codes (e.g. "en", "pt").
+var languageRegexp = regexp.MustCompile(`^[a-z]{2}$`)
+
+// NewReview returns an instance of the Review entity.
+func NewReview(title, body string, spoiler bool, language string) *Review {
+	return &Review{
+		Title:     title,
+		Body:      body,
+		Spoiler:   spoiler,
+		Language:  language,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// IsValid returns true if the given ReviewSort is supported.
+func (s ReviewSort) IsValid() bool {
+	return s == ReviewSortNewest || s == ReviewSortHelpful
+}
+
+func (rv *Review) validate() error {
+	if rv.Body == "" {
+		return errors.New("review body is required")
+	}
+	if utf8.RuneCountInString(rv.Body) > maxReviewBodyLength {
+		return errors.New("review body must have at most 5000 characters")
+	}
+	if utf8.RuneCountInString(rv.Title) > maxReviewTitleLength {
+		return errors.New("review title must have at most 120 characters")
+	}
+	if rv.Language != "" && !languageRegexp.MatchString(rv.Language) {
+		return errors.New("review language must be a two-letter ISO 639-1 code")
+	}
+	if rv.HelpfulVotes < 0 || rv.UnhelpfulVotes < 0 {
+		return errors.New("review vote counts must not be negative")
+	}
+	if rv.CreatedAt.After(rv.UpdatedAt) {
+		return errors.New("review created_at must be before updated_at")
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReview(t *testing.T) {
+	title := "A masterpiece"
+	body := "Loved every minute of it."
+	language := "en"
+
+	review := NewReview(title, body, true, language)
+
+	assert.Equal(t, title, review.Title)
+	assert.Equal(t, body, review.Body)
+	assert.True(t, review.Spoiler)
+	assert.Equal(t, language, review.Language)
+	assert.Zero(t, review.HelpfulVotes)
+	assert.Zero(t, review.UnhelpfulVotes)
+	assert.WithinDuration(t, time.Now(), review.CreatedAt, time.Second)
+	assert.WithinDuration(t, time.Now(), review.UpdatedAt, time.Second)
+}
+
+func TestReviewSort_IsValid(t *testing.T) {
+	assert.True(t, ReviewSortNewest.IsValid())
+	assert.True(t, ReviewSortHelpful.IsValid())
+	assert.False(t, ReviewSort("oldest").IsValid())
+	assert.False(t, ReviewSort("").IsValid())
+}
+
+func TestReview_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		review    *Review
+		wantError bool
+	}{
+		{
+			name:      "valid review",
+			review:    NewReview("A masterpiece", "Loved every minute of it.", false, "en"),
+			wantError: false,
+		},
+		{
+			name:      "valid review without title and language",
+			review:    NewReview("", "Loved every minute of it.", false, ""),
+			wantError: false,
+		},
+		{
+			name:      "missing body",
+			review:    NewReview("A masterpiece", "", false, "en"),
+			wantError: true,
+		},
+		{
+			name:      "body too long",
+			review:    NewReview("A masterpiece", strings.Repeat("a", 5001), false, "en"),
+			wantError: true,
+		},
+		{
+			name:      "title too long",
+			review:    NewReview(strings.Repeat("a", 121), "Loved every minute of it.", false, "en"),
+			wantError: true,
+		},
+		{
+			name:      "invalid language",
+			review:    NewReview("A masterpiece", "Loved every minute of it.", false, "english"),
+			wantError: true,
+		},
+		{
+			name: "negative helpful votes",
+			review: &Review{
+				Body:         "Loved every minute of it.",
+				HelpfulVotes: -1,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			},
+			wantError: true,
+		},
+		{
+			name: "negative unhelpful votes",
+			review: &Review{
+				Body:           "Loved every minute of it.",
+				UnhelpfulVotes: -1,
+				CreatedAt:      time.Now(),
+				UpdatedAt:      time.Now(),
+			},
+			wantError: true,
+		},
+		{
+			name: "created_at after updated_at",
+			review: &Review{
+				Body:      "Loved every minute of it.",
+				CreatedAt: time.Now().Add(1 * time.Hour),
+				UpdatedAt: time.Now(),
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.review.validate()
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Similarity holds the most similar movies of a movie, computed with item-based collaborative filtering.
+type Similarity struct {
+	MovieID   string     `json:"movieId" bson:"movieId"`
+	Neighbors []Neighbor `json:"neighbors" bson:"neighbors"`
+	UpdatedAt time.Time  `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Neighbor is a movie similar to another one, along with their similarity score.
+type Neighbor struct {
+	MovieID string  `json:"movieId" bson:"movieId"`
+	Score   float64 `json:"score" bson:"score"`
+}
+
+// SimilarityAccumulator builds item-to-item similarities one user at a time,
+// so the ratings collection can be streamed instead of loaded into memory.
+//
+// Similarity is the cosine between the movies' mean-centered rating vectors
+// (i.e. adjusted cosine), where each rating is centered on its user's mean.
+type SimilarityAccumulator struct {
+	maxRatingsPerUser int
+	maxNeighbors      int
+	minSupport        int
+	ids               map[string]uint32
+	movies            []string
+	norms             []float64
+	pairs             map[uint64]*pairStats
+}
+
+type pairStats struct {
+	dot     float64
+	support int
+}
+
+// NewSimilarityAccumulator returns a new instance of SimilarityAccumulator.
+// maxRatingsPerUser bounds the quadratic cost of a single user, keeping the most recent ratings,
+// maxNeighbors is the number of neighbors kept per movie and
+// minSupport is the minimum number of users that must have rated both movies of a pair.
+func NewSimilarityAccumulator(maxRatingsPerUser, maxNeighbors, minSupport int) *SimilarityAccumulator {
+	return &SimilarityAccumulator{
+		maxRatingsPerUser: maxRatingsPerUser,
+		maxNeighbors:      maxNeighbors,
+		minSupport:        minSupport,
+		ids:               make(map[string]uint32),
+		pairs:             make(map[uint64]*pairStats),
+	}
+}
+
+// Add accumulates all the ratings given by a single user.
+func (sa *SimilarityAccumulator) Add(ratings []*Rating) {
+	if len(ratings) < 2 {
+		return
+	}
+
+	if sa.maxRatingsPerUser > 0 && len(ratings) > sa.maxRatingsPerUser {
+		recent := make([]*Rating, len(ratings))
+		copy(recent, ratings)
+		sort.Slice(recent, func(i, j int) bool { return recent[i].UpdatedAt.After(recent[j].UpdatedAt) })
+		ratings = recent[:sa.maxRatingsPerUser]
+	}
+
+	mean := meanValue(ratings)
+
+	ids := make([]uint32, len(ratings))
+	deviations := make([]float64, len(ratings))
+	for i, r := range ratings {
+		ids[i] = sa.id(r.MovieID)
+		deviations[i] = float64(r.Value) - mean
+		sa.norms[ids[i]] += deviations[i] * deviations[i]
+	}
+
+	for i := 0; i < len(ids); i++ {
+		for j := i + 1; j < len(ids); j++ {
+			key := pairKey(ids[i], ids[j])
+			ps, ok := sa.pairs[key]
+			if !ok {
+				ps = &pairStats{}
+				sa.pairs[key] = ps
+			}
+			ps.dot += deviations[i] * deviations[j]
+			ps.support++
+		}
+	}
+}
+
+// Similarities returns the accumulated similarities, keeping only positively correlated neighbors.
+func (sa *SimilarityAccumulator) Similarities() []*Similarity {
+	neighbors := make(map[uint32][]Neighbor)
+
+	for key, ps := range sa.pairs {
+		if ps.support < sa.minSupport {
+			continue
+		}
+
+		a, b := uint32(key>>32), uint32(key)
+		denominator := math.Sqrt(sa.norms[a]) * math.Sqrt(sa.norms[b])
+		if denominator == 0 {
+			continue
+		}
+
+		score := ps.dot / denominator
+		if score <= 0 {
+			continue
+		}
+
+		neighbors[a] = append(neighbors[a], Neighbor{MovieID: sa.movies[b], Score: score})
+		neighbors[b] = append(neighbors[b], Neighbor{MovieID: sa.movies[a], Score: score})
+	}
+
+	now := time.Now()
+	list := make([]*Similarity, 0, len(neighbors))
+	for id, nn := range neighbors {
+		sort.Slice(nn, func(i, j int) bool { return nn[i].Score > nn[j].Score })
+		if sa.maxNeighbors > 0 && len(nn) > sa.maxNeighbors {
+			nn = nn[:sa.maxNeighbors]
+		}
+		list = append(list, &Similarity{
+			MovieID:   sa.movies[id],
+			Neighbors: nn,
+			UpdatedAt: now,
+		})
+	}
+
+	return list
+}
+
+func (sa *SimilarityAccumulator) id(movieID string) uint32 {
+	if id, ok := sa.ids[movieID]; ok {
+		return id
+	}
+	id := uint32(len(sa.movies))
+	sa.ids[movieID] = id
+	sa.movies = append(sa.movies, movieID)
+	sa.norms = append(sa.norms, 0)
+	return id
+}
+
+func pairKey(a, b uint32) uint64 {
+	if a > b {
+		a, b = b, a
+	}
+	return uint64(a)<<32 | uint64(b)
+}
+
+func meanValue(ratings []*Rating) float64 {
+	var sum float64
+	for _, r := range ratings {
+		sum += float64(r.Value)
+	}
+	return sum / float64(len(ratings))
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ratingsOf(userID string, values map[string]float32) []*Rating {
+	list := make([]*Rating, 0, len(values))
+	for movieID, value := range values {
+		list = append(list, NewRating(userID, movieID, value))
+	}
+	return list
+}
+
+func TestSimilarityAccumulator(t *testing.T) {
+	acc := NewSimilarityAccumulator(0, 10, 2)
+
+	// movies a and b are loved and hated together, c goes the opposite way
+	acc.Add(ratingsOf("alice", map[string]float32{"a": 5, "b": 4.5, "c": 1}))
+	acc.Add(ratingsOf("bob", map[string]float32{"a": 1, "b": 1.5, "c": 5}))
+	acc.Add(ratingsOf("carol", map[string]float32{"a": 4, "b": 4}))
+	// a single rating carries no similarity signal
+	acc.Add(ratingsOf("dave", map[string]float32{"c": 3}))
+
+	sims := acc.Similarities()
+
+	byMovie := make(map[string]*Similarity)
+	for _, s := range sims {
+		byMovie[s.MovieID] = s
+	}
+
+	assert.Len(t, sims, 2)
+	assert.Len(t, byMovie["a"].Neighbors, 1)
+	assert.Equal(t, "b", byMovie["a"].Neighbors[0].MovieID)
+	assert.Greater(t, byMovie["a"].Neighbors[0].Score, 0.9)
+	assert.Equal(t, "a", byMovie["b"].Neighbors[0].MovieID)
+	assert.Nil(t, byMovie["c"])
+}
+
+func TestSimilarityAccumulator_MinSupport(t *testing.T) {
+	acc := NewSimilarityAccumulator(0, 10, 3)
+
+	acc.Add(ratingsOf("alice", map[string]float32{"a": 5, "b": 4.5, "c": 1}))
+	acc.Add(ratingsOf("bob", map[string]float32{"a": 1, "b": 1.5, "c": 5}))
+
+	assert.Empty(t, acc.Similarities())
+}
+
+func TestSimilarityAccumulator_MaxRatingsPerUser(t *testing.T) {
+	acc := NewSimilarityAccumulator(2, 10, 1)
+
+	old := NewRating("alice", "a", 5)
+	old.UpdatedAt = time.Now().Add(-time.Hour)
+	acc.Add([]*Rating{old, NewRating("alice", "b", 1), NewRating("alice", "c", 5)})
+
+	// only the two most recent ratings (b and c) are considered
+	assert.Len(t, acc.ids, 2)
+	assert.NotContains(t, acc.ids, "a")
+}
+
+func TestSimilarityAccumulator_MaxNeighbors(t *testing.T) {
+	acc := NewSimilarityAccumulator(0, 1, 1)
+
+	acc.Add(ratingsOf("alice", map[string]float32{"a": 5, "b": 5, "c": 4, "d": 1}))
+	acc.Add(ratingsOf("bob", map[string]float32{"a": 4, "b": 4, "c": 4.5, "d": 1}))
+
+	for _, s := range acc.Similarities() {
+		assert.LessOrEqual(t, len(s.Neighbors), 1)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	ratings := ratingsOf("alice", map[string]float32{"a": 5, "c": 1})
+	similarities := []*Similarity{
+		{MovieID: "a", Neighbors: []Neighbor{{MovieID: "b", Score: 0.9}, {MovieID: "c", Score: 0.5}}},
+		{MovieID: "c", Neighbors: []Neighbor{{MovieID: "d", Score: 0.8}}},
+		{MovieID: "x", Neighbors: []Neighbor{{MovieID: "y", Score: 0.8}}},
+	}
+
+	list := Recommend(ratings, similarities, 10)
+
+	assert.Len(t, list, 2)
+	// b is similar to a movie alice loved, d to a movie she hated
+	assert.Equal(t, "b", list[0].MovieID)
+	assert.Equal(t, float64(5), list[0].Score)
+	assert.Equal(t, "d", list[1].MovieID)
+	assert.Equal(t, float64(1), list[1].Score)
+
+	assert.Len(t, Recommend(ratings, similarities, 1), 1)
+	assert.Empty(t, Recommend(nil, similarities, 10))
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrUserStatsNotCached is returned when the UserStats of a user have to be computed again.
+var ErrUserStatsNotCached = errors.New("user stats not cached")
+
+// UserStats is the aggregation of all the ratings of a user, shown on their profile.
+type UserStats struct {
+	UserID       string           `json:"userId" bson:"userId"`
+	Ratings      int              `json:"ratings" bson:"ratings"`
+	Mean         float64          `json:"mean" bson:"mean"`
+	Distribution []Bucket         `json:"distribution" bson:"distribution"`
+	Monthly      []MonthlyRatings `json:"monthly" bson:"monthly"`
+	Genres       []GenreStats     `json:"genres" bson:"genres"`
+	ComputedAt   time.Time        `json:"computedAt" bson:"computedAt"`
+}
+
+// MonthlyRatings is the number of ratings a user gave in a month, formatted as YYYY-MM.
+type MonthlyRatings struct {
+	Month   string `json:"month" bson:"month"`
+	Ratings int    `json:"ratings" bson:"ratings"`
+}
+
+// GenreStats is the number and mean of the ratings a user gave to the movies of a genre.
+type GenreStats struct {
+	Genre   string  `json:"genre" bson:"genre"`
+	Ratings int     `json:"ratings" bson:"ratings"`
+	Mean    float64 `json:"mean" bson:"mean"`
+}
+
+// NewUserStats builds the UserStats of a user from their ratings and the genres of the rated movies, by movie ID.
+// Ratings are counted in the month they were first given, and movies of unknown genres are left out of the genre stats.
+// Genres are sorted by number of ratings and then by mean, so the favourite ones come first, limited to topGenres.
+func NewUserStats(userID string, ratings []*Rating, genres map[string][]string, topGenres int) *UserStats {
+	stats := &UserStats{
+		UserID:     userID,
+		Ratings:    len(ratings),
+		Monthly:    []MonthlyRatings{},
+		Genres:     []GenreStats{},
+		ComputedAt: time.Now(),
+	}
+
+	var sum float64
+	counts := make(map[float32]int)
+	months := make(map[string]int)
+	genreSums := make(map[string]float64)
+	genreCounts := make(map[string]int)
+
+	for _, r := range ratings {
+		sum += float64(r.Value)
+		counts[r.Value]++
+		months[r.CreatedAt.UTC().Format("2006-01")]++
+
+		for _, g := range genres[r.MovieID] {
+			genreSums[g] += float64(r.Value)
+			genreCounts[g]++
+		}
+	}
+
+	if stats.Ratings > 0 {
+		stats.Mean = sum / float64(stats.Ratings)
+	}
+	stats.Distribution = NewDistribution(counts)
+
+	for m, n := range months {
+		stats.Monthly = append(stats.Monthly, MonthlyRatings{Month: m, Ratings: n})
+	}
+	sort.Slice(stats.Monthly, func(i, j int) bool { return stats.Monthly[i].Month < stats.Monthly[j].Month })
+
+	for g, n := range genreCounts {
+		stats.Genres = append(stats.Genres, GenreStats{Genre: g, Ratings: n, Mean: genreSums[g] / float64(n)})
+	}
+	sort.Slice(stats.Genres, func(i, j int) bool {
+		a, b := stats.Genres[i], stats.Genres[j]
+		if a.Ratings != b.Ratings {
+			return a.Ratings > b.Ratings
+		}
+		if a.Mean != b.Mean {
+			return a.Mean > b.Mean
+		}
+		return a.Genre < b.Genre
+	})
+	if len(stats.Genres) > topGenres {
+		stats.Genres = stats.Genres[:topGenres]
+	}
+
+	return stats
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserStats(t *testing.T) {
+	ratings := ratingsOf("alice", map[string]float32{"a": 5, "b": 4, "c": 1, "d": 3})
+	for _, r := range ratings {
+		if r.MovieID == "a" {
+			r.CreatedAt = time.Date(2023, time.December, 31, 12, 0, 0, 0, time.UTC)
+		}
+	}
+	genres := map[string][]string{
+		"a": {"Drama", "Comedy"},
+		"b": {"Drama"},
+		"c": {"Horror"},
+		"d": {"Comedy"},
+	}
+
+	stats := NewUserStats("alice", ratings, genres, 2)
+
+	assert.Equal(t, "alice", stats.UserID)
+	assert.Equal(t, 4, stats.Ratings)
+	assert.InDelta(t, 3.25, stats.Mean, 0.0001)
+	assert.Len(t, stats.Distribution, 10)
+	assert.Equal(t, Bucket{Value: 4, Ratings: 1}, stats.Distribution[7])
+
+	assert.Len(t, stats.Monthly, 2)
+	assert.Equal(t, MonthlyRatings{Month: "2023-12", Ratings: 1}, stats.Monthly[0])
+	assert.Equal(t, 3, stats.Monthly[1].Ratings)
+
+	// Drama and Comedy tie on count, Drama has the higher mean, Horror is left out by the limit
+	assert.Equal(t, []GenreStats{
+		{Genre: "Drama", Ratings: 2, Mean: 4.5},
+		{Genre: "Comedy", Ratings: 2, Mean: 4},
+	}, stats.Genres)
+}
+
+func TestNewUserStats_Empty(t *testing.T) {
+	stats := NewUserStats("alice", nil, nil, 5)
+
+	assert.Equal(t, 0, stats.Ratings)
+	assert.Equal(t, float64(0), stats.Mean)
+	assert.Len(t, stats.Distribution, 10)
+	assert.Empty(t, stats.Monthly)
+	assert.Empty(t, stats.Genres)
+}
+
+func TestNewUserStats_ReRated(t *testing.T) {
+	// a re-rated movie keeps the creation time of its first rating, which it is counted in the month of
+	ratings := ratingsOf("alice", map[string]float32{"a": 4})
+	ratings[0].CreatedAt = time.Date(2023, time.November, 5, 12, 0, 0, 0, time.UTC)
+	ratings[0].UpdatedAt = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	stats := NewUserStats("alice", ratings, nil, 5)
+
+	assert.Equal(t, []MonthlyRatings{{Month: "2023-11", Ratings: 1}}, stats.Monthly)
+}
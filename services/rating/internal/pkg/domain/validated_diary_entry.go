@@ -0,0 +1,24 @@
+package domain
+
+// ValidatedDiaryEntry is used to validate an instance of DiaryEntry data.
+type ValidatedDiaryEntry struct {
+	DiaryEntry
+	isValidated bool
+}
+
+// IsValid returns true if the instance of DiaryEntry is validated.
+func (vde *ValidatedDiaryEntry) IsValid() bool {
+	return vde.isValidated
+}
+
+// NewValidatedDiaryEntry returns an instance of ValidatedDiaryEntry if the given DiaryEntry instance is valid.
+func NewValidatedDiaryEntry(entry *DiaryEntry) (*ValidatedDiaryEntry, error) {
+	if err := entry.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ValidatedDiaryEntry{
+		DiaryEntry:  *entry,
+		isValidated: true,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package domain
+
+// ValidatedVote is used to validate an instance of Vote data.
+type ValidatedVote struct {
+	Vote
+	isValidated bool
+}
+
+// IsValid returns true if the instance of Vote is validated.
+func (vv *ValidatedVote) IsValid() bool {
+	return vv.isValidated
+}
+
+// NewValidatedVote returns an instance of ValidatedVote if the given Vote instance is valid.
+func NewValidatedVote(vote *Vote) (*ValidatedVote, error) {
+	if err := vote.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ValidatedVote{
+		Vote:        *vote,
+		isValidated: true,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package domain
+
+// ValidatedWatchlistEntry is used to validate an instance of WatchlistEntry data.
+type ValidatedWatchlistEntry struct {
+	WatchlistEntry
+	isValidated bool
+}
+
+// IsValid returns true if the instance of WatchlistEntry is validated.
+func (vwe *ValidatedWatchlistEntry) IsValid() bool {
+	return vwe.isValidated
+}
+
+// NewValidatedWatchlistEntry returns an instance of ValidatedWatchlistEntry if the given WatchlistEntry instance is valid.
+func NewValidatedWatchlistEntry(entry *WatchlistEntry) (*ValidatedWatchlistEntry, error) {
+	if err := entry.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ValidatedWatchlistEntry{
+		WatchlistEntry: *entry,
+		isValidated:    true,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Vote entity represents a user marking another user's review as helpful or unhelpful.
+type Vote struct {
+	ID        string    `json:"id" bson:"id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	MovieID   string    `json:"movieId" bson:"movieId"`
+	VoterID   string    `json:"voterId" bson:"voterId"`
+	Helpful   bool      `json:"helpful" bson:"helpful"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// NewVote returns an instance of the Vote entity.
+func NewVote(userID, movieID, voterID string, helpful bool) *Vote {
+	return &Vote{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		MovieID:   movieID,
+		VoterID:   voterID,
+		Helpful:   helpful,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (v *Vote) validate() error {
+	if v.ID == "" {
+		return errors.New("id is required")
+	}
+	if v.UserID == "" {
+		return errors.New("userId is required")
+	}
+	if v.MovieID == "" {
+		return errors.New("movieId is required")
+	}
+	if v.VoterID == "" {
+		return errors.New("voterId is required")
+	}
+	if v.UserID == v.VoterID {
+		return errors.New("users cannot vote on their own reviews")
+	}
+	if v.CreatedAt.After(v.UpdatedAt) {
+		return errors.New("created_at must be before updated_at")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVote(t *testing.T) {
+	userID := "user-123"
+	movieID := "movie-456"
+	voterID := "user-789"
+
+	vote := NewVote(userID, movieID, voterID, true)
+
+	assert.NotEmpty(t, vote.ID)
+	assert.Equal(t, userID, vote.UserID)
+	assert.Equal(t, movieID, vote.MovieID)
+	assert.Equal(t, voterID, vote.VoterID)
+	assert.True(t, vote.Helpful)
+	assert.WithinDuration(t, time.Now(), vote.CreatedAt, time.Second)
+	assert.WithinDuration(t, time.Now(), vote.UpdatedAt, time.Second)
+}
+
+func TestVote_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		vote      *Vote
+		wantError bool
+	}{
+		{
+			name:      "valid vote",
+			vote:      NewVote("user-123", "movie-456", "user-789", false),
+			wantError: false,
+		},
+		{
+			name: "missing ID",
+			vote: &Vote{
+				UserID:    "user-123",
+				MovieID:   "movie-456",
+				VoterID:   "user-789",
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			wantError: true,
+		},
+		{
+			name:      "missing UserID",
+			vote:      NewVote("", "movie-456", "user-789", true),
+			wantError: true,
+		},
+		{
+			name:      "missing MovieID",
+			vote:      NewVote("user-123", "", "user-789", true),
+			wantError: true,
+		},
+		{
+			name:      "missing VoterID",
+			vote:      NewVote("user-123", "movie-456", "", true),
+			wantError: true,
+		},
+		{
+			name:      "vote on own review",
+			vote:      NewVote("user-123", "movie-456", "user-123", true),
+			wantError: true,
+		},
+		{
+			name: "created_at after updated_at",
+			vote: &Vote{
+				ID:        uuid.New().String(),
+				UserID:    "user-123",
+				MovieID:   "movie-456",
+				VoterID:   "user-789",
+				CreatedAt: time.Now().Add(1 * time.Hour),
+				UpdatedAt: time.Now(),
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.vote.validate()
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
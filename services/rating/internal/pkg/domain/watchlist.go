@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxWatchlistNoteLength is the maximum number of characters of a watchlist note.
+const maxWatchlistNoteLength = 500
+
+// ErrWatchlistEntryExists is returned when a movie is added twice to a watchlist.
+var ErrWatchlistEntryExists = errors.New("movie is already in the watchlist")
+
+// WatchlistEntry entity represents a movie a user plans to watch.
+// Entries are ordered by their position in the watchlist, starting at 1.
+type WatchlistEntry struct {
+	ID        string    `json:"id" bson:"id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	MovieID   string    `json:"movieId" bson:"movieId"`
+	Note      string    `json:"note,omitempty" bson:"note,omitempty"`
+	Genres    []string  `json:"genres" bson:"genres"`
+	Position  int       `json:"position" bson:"position"`
+	AddedAt   time.Time `json:"addedAt" bson:"addedAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// NewWatchlistEntry returns an instance of the WatchlistEntry entity.
+// Its position is assigned when it is added to the watchlist.
+func NewWatchlistEntry(userID, movieID, note string, genres []string) *WatchlistEntry {
+	if genres == nil {
+		genres = []string{}
+	}
+
+	return &WatchlistEntry{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		MovieID:   movieID,
+		Note:      note,
+		Genres:    genres,
+		AddedAt:   time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (we *WatchlistEntry) validate() error {
+	if we.ID == "" {
+		return errors.New("id is required")
+	}
+	if we.UserID == "" {
+		return errors.New("userId is required")
+	}
+	if we.MovieID == "" {
+		return errors.New("movieId is required")
+	}
+	if err := ValidateWatchlistNote(we.Note); err != nil {
+		return err
+	}
+	if we.Position < 0 {
+		return errors.New("position must be positive")
+	}
+	if we.AddedAt.After(we.UpdatedAt) {
+		return errors.New("added_at must be before updated_at")
+	}
+
+	return nil
+}
+
+// ValidateWatchlistNote returns an error if the note is too long.
+func ValidateWatchlistNote(note string) error {
+	if utf8.RuneCountInString(note) > maxWatchlistNoteLength {
+		return errors.New("note must be up to 500 characters")
+	}
+	return nil
+}
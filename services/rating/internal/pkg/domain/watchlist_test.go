@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWatchlistEntry(t *testing.T) {
+	entry := NewWatchlistEntry("user-123", "movie-456", "recommended by a friend", []string{"Drama"})
+
+	assert.NotEmpty(t, entry.ID)
+	assert.Equal(t, "user-123", entry.UserID)
+	assert.Equal(t, "movie-456", entry.MovieID)
+	assert.Equal(t, "recommended by a friend", entry.Note)
+	assert.Equal(t, []string{"Drama"}, entry.Genres)
+	assert.Zero(t, entry.Position)
+	assert.WithinDuration(t, time.Now(), entry.AddedAt, time.Second)
+	assert.NotNil(t, NewWatchlistEntry("user-123", "movie-456", "", nil).Genres)
+}
+
+func TestWatchlistEntry_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     *WatchlistEntry
+		wantError bool
+	}{
+		{
+			name:      "valid entry",
+			entry:     NewWatchlistEntry("user-123", "movie-456", "", nil),
+			wantError: false,
+		},
+		{
+			name:      "missing UserID",
+			entry:     NewWatchlistEntry("", "movie-456", "", nil),
+			wantError: true,
+		},
+		{
+			name:      "missing MovieID",
+			entry:     NewWatchlistEntry("user-123", "", "", nil),
+			wantError: true,
+		},
+		{
+			name:      "note too long",
+			entry:     NewWatchlistEntry("user-123", "movie-456", strings.Repeat("a", 501), nil),
+			wantError: true,
+		},
+		{
+			name: "added_at after updated_at",
+			entry: &WatchlistEntry{
+				ID:        "entry-1",
+				UserID:    "user-123",
+				MovieID:   "movie-456",
+				AddedAt:   time.Now().Add(time.Hour),
+				UpdatedAt: time.Now(),
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.validate()
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	movieClient "github.com/victorspringer/backend-coding-challenge/services/movie/pkg/client"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+)
+
+// progressInterval is how often the progress of a running import is stored.
+const progressInterval = time.Second
+
+// Common errors.
+var (
+	ErrInvalidFile = errors.New("invalid file")
+	ErrQueueFull   = errors.New("too many imports in progress, try again later")
+)
+
+// Importer runs the bulk rating imports in the background, one at a time.
+type Importer struct {
+	repository  domain.Repository
+	mc          *movieClient.Client
+	logger      *log.Logger
+	serviceKey  string
+	workers     int
+	maxFileSize int64
+	maxRows     int
+	queue       chan *job
+}
+
+type job struct {
+	imp   *domain.Import
+	rows  []domain.ImportRow
+	burst *domain.BurstPolicy
+}
+
+// NewImporter returns a new instance of Importer.
+// The movies are looked up with the service key of the movie service, as the imports may run after the access token
+// of the requester expired. workers is the number of rows matched concurrently, queueSize the number of imports that can wait to run
+// and maxFileSize (in bytes) and maxRows bound the size of an export.
+func NewImporter(
+	repo domain.Repository,
+	mc *movieClient.Client,
+	logger *log.Logger,
+	serviceKey string,
+	workers,
+	queueSize int,
+	maxFileSize int64,
+	maxRows int,
+) *Importer {
+	return &Importer{
+		repository:  repo,
+		mc:          mc,
+		logger:      logger,
+		serviceKey:  serviceKey,
+		workers:     workers,
+		maxFileSize: maxFileSize,
+		maxRows:     maxRows,
+		queue:       make(chan *job, queueSize),
+	}
+}
+
+// MaxFileSize returns the maximum size of an export, in bytes.
+func (im *Importer) MaxFileSize() int64 {
+	return im.maxFileSize
+}
+
+// Start parses an export of the user's ratings, stores a new Import and schedules it to run in the background.
+// The imported ratings take part in the burst detection of the given policy, if any, as the ratings given here do.
+func (im *Importer) Start(ctx context.Context, userID string, file io.Reader, burst *domain.BurstPolicy) (*domain.Import, error) {
+	source, rows, unmatched, err := domain.ParseImport(file, im.maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	imp := domain.NewImport(userID, source, len(rows)+len(unmatched), unmatched)
+	if err := im.repository.CreateImport(ctx, imp); err != nil {
+		return nil, err
+	}
+
+	select {
+	case im.queue <- &job{imp: imp, rows: rows, burst: burst}:
+		return imp, nil
+	default:
+		now := time.Now()
+		imp.Status, imp.Error, imp.FinishedAt, imp.UpdatedAt = domain.ImportFailed, ErrQueueFull.Error(), &now, now
+		if err := im.repository.UpdateImport(ctx, imp); err != nil {
+			im.logger.Error("failed to save import", log.String("importId", imp.ID), log.Error(err))
+		}
+		return nil, ErrQueueFull
+	}
+}
+
+// Run processes the queued imports until ctx is done.
+func (im *Importer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-im.queue:
+			im.process(ctx, j)
+		}
+	}
+}
+
+func (im *Importer) process(ctx context.Context, j *job) {
+	start := time.Now()
+	imp := j.imp
+
+	// the import is shared with the workers, so the mutex guards it
+	var mu sync.Mutex
+	save := func() {
+		imp.UpdatedAt = time.Now()
+		if err := im.repository.UpdateImport(context.WithoutCancel(ctx), imp); err != nil {
+			im.logger.Error("failed to save import progress", log.String("importId", imp.ID), log.Error(err))
+		}
+	}
+
+	mu.Lock()
+	imp.Status = domain.ImportRunning
+	save()
+	mu.Unlock()
+
+	rows := make(chan domain.ImportRow)
+	var wg sync.WaitGroup
+	for i := 0; i < im.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range rows {
+				imported, reason := im.importRow(ctx, imp.UserID, row, j.burst)
+
+				mu.Lock()
+				imp.ProcessedRows++
+				switch {
+				case reason != "":
+					imp.Unmatch(row, reason)
+				case imported:
+					imp.Imported++
+				default:
+					imp.Skipped++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+feed:
+	for _, row := range j.rows {
+		for {
+			select {
+			case <-ctx.Done():
+				break feed
+			case <-ticker.C:
+				mu.Lock()
+				save()
+				mu.Unlock()
+				continue
+			case rows <- row:
+			}
+			break
+		}
+	}
+	close(rows)
+	wg.Wait()
+
+	now := time.Now()
+	imp.FinishedAt = &now
+	imp.Status = domain.ImportCompleted
+	if ctx.Err() != nil {
+		imp.Status = domain.ImportFailed
+		imp.Error = "import interrupted, the service was stopped"
+	}
+	save()
+
+	im.logger.Info(
+		"ratings imported",
+		log.String("importId", imp.ID),
+		log.String("status", string(imp.Status)),
+		log.Int("imported", imp.Imported),
+		log.Int("skipped", imp.Skipped),
+		log.Int("unmatched", len(imp.Unmatched)),
+		log.String("duration", time.Since(start).String()),
+	)
+}
+
+// importRow matches a row to a movie and rates it, unless the user already rated it here.
+// It returns the reason why the row was not imported, if it wasn't matched.
+func (im *Importer) importRow(ctx context.Context, userID string, row domain.ImportRow, burst *domain.BurstPolicy) (bool, string) {
+	movie, reason := im.match(ctx, row)
+	if movie == nil {
+		return false, reason
+	}
+
+	// ratings given here are more up to date than the imported ones
+	if _, err := im.repository.FindByUserAndMovieID(ctx, userID, movie.ID); err == nil {
+		return false, ""
+	}
+
+	rat := domain.NewRating(userID, movie.ID, row.Value)
+	if !row.RatedAt.IsZero() && row.RatedAt.Before(rat.CreatedAt) {
+		rat.CreatedAt, rat.UpdatedAt = row.RatedAt, row.RatedAt
+	}
+
+	vr, err := domain.NewValidatedRating(rat)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	// new accounts could otherwise rate-bomb a movie by importing a forged export
+	saved, err := im.repository.Upsert(ctx, vr, burst)
+	if err != nil {
+		im.logger.Error("failed to import rating", log.String("movieId", movie.ID), log.Error(err))
+		return false, "failed to save rating"
+	}
+
+	if saved.Moderation == domain.ModerationHeld {
+		im.logger.Warn("imported rating held for moderation", log.String("movieId", saved.MovieID), log.String("userId", saved.UserID))
+	}
+
+	// genres are kept along with the ratings to build the leaderboards
+	if err := im.repository.SaveMovieGenres(ctx, movie.ID, movie.Genres); err != nil {
+		im.logger.Warn("failed to save movie genres", log.String("movieId", movie.ID), log.Error(err))
+	}
+
+	return true, ""
+}
+
+// match looks the movie of a row up by IMDb ID, falling back to its title and year.
+func (im *Importer) match(ctx context.Context, row domain.ImportRow) (*movieClient.Movie, string) {
+	if row.IMDbID != "" {
+		list, err := im.mc.SearchAsService(ctx, row.IMDbID, "", 0, im.serviceKey)
+		if err != nil {
+			return nil, "movie service unavailable"
+		}
+		if len(list) > 0 {
+			return list[0], ""
+		}
+	}
+
+	if row.Title == "" {
+		return nil, "no matching movie"
+	}
+
+	list, err := im.mc.SearchAsService(ctx, "", row.Title, row.Year, im.serviceKey)
+	if err != nil {
+		return nil, "movie service unavailable"
+	}
+
+	switch len(list) {
+	case 0:
+		return nil, "no matching movie"
+	case 1:
+		return list[0], ""
+	default:
+		return nil, fmt.Sprintf("ambiguous match, %d movies found", len(list))
+	}
+}
@@ -0,0 +1,202 @@
+package leaderboard
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+)
+
+// genresBatchSize is the number of movies whose genres are retrieved at once.
+const genresBatchSize = 1000
+
+// Builder periodically rebuilds the leaderboards from the ratings collection.
+type Builder struct {
+	repository       domain.Repository
+	logger           *log.Logger
+	interval         time.Duration
+	size             int
+	minRatings       int
+	trendingWindow   time.Duration
+	trendingHalfLife time.Duration
+}
+
+// NewBuilder returns a new instance of Builder.
+func NewBuilder(
+	repo domain.Repository,
+	logger *log.Logger,
+	interval time.Duration,
+	size,
+	minRatings int,
+	trendingWindow,
+	trendingHalfLife time.Duration,
+) *Builder {
+	return &Builder{
+		repository:       repo,
+		logger:           logger,
+		interval:         interval,
+		size:             size,
+		minRatings:       minRatings,
+		trendingWindow:   trendingWindow,
+		trendingHalfLife: trendingHalfLife,
+	}
+}
+
+// Run builds the leaderboards right away and then once every interval, until ctx is done.
+func (b *Builder) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Build(ctx); err != nil {
+			b.logger.Error("failed to build leaderboards", log.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Build computes all the leaderboards and replaces the stored ones.
+func (b *Builder) Build(ctx context.Context) error {
+	start := time.Now()
+	b.logger.Debug("building leaderboards")
+
+	var (
+		stats []domain.MovieStats
+		count int
+		sum   float64
+	)
+	err := b.repository.ForEachMovieStats(ctx, func(ms domain.MovieStats) error {
+		stats = append(stats, ms)
+		count += ms.Ratings
+		sum += ms.Sum
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	leaderboards := []*domain.Leaderboard{}
+
+	if count > 0 {
+		leaderboards = append(leaderboards, b.topRated(stats, sum/float64(count)))
+	}
+
+	mostRated, err := b.mostRated(ctx, stats)
+	if err != nil {
+		return err
+	}
+	leaderboards = append(leaderboards, mostRated...)
+
+	trending, err := b.trending(ctx)
+	if err != nil {
+		return err
+	}
+	leaderboards = append(leaderboards, trending)
+
+	if err := b.repository.ReplaceLeaderboards(ctx, leaderboards); err != nil {
+		return err
+	}
+
+	b.logger.Info(
+		"leaderboards built",
+		log.Int("leaderboards", len(leaderboards)),
+		log.String("duration", time.Since(start).String()),
+	)
+
+	return nil
+}
+
+// topRated ranks movies by their Bayesian average, so a few high ratings don't top the chart.
+func (b *Builder) topRated(stats []domain.MovieStats, globalMean float64) *domain.Leaderboard {
+	rk := domain.NewRanking(b.size)
+	for _, ms := range stats {
+		rk.Push(domain.LeaderboardEntry{
+			MovieID: ms.MovieID,
+			Score:   domain.BayesianAverage(ms.Average(), ms.Ratings, globalMean, b.minRatings),
+			Ratings: ms.Ratings,
+			Average: ms.Average(),
+		})
+	}
+
+	return &domain.Leaderboard{Name: domain.TopRatedLeaderboard, Entries: rk.Entries()}
+}
+
+// mostRated ranks movies of each genre by their number of ratings.
+func (b *Builder) mostRated(ctx context.Context, stats []domain.MovieStats) ([]*domain.Leaderboard, error) {
+	rankings := make(map[string]*domain.Ranking)
+
+	for i := 0; i < len(stats); i += genresBatchSize {
+		end := i + genresBatchSize
+		if end > len(stats) {
+			end = len(stats)
+		}
+
+		ids := make([]string, 0, end-i)
+		for _, ms := range stats[i:end] {
+			ids = append(ids, ms.MovieID)
+		}
+
+		genres, err := b.repository.FindMovieGenres(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ms := range stats[i:end] {
+			for _, g := range genres[ms.MovieID] {
+				name := domain.MostRatedLeaderboard(g)
+				rk, ok := rankings[name]
+				if !ok {
+					rk = domain.NewRanking(b.size)
+					rankings[name] = rk
+				}
+				rk.Push(domain.LeaderboardEntry{
+					MovieID: ms.MovieID,
+					Score:   float64(ms.Ratings),
+					Ratings: ms.Ratings,
+					Average: ms.Average(),
+				})
+			}
+		}
+	}
+
+	list := make([]*domain.Leaderboard, 0, len(rankings))
+	for name, rk := range rankings {
+		list = append(list, &domain.Leaderboard{Name: name, Entries: rk.Entries()})
+	}
+
+	return list, nil
+}
+
+// trending ranks movies by their rating activity in the trending window, recent ratings weighing more.
+func (b *Builder) trending(ctx context.Context) (*domain.Leaderboard, error) {
+	now := time.Now()
+
+	scores := make(map[string]*domain.LeaderboardEntry)
+	err := b.repository.ForEachRatingSince(ctx, now.Add(-b.trendingWindow), func(r *domain.Rating) error {
+		e, ok := scores[r.MovieID]
+		if !ok {
+			e = &domain.LeaderboardEntry{MovieID: r.MovieID}
+			scores[r.MovieID] = e
+		}
+		e.Score += domain.DecayWeight(now.Sub(r.UpdatedAt), b.trendingHalfLife)
+		e.Average = (e.Average*float64(e.Ratings) + float64(r.Value)) / float64(e.Ratings+1)
+		e.Ratings++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rk := domain.NewRanking(b.size)
+	for _, e := range scores {
+		rk.Push(*e)
+	}
+
+	return &domain.Leaderboard{Name: domain.TrendingLeaderboard, Entries: rk.Entries()}, nil
+}
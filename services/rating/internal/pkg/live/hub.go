@@ -0,0 +1,271 @@
+package live
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
+)
+
+// Update types, sent as the server-sent event names.
+const (
+	RatingUpdate  = "rating"
+	SummaryUpdate = "summary"
+)
+
+const (
+	// retryInterval is how long the hub waits before subscribing again to the stream after a failure.
+	retryInterval = time.Second
+	// replayBatchSize is the number of stream entries read at once when replaying.
+	replayBatchSize = 1000
+)
+
+// Update is a change of a movie's ratings sent to the clients following it.
+// Rating updates carry the stream entry ID of their event, so clients can resume after it.
+type Update struct {
+	ID   string
+	Type string
+	Data interface{}
+}
+
+// Client is a follower of a movie's updates.
+type Client struct {
+	movieID string
+	updates chan *Update
+}
+
+// Updates returns the channel the client's updates are sent to.
+// It is closed when the client falls behind or the hub is closed, the client may then resume from its last update ID.
+func (c *Client) Updates() <-chan *Update {
+	return c.updates
+}
+
+// Hub fans the rating event stream out to the clients following a movie on this replica.
+// Every replica reads the whole stream, so clients get the changes whatever the replica that handled them.
+type Hub struct {
+	subscriber *events.Subscriber
+	repository domain.Repository
+	logger     *log.Logger
+	heartbeat  time.Duration
+	bufferSize int
+	maxReplay  int
+	mu         sync.RWMutex
+	clients    map[string]map[*Client]struct{}
+	closed     bool
+}
+
+// NewHub returns a new instance of Hub.
+// heartbeat is how often idle connections are kept alive, bufferSize the number of updates a client can fall behind
+// and maxReplay the maximum number of stream entries read when a client resumes.
+func NewHub(
+	subscriber *events.Subscriber,
+	repo domain.Repository,
+	logger *log.Logger,
+	heartbeat time.Duration,
+	bufferSize,
+	maxReplay int,
+) *Hub {
+	return &Hub{
+		subscriber: subscriber,
+		repository: repo,
+		logger:     logger,
+		heartbeat:  heartbeat,
+		bufferSize: bufferSize,
+		maxReplay:  maxReplay,
+		clients:    make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Heartbeat returns how often idle connections are kept alive.
+func (h *Hub) Heartbeat() time.Duration {
+	return h.heartbeat
+}
+
+// Run delivers the events published from now on to the clients, until ctx is done.
+func (h *Hub) Run(ctx context.Context) {
+	for {
+		err := h.subscriber.Subscribe(ctx, events.OffsetNewest, h.dispatch)
+		if err == nil {
+			return
+		}
+
+		// events published until the hub subscribes again are missed, clients get them on their next summary
+		h.logger.Error("failed to read rating events", log.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// Join adds a client following the updates of a movie.
+func (h *Hub) Join(movieID string) *Client {
+	c := &Client{movieID: movieID, updates: make(chan *Update, h.bufferSize)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(c.updates)
+		return c
+	}
+
+	if h.clients[movieID] == nil {
+		h.clients[movieID] = make(map[*Client]struct{})
+	}
+	h.clients[movieID][c] = struct{}{}
+
+	return c
+}
+
+// Leave removes a client, closing its updates channel if it is still open.
+func (h *Hub) Leave(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(c)
+}
+
+// Close disconnects all the clients, e.g. when the server is shutting down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, clients := range h.clients {
+		for c := range clients {
+			h.remove(c)
+		}
+	}
+}
+
+// remove must be called with the lock held.
+func (h *Hub) remove(c *Client) {
+	clients, ok := h.clients[c.movieID]
+	if !ok {
+		return
+	}
+	if _, ok := clients[c]; !ok {
+		return
+	}
+
+	delete(clients, c)
+	if len(clients) == 0 {
+		delete(h.clients, c.movieID)
+	}
+	close(c.updates)
+}
+
+// Replay returns the rating updates of a movie published after the given stream entry ID, but the held ones,
+// followed by its summary. If more than maxReplay stream entries were published since, none is replayed, as the
+// summary reflects them anyway, and the client resumes from the end of the stream.
+// It returns the ID of the stream entry the client resumes after.
+func (h *Hub) Replay(ctx context.Context, movieID, after string) ([]*Update, string, error) {
+	// entries published from now on reach the client as it joined before, so the replay stops at the current end
+	tail, err := h.subscriber.LastID(ctx)
+	if err != nil {
+		return nil, after, err
+	}
+
+	list := []*Update{}
+	caughtUp := false
+
+	for read := 0; read < h.maxReplay && !caughtUp; read += replayBatchSize {
+		count := replayBatchSize
+		if h.maxReplay-read < count {
+			count = h.maxReplay - read
+		}
+
+		messages, last, err := h.subscriber.Range(ctx, after, int64(count))
+		if err != nil {
+			return nil, after, err
+		}
+
+		for _, m := range messages {
+			if m.Event.Rating.MovieID == movieID && !m.Event.Rating.Held {
+				list = append(list, ratingUpdate(m))
+			}
+		}
+
+		caughtUp = last == after
+		if c, err := events.CompareIDs(last, tail); err != nil || c >= 0 {
+			caughtUp = true
+		}
+		after = last
+	}
+
+	// too far behind, the client skips to the summary
+	if !caughtUp {
+		list = []*Update{}
+		after = tail
+	}
+
+	summary, err := h.Summary(ctx, movieID)
+	if err != nil {
+		return nil, after, err
+	}
+
+	return append(list, summary), after, nil
+}
+
+// Summary returns the current summary of a movie's ratings as an update.
+func (h *Hub) Summary(ctx context.Context, movieID string) (*Update, error) {
+	summary, err := h.repository.FindRatingSummary(ctx, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Update{Type: SummaryUpdate, Data: summary}, nil
+}
+
+func (h *Hub) dispatch(ctx context.Context, m *events.Message) {
+	movieID := m.Event.Rating.MovieID
+
+	h.mu.RLock()
+	followed := len(h.clients[movieID]) > 0
+	h.mu.RUnlock()
+
+	if !followed {
+		return
+	}
+
+	// held ratings are not shown, though holding one changes the summary
+	updates := []*Update{}
+	if !m.Event.Rating.Held {
+		updates = append(updates, ratingUpdate(m))
+	}
+
+	// the summary is computed once per event, whatever the number of clients
+	summary, err := h.Summary(ctx, movieID)
+	if err != nil {
+		h.logger.Error("failed to summarize ratings", log.String("movieId", movieID), log.Error(err))
+	} else {
+		updates = append(updates, summary)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients[movieID] {
+		for _, u := range updates {
+			select {
+			case c.updates <- u:
+			default:
+				// a slow client is disconnected rather than slowing everyone down, it resumes from its last update
+				h.remove(c)
+			}
+			if _, ok := h.clients[movieID][c]; !ok {
+				break
+			}
+		}
+	}
+}
+
+func ratingUpdate(m *events.Message) *Update {
+	return &Update{ID: m.StreamID, Type: RatingUpdate, Data: m.Event}
+}
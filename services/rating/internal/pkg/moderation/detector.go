@@ -0,0 +1,37 @@
+package moderation
+
+import (
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+)
+
+// Detector tells which ratings take part in the detection of suspicious bursts: the ones given by new accounts.
+// The repository tracks their velocity per movie and holds the ratings of a burst as they are stored,
+// so the held ratings are never published as visible.
+type Detector struct {
+	policy domain.BurstPolicy
+}
+
+// NewDetector returns a new instance of Detector.
+// A burst is more than maxNewAccountRatings ratings given to a movie within window by accounts younger than newAccountAge.
+func NewDetector(window, newAccountAge time.Duration, maxNewAccountRatings int) *Detector {
+	return &Detector{
+		policy: domain.BurstPolicy{
+			Window:               window,
+			NewAccountAge:        newAccountAge,
+			MaxNewAccountRatings: maxNewAccountRatings,
+		},
+	}
+}
+
+// Policy returns the BurstPolicy a rating given by a user whose account was created at the given time is stored with,
+// or nil if the account is not new.
+func (d *Detector) Policy(accountCreatedAt time.Time) *domain.BurstPolicy {
+	if !d.policy.IsNewAccount(accountCreatedAt, time.Now()) {
+		return nil
+	}
+
+	policy := d.policy
+	return &policy
+}
@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
+)
+
+// Relay publishes the events written to the outbox to the event stream.
+// Several replicas can run a relay, each one only publishes the events it claimed.
+// An event may be published more than once if a relay fails before marking it as published.
+type Relay struct {
+	id         string
+	repository domain.Repository
+	publisher  *events.Publisher
+	logger     *log.Logger
+	interval   time.Duration
+	batchSize  int
+	lease      time.Duration
+}
+
+// NewRelay returns a new instance of Relay.
+// lease is how long the claimed events are locked for this relay before another one can claim them.
+func NewRelay(
+	repo domain.Repository,
+	publisher *events.Publisher,
+	logger *log.Logger,
+	interval time.Duration,
+	batchSize int,
+	lease time.Duration,
+) *Relay {
+	return &Relay{
+		id:         uuid.New().String(),
+		repository: repo,
+		publisher:  publisher,
+		logger:     logger,
+		interval:   interval,
+		batchSize:  batchSize,
+		lease:      lease,
+	}
+}
+
+// Run relays the outbox once every interval, until ctx is done.
+func (rl *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(rl.interval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while the batches are full
+		for {
+			n, err := rl.Relay(ctx)
+			if err != nil {
+				rl.logger.Error("failed to relay events", log.Error(err))
+				break
+			}
+			if n < rl.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes a batch of events in the order they occurred and returns the number of events published.
+func (rl *Relay) Relay(ctx context.Context) (int, error) {
+	list, err := rl.repository.ClaimEvents(ctx, rl.id, rl.lease, rl.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := make([]string, 0, len(list))
+	for _, e := range list {
+		// stop at the first failure, so later events of the batch don't overtake it
+		if err = rl.publisher.Publish(ctx, toStreamEvent(e)); err != nil {
+			break
+		}
+		published = append(published, e.ID)
+	}
+
+	if markErr := rl.repository.MarkEventsPublished(ctx, published); markErr != nil {
+		return 0, markErr
+	}
+
+	if len(published) > 0 {
+		rl.logger.Debug("events relayed", log.Int("events", len(published)))
+	}
+
+	return len(published), err
+}
+
+func toStreamEvent(e *domain.Event) *events.Event {
+	se := &events.Event{
+		ID:   e.ID,
+		Type: string(e.Type),
+		Rating: events.Rating{
+			ID:        e.Rating.ID,
+			UserID:    e.Rating.UserID,
+			MovieID:   e.Rating.MovieID,
+			Value:     e.Rating.Value,
+			CreatedAt: e.Rating.CreatedAt,
+			UpdatedAt: e.Rating.UpdatedAt,
+			Held:      e.Rating.Moderation == domain.ModerationHeld,
+		},
+		OccurredAt: e.OccurredAt,
+	}
+
+	if d := e.Rating.Dimensions; d != nil {
+		se.Rating.Dimensions = &events.Dimensions{
+			Story:         d.Story,
+			Acting:        d.Acting,
+			Visuals:       d.Visuals,
+			Entertainment: d.Entertainment,
+		}
+	}
+
+	if rv := e.Rating.Review; rv != nil {
+		se.Rating.Review = &events.Review{
+			Title:          rv.Title,
+			Body:           rv.Body,
+			Spoiler:        rv.Spoiler,
+			Language:       rv.Language,
+			HelpfulVotes:   rv.HelpfulVotes,
+			UnhelpfulVotes: rv.UnhelpfulVotes,
+			CreatedAt:      rv.CreatedAt,
+			UpdatedAt:      rv.UpdatedAt,
+		}
+	}
+
+	return se
+}
@@ -0,0 +1,85 @@
+package recommendation
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+)
+
+// Builder periodically rebuilds the item-to-item similarities from the ratings collection.
+type Builder struct {
+	repository        domain.Repository
+	logger            *log.Logger
+	interval          time.Duration
+	maxRatingsPerUser int
+	maxNeighbors      int
+	minSupport        int
+}
+
+// NewBuilder returns a new instance of Builder.
+func NewBuilder(
+	repo domain.Repository,
+	logger *log.Logger,
+	interval time.Duration,
+	maxRatingsPerUser,
+	maxNeighbors,
+	minSupport int,
+) *Builder {
+	return &Builder{
+		repository:        repo,
+		logger:            logger,
+		interval:          interval,
+		maxRatingsPerUser: maxRatingsPerUser,
+		maxNeighbors:      maxNeighbors,
+		minSupport:        minSupport,
+	}
+}
+
+// Run builds the similarities right away and then once every interval, until ctx is done.
+func (b *Builder) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Build(ctx); err != nil {
+			b.logger.Error("failed to build similarities", log.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Build computes the similarities from all the ratings and replaces the stored ones.
+func (b *Builder) Build(ctx context.Context) error {
+	start := time.Now()
+	b.logger.Debug("building similarities")
+
+	acc := domain.NewSimilarityAccumulator(b.maxRatingsPerUser, b.maxNeighbors, b.minSupport)
+
+	err := b.repository.ForEachUserRatings(ctx, func(_ string, ratings []*domain.Rating) error {
+		acc.Add(ratings)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	similarities := acc.Similarities()
+	if err := b.repository.ReplaceSimilarities(ctx, similarities); err != nil {
+		return err
+	}
+
+	b.logger.Info(
+		"similarities built",
+		log.Int("movies", len(similarities)),
+		log.String("duration", time.Since(start).String()),
+	)
+
+	return nil
+}
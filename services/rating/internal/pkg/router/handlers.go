@@ -1,16 +1,41 @@
 package router
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/victorspringer/backend-coding-challenge/lib/context"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	movieClient "github.com/victorspringer/backend-coding-challenge/services/movie/pkg/client"
 	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/importer"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/live"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
 )
 
+// maxLookupMovies is the maximum number of movies of a ratings lookup.
+const maxLookupMovies = 100
+
+// favouriteGenres is the number of genres in a user's stats.
+const favouriteGenres = 10
+
+// maxGenreLookups is the maximum number of movies of unknown genres looked up in the movie service
+// when computing a user's stats. The genres found are saved, so the next request looks up the remaining ones.
+const maxGenreLookups = 100
+
+// comparisonTop is the number of agreements and disagreements listed when comparing two users.
+const comparisonTop = 5
+
+// serviceKeyHeader is the header the other services of the system send the service key in.
+const serviceKeyHeader = "X-Service-Key"
+
 func (rt *router) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
 }
@@ -46,6 +71,62 @@ func (rt *router) findByUserHandler(w http.ResponseWriter, r *http.Request) {
 	rt.respond(w, r, u, http.StatusOK)
 }
 
+// @Summary Look up ratings by user ID and movie IDs
+// @Description Get the ratings given by a specific user to any of the given movies (up to 100), e.g. to show them on movie cards. Movies the user didn't rate are left out
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Accept json
+// @Produce json
+// @Param lookup body lookupPayload true "Movie IDs"
+// @Success 200 {object} response{response=[]domain.Rating}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/lookup [post]
+func (rt *router) lookupHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p lookupPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.String("body", string(b)), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(p.MovieIDs) == 0 || len(p.MovieIDs) > maxLookupMovies {
+		rt.respond(w, r, fmt.Sprintf("movieIds must have from 1 to %d movie IDs", maxLookupMovies), http.StatusBadRequest)
+		return
+	}
+
+	list, err := rt.repository.FindByUserAndMovieIDs(ctx, userID, p.MovieIDs)
+	if err != nil {
+		rt.logger.Error("failed to look up ratings", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, list, http.StatusOK)
+}
+
 // @Summary Find ratings by movie ID
 // @Description Get all ratings for a specific movie
 // @Tags ratings
@@ -78,7 +159,7 @@ func (rt *router) findByMovieHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Create a new (or override an old) rating
-// @Description Create a new (or override an old) rating for a movie by a user
+// @Description Create a new (or override an old) rating for a movie by a user, optionally scoring its story, acting, visuals and entertainment from 0.5 to 5. Previous dimension scores are kept if none are given
 // @Tags ratings
 // @Security ApiKeyAuth
 // @Param Authorization header string true "Insert your access token"
@@ -88,6 +169,7 @@ func (rt *router) findByMovieHandler(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} response{response=domain.Rating}
 // @Failure 400 {object} response
 // @Failure 401 {object} response
+// @Failure 422 {object} response
 // @Failure 500 {object} response
 // @Router /upsert [post]
 func (rt *router) upsertHandler(w http.ResponseWriter, r *http.Request) {
@@ -121,6 +203,7 @@ func (rt *router) upsertHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rat := domain.NewRating(p.UserID, p.MovieID, p.Value)
+	rat.Dimensions = p.Dimensions
 
 	vr, err := domain.NewValidatedRating(rat)
 	if err != nil {
@@ -129,12 +212,1424 @@ func (rt *router) upsertHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rat, err = rt.repository.Upsert(ctx, vr)
+	// the user is known to exist, as it is the one logged in
+	// the movie is checked against the movie service, accepting the rating if the service can't tell
+	movie, err := rt.mc.FindByID(ctx, p.MovieID, context.GetAccessToken(ctx))
+	if err == movieClient.ErrMovieNotFound {
+		rt.respond(w, r, fmt.Sprintf("movie %s doesn't exist", p.MovieID), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		rt.logger.Warn("failed to verify movie existence", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+	}
+
+	// bursts of ratings from new accounts are held for moderation
+	rat, err = rt.repository.Upsert(ctx, vr, rt.md.Policy(context.GetUserCreatedAt(ctx)))
 	if err != nil {
 		rt.logger.Error("failed to create / update rating", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
 		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if rat.Moderation == domain.ModerationHeld {
+		rt.logger.Warn("rating held for moderation", log.String("movieId", rat.MovieID), log.String("userId", rat.UserID), log.String("requestId", context.GetRequestID(ctx)))
+	}
+
+	// genres are kept along with the ratings to build the leaderboards
+	if movie != nil {
+		if err := rt.repository.SaveMovieGenres(ctx, movie.ID, movie.Genres); err != nil {
+			rt.logger.Warn("failed to save movie genres", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		}
+	}
+
+	rt.respond(w, r, rat, http.StatusOK)
+}
+
+// @Summary Find rating summary by movie ID
+// @Description Get the number, average and distribution of the ratings of a movie, along with the averages of the dimension scores (story, acting, visuals and entertainment)
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response{response=domain.RatingSummary}
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /movie/{id}/summary [get]
+func (rt *router) summaryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	summary, err := rt.repository.FindRatingSummary(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		rt.logger.Error("failed to summarize ratings", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, summary, http.StatusOK)
+}
+
+// @Summary Follow the ratings of a movie
+// @Description Stream the new, changed and deleted ratings of a movie ("rating" events) each followed by the updated summary ("summary" events) as server-sent events, starting with the current summary. Heartbeats are sent as comments while idle. Reconnecting with the Last-Event-ID header replays the rating events missed since then
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param Last-Event-ID header string false "ID of the last rating event received"
+// @Param id path string true "Movie ID"
+// @Produce text/event-stream
+// @Success 200 {string} string
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /movie/{id}/events [get]
+func (rt *router) movieEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	movieID := chi.URLParam(r, "id")
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID != "" {
+		if !events.IsValidID(lastEventID) {
+			rt.respond(w, r, "Last-Event-ID must be the ID of a rating event", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		rt.logger.Error("streaming unsupported", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// the client joins before replaying, so no update is lost in between
+	client := rt.hub.Join(movieID)
+	defer rt.hub.Leave(client)
+
+	var (
+		updates []*live.Update
+		err     error
+	)
+	if lastEventID != "" {
+		updates, lastEventID, err = rt.hub.Replay(ctx, movieID, lastEventID)
+	} else {
+		var summary *live.Update
+		summary, err = rt.hub.Summary(ctx, movieID)
+		updates = []*live.Update{summary}
+	}
+	if err != nil {
+		rt.logger.Error("failed to start rating events", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(u *live.Update) error {
+		if u.ID != "" {
+			if lastEventID != "" {
+				// updates received while replaying may have been replayed already
+				if c, err := events.CompareIDs(u.ID, lastEventID); err == nil && c <= 0 {
+					return nil
+				}
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\n", u.ID); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(u.Data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", u.Type, b); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for _, u := range updates {
+		if err := send(u); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(rt.hub.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case u, ok := <-client.Updates():
+			if !ok {
+				return
+			}
+			if err := send(u); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// @Summary Find reviews by movie ID
+// @Description Get a page of written reviews for a specific movie
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "Movie ID"
+// @Param sort query string false "Sort order" Enums(newest, helpful) default(newest)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size" default(20)
+// @Produce json
+// @Success 200 {object} response{response=[]domain.Rating}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /movie/{id}/reviews [get]
+func (rt *router) findReviewsByMovieHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	movieID := chi.URLParam(r, "id")
+
+	sort := domain.ReviewSortNewest
+	if s := r.URL.Query().Get("sort"); s != "" {
+		sort = domain.ReviewSort(s)
+	}
+	if !sort.IsValid() {
+		rt.respond(w, r, "sort must be either newest or helpful", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := rt.repository.FindReviewsByMovieID(ctx, movieID, sort, page, limit)
+	if err != nil {
+		rt.logger.Error("reviews not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, list, http.StatusOK)
+}
+
+// @Summary Create or edit a review
+// @Description Create or edit the written review attached to a user's rating for a movie
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Accept json
+// @Produce json
+// @Param review body reviewPayload true "Review"
+// @Success 200 {object} response{response=domain.Rating}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/review/{movieId} [put]
+func (rt *router) updateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p reviewPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.String("body", string(b)), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rat, err := rt.repository.FindByUserAndMovieID(ctx, userID, movieID)
+	if err != nil {
+		rt.logger.Error("rating not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	review := domain.NewReview(p.Title, p.Body, p.Spoiler, p.Language)
+	if rat.Review != nil {
+		review.CreatedAt = rat.Review.CreatedAt
+		review.HelpfulVotes = rat.Review.HelpfulVotes
+		review.UnhelpfulVotes = rat.Review.UnhelpfulVotes
+	}
+	rat.Review = review
+	rat.UpdatedAt = review.UpdatedAt
+
+	vr, err := domain.NewValidatedRating(rat)
+	if err != nil {
+		rt.logger.Error("invalid review data", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rat, err = rt.repository.UpdateReview(ctx, vr)
+	if err != nil {
+		rt.logger.Error("failed to update review", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	rt.respond(w, r, rat, http.StatusOK)
 }
+
+// @Summary Import ratings
+// @Description Upload a Letterboxd ratings.csv or an IMDb ratings export to import its ratings in the background. Movies the user already rated are skipped
+// @Tags imports
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "Ratings export (csv)"
+// @Success 202 {object} response{response=domain.Import}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 413 {object} response
+// @Failure 500 {object} response
+// @Failure 503 {object} response
+// @Router /user/{id}/import [post]
+func (rt *router) importHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, rt.im.MaxFileSize())
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			rt.respond(w, r, fmt.Sprintf("file must be up to %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		rt.logger.Error("failed to read uploaded file", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// imported ratings take part in the burst detection, as new accounts could import forged exports
+	imp, err := rt.im.Start(ctx, userID, file, rt.md.Policy(context.GetUserCreatedAt(ctx)))
+	if err != nil {
+		rt.logger.Error("failed to start import", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		switch {
+		case errors.Is(err, importer.ErrInvalidFile):
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, importer.ErrQueueFull):
+			rt.respond(w, r, err.Error(), http.StatusServiceUnavailable)
+		default:
+			rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	rt.respond(w, r, imp, http.StatusAccepted)
+}
+
+// @Summary Find import
+// @Description Poll the progress of a ratings import, including the rows that could not be matched to a movie
+// @Tags imports
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param importId path string true "Import ID"
+// @Produce json
+// @Success 200 {object} response{response=domain.Import}
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/import/{importId} [get]
+func (rt *router) findImportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	imp, err := rt.repository.FindImport(ctx, chi.URLParam(r, "importId"))
+	if err != nil || imp.UserID != userID {
+		rt.respond(w, r, "import not found", http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, imp, http.StatusOK)
+}
+
+// @Summary Remove a rating
+// @Description Remove a user's rating for a movie, along with its review and the review's votes
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/rating/{movieId} [delete]
+func (rt *router) deleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if err := rt.repository.Delete(ctx, userID, movieID); err != nil {
+		rt.logger.Error("failed to delete rating", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary Remove all the data of a user
+// @Description Remove the ratings, reviews, votes, watchlist, diary and imports of a user, e.g. when their account is deleted. The movies' rating summaries and leaderboards catch up with the removed ratings. It can be called again to resume after a failure. Allowed to the user themselves and admins, or to the other services with the service key
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string false "Insert your access token, unless the service key is given"
+// @Param X-Service-Key header string false "Service key of the other services of the system"
+// @Param id path string true "User ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id} [delete]
+func (rt *router) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := chi.URLParam(r, "id")
+
+	if serviceKey := r.Header.Get(serviceKeyHeader); serviceKey != "" {
+		// nobody is allowed with an empty service key
+		if rt.serviceKey == "" || subtle.ConstantTimeCompare([]byte(serviceKey), []byte(rt.serviceKey)) != 1 {
+			rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	} else {
+		level := context.GetUserLevel(ctx)
+		if level == "anonymous" {
+			rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		if username := context.GetUserUsername(ctx); level != "admin" && (username == "" || username != userID) {
+			rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	deleted, err := rt.repository.DeleteUser(ctx, userID)
+	if err != nil {
+		rt.logger.Error(
+			"failed to delete user data",
+			log.String("userId", userID),
+			log.Int("deletedRatings", deleted),
+			log.Error(err),
+			log.String("requestId", context.GetRequestID(ctx)),
+		)
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.logger.Info("user data deleted", log.String("userId", userID), log.Int("deletedRatings", deleted))
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary Remove a review
+// @Description Remove the written review attached to a user's rating for a movie, keeping the rating itself
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/review/{movieId} [delete]
+func (rt *router) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if err := rt.repository.DeleteReview(ctx, userID, movieID); err != nil {
+		rt.logger.Error("failed to delete review", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary Vote on a review
+// @Description Mark another user's review as helpful or unhelpful, replacing any previous vote of the logged-in user
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID of the review author"
+// @Param movieId path string true "Movie ID"
+// @Accept json
+// @Produce json
+// @Param vote body votePayload true "Vote"
+// @Success 200 {object} response{response=domain.Vote}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/review/{movieId}/vote [put]
+func (rt *router) voteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	username := context.GetUserUsername(ctx)
+	if username == "" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p votePayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.String("body", string(b)), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vv, err := domain.NewValidatedVote(domain.NewVote(userID, movieID, username, p.Helpful))
+	if err != nil {
+		rt.logger.Error("invalid vote data", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rat, err := rt.repository.FindByUserAndMovieID(ctx, userID, movieID)
+	if err != nil || rat.Review == nil {
+		rt.logger.Error("review not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, "review not found", http.StatusNotFound)
+		return
+	}
+
+	// the review may have been removed meanwhile, in which case the vote is not kept
+	v, err := rt.repository.Vote(ctx, vv)
+	if err == domain.ErrReviewNotFound {
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		rt.logger.Error("failed to vote on review", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, v, http.StatusOK)
+}
+
+// @Summary Remove a vote on a review
+// @Description Remove the logged-in user's helpful or unhelpful vote on another user's review
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID of the review author"
+// @Param movieId path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/review/{movieId}/vote [delete]
+func (rt *router) deleteVoteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	username := context.GetUserUsername(ctx)
+	if username == "" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	if err := rt.repository.DeleteVote(ctx, userID, movieID, username); err != nil {
+		rt.logger.Error("failed to delete vote", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary Recommend movies to a user
+// @Description Get the top movies a user has not rated yet, scored by item-based collaborative filtering
+// @Tags recommendations
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param userId path string true "User ID"
+// @Param limit query int false "Number of recommendations" default(20)
+// @Produce json
+// @Success 200 {object} response{response=[]domain.Recommendation}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /recommendations/{userId} [get]
+func (rt *router) recommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "userId")
+
+	_, limit, err := parsePagination(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ratings, err := rt.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		rt.logger.Error("rating not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	movieIDs := make([]string, 0, len(ratings))
+	for _, rat := range ratings {
+		movieIDs = append(movieIDs, rat.MovieID)
+	}
+
+	similarities, err := rt.repository.FindSimilarities(ctx, movieIDs)
+	if err != nil {
+		rt.logger.Error("failed to find similarities", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, domain.Recommend(ratings, similarities, limit), http.StatusOK)
+}
+
+// @Summary Find rating stats by user ID
+// @Description Get the number, mean and distribution of the ratings of a specific user, along with the number of ratings per month and their favourite genres by number and mean of ratings
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Produce json
+// @Success 200 {object} response{response=domain.UserStats}
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/stats [get]
+func (rt *router) statsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	// the version tells if the ratings changed while the stats were computed, which are then not cached
+	stats, version, err := rt.repository.FindUserStats(ctx, userID)
+	if err == nil {
+		rt.respond(w, r, stats, http.StatusOK)
+		return
+	}
+	if err != domain.ErrUserStatsNotCached {
+		rt.logger.Warn("failed to find cached user stats", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+	}
+
+	ratings, err := rt.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		rt.logger.Error("rating not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	movieIDs := make([]string, 0, len(ratings))
+	for _, rat := range ratings {
+		movieIDs = append(movieIDs, rat.MovieID)
+	}
+
+	genres, complete, err := rt.movieGenres(r, movieIDs)
+	if err != nil {
+		rt.logger.Error("failed to find movie genres", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stats = domain.NewUserStats(userID, ratings, genres, favouriteGenres)
+
+	// stats missing some genres are computed again on the next request
+	if complete {
+		if err := rt.repository.SaveUserStats(ctx, stats, version); err != nil {
+			rt.logger.Warn("failed to cache user stats", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		}
+	}
+
+	rt.respond(w, r, stats, http.StatusOK)
+}
+
+// movieGenres returns the genres of the given movies, by movie ID, looking the unknown ones up in the movie service.
+// It returns false if the genres of some movies could not be found.
+func (rt *router) movieGenres(r *http.Request, movieIDs []string) (map[string][]string, bool, error) {
+	ctx := r.Context()
+
+	genres, err := rt.repository.FindMovieGenres(ctx, movieIDs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	complete := true
+	lookups := 0
+	for _, id := range movieIDs {
+		if _, ok := genres[id]; ok {
+			continue
+		}
+		if lookups == maxGenreLookups {
+			complete = false
+			break
+		}
+		lookups++
+
+		movie, err := rt.mc.FindByID(ctx, id, context.GetAccessToken(ctx))
+		if err == movieClient.ErrMovieNotFound {
+			// the movie was removed, it has no genres to count
+			continue
+		}
+		if err != nil {
+			rt.logger.Warn("failed to find movie genres", log.String("movieId", id), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			complete = false
+			continue
+		}
+
+		genres[id] = movie.Genres
+		if err := rt.repository.SaveMovieGenres(ctx, movie.ID, movie.Genres); err != nil {
+			rt.logger.Warn("failed to save movie genres", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		}
+	}
+
+	return genres, complete, nil
+}
+
+// @Summary Compare the taste of two users
+// @Description Get the similarity, agreement percentage and the biggest agreements and disagreements between two users, based on the movies both of them rated
+// @Tags ratings
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param userA path string true "User ID"
+// @Param userB path string true "User ID to compare with"
+// @Produce json
+// @Success 200 {object} response{response=domain.Comparison}
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /compare/{userA}/{userB} [get]
+func (rt *router) compareHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userA := chi.URLParam(r, "userA")
+	userB := chi.URLParam(r, "userB")
+
+	ratingsA, err := rt.repository.FindByUserID(ctx, userA)
+	if err != nil {
+		rt.logger.Error("rating not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	ratingsB, err := rt.repository.FindByUserID(ctx, userB)
+	if err != nil {
+		rt.logger.Error("rating not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, domain.Compare(userA, userB, ratingsA, ratingsB, comparisonTop), http.StatusOK)
+}
+
+// @Summary Find held ratings
+// @Description Get a page of the ratings held for moderation, the longest held first. Held ratings are part of suspicious bursts of ratings from new accounts, and are left out of the rating summaries, leaderboards and recommendations until an admin reviews them. Admins only
+// @Tags moderation
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size, up to 100 (default 20)"
+// @Produce json
+// @Success 200 {object} response{response=[]domain.Rating}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /moderation/ratings [get]
+func (rt *router) findHeldRatingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level != "admin" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := rt.repository.FindHeldRatings(ctx, page, limit)
+	if err != nil {
+		rt.logger.Error("failed to find held ratings", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, list, http.StatusOK)
+}
+
+// @Summary Approve a held rating
+// @Description Release a rating held for moderation, so it counts towards the aggregates again. Approved ratings are not held again. Admins only
+// @Tags moderation
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param userId path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response{response=domain.Rating}
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /moderation/ratings/{userId}/{movieId}/approve [post]
+func (rt *router) approveRatingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level != "admin" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	rat, err := rt.repository.ApproveRating(ctx, chi.URLParam(r, "userId"), chi.URLParam(r, "movieId"))
+	if err != nil {
+		rt.logger.Error("failed to approve rating", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, rat, http.StatusOK)
+}
+
+// @Summary Reject a held rating
+// @Description Remove a rating held for moderation. Admins only
+// @Tags moderation
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param userId path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /moderation/ratings/{userId}/{movieId}/reject [post]
+func (rt *router) rejectRatingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level != "admin" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if err := rt.repository.RejectRating(ctx, chi.URLParam(r, "userId"), chi.URLParam(r, "movieId")); err != nil {
+		rt.logger.Error("failed to reject rating", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary Top rated movies
+// @Description Get the movies with the highest Bayesian average rating, so a few high ratings don't top the chart
+// @Tags leaderboards
+// @Produce json
+// @Success 200 {object} response{response=domain.Leaderboard}
+// @Failure 404 {object} response
+// @Router /leaderboard/top-rated [get]
+func (rt *router) topRatedHandler(w http.ResponseWriter, r *http.Request) {
+	rt.leaderboard(w, r, domain.TopRatedLeaderboard)
+}
+
+// @Summary Trending movies
+// @Description Get the movies with the most rating activity in the last 7 days, recent ratings weighing more
+// @Tags leaderboards
+// @Produce json
+// @Success 200 {object} response{response=domain.Leaderboard}
+// @Failure 404 {object} response
+// @Router /leaderboard/trending [get]
+func (rt *router) trendingHandler(w http.ResponseWriter, r *http.Request) {
+	rt.leaderboard(w, r, domain.TrendingLeaderboard)
+}
+
+// @Summary Most rated movies of a genre
+// @Description Get the movies of a genre with the highest number of ratings
+// @Tags leaderboards
+// @Param genre path string true "Genre"
+// @Produce json
+// @Success 200 {object} response{response=domain.Leaderboard}
+// @Failure 404 {object} response
+// @Router /leaderboard/most-rated/{genre} [get]
+func (rt *router) mostRatedHandler(w http.ResponseWriter, r *http.Request) {
+	rt.leaderboard(w, r, domain.MostRatedLeaderboard(chi.URLParam(r, "genre")))
+}
+
+func (rt *router) leaderboard(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+
+	l, err := rt.repository.FindLeaderboard(ctx, name)
+	if err != nil {
+		rt.logger.Error("leaderboard not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, l, http.StatusOK)
+}
+
+// @Summary Find watchlist
+// @Description Get a page of a user's watchlist in order, optionally filtered by genre
+// @Tags watchlist
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param genre query string false "Genre (case insensitive)"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size, up to 100 (default 20)"
+// @Produce json
+// @Success 200 {object} response{response=[]domain.WatchlistEntry}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/watchlist [get]
+func (rt *router) findWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := rt.repository.FindWatchlist(ctx, userID, r.URL.Query().Get("genre"), page, limit)
+	if err != nil {
+		rt.logger.Error("failed to find watchlist", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, list, http.StatusOK)
+}
+
+// @Summary Add to watchlist
+// @Description Add a movie to the end of a user's watchlist. It is removed automatically once the user rates it
+// @Tags watchlist
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Accept json
+// @Produce json
+// @Param entry body watchlistPayload true "Watchlist entry"
+// @Success 201 {object} response{response=domain.WatchlistEntry}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 409 {object} response
+// @Failure 422 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/watchlist [post]
+func (rt *router) addToWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p watchlistPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.String("body", string(b)), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the movie is checked against the movie service, accepting it without genres if the service can't tell
+	var genres []string
+	movie, err := rt.mc.FindByID(ctx, p.MovieID, context.GetAccessToken(ctx))
+	switch {
+	case err == movieClient.ErrMovieNotFound:
+		rt.respond(w, r, fmt.Sprintf("movie %s doesn't exist", p.MovieID), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		rt.logger.Warn("failed to verify movie existence", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+	default:
+		genres = movie.Genres
+	}
+
+	vwe, err := domain.NewValidatedWatchlistEntry(domain.NewWatchlistEntry(userID, p.MovieID, p.Note, genres))
+	if err != nil {
+		rt.logger.Error("invalid watchlist entry data", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := rt.repository.AddToWatchlist(ctx, vwe)
+	if err == domain.ErrWatchlistEntryExists {
+		rt.respond(w, r, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		rt.logger.Error("failed to add to watchlist", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, entry, http.StatusCreated)
+}
+
+// @Summary Edit a watchlist entry
+// @Description Change the note of a movie in a user's watchlist and/or move it to another position, starting at 1
+// @Tags watchlist
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Accept json
+// @Produce json
+// @Param entry body watchlistUpdatePayload true "Fields to change"
+// @Success 200 {object} response{response=domain.WatchlistEntry}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/watchlist/{movieId} [patch]
+func (rt *router) updateWatchlistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p watchlistUpdatePayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.String("body", string(b)), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if p.Note == nil && p.Position == nil {
+		rt.respond(w, r, "either note or position is required", http.StatusBadRequest)
+		return
+	}
+
+	if p.Note != nil {
+		if err := domain.ValidateWatchlistNote(*p.Note); err != nil {
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var entry *domain.WatchlistEntry
+
+	if p.Note != nil {
+		entry, err = rt.repository.UpdateWatchlistNote(ctx, userID, movieID, *p.Note)
+		if err != nil {
+			rt.logger.Error("failed to update watchlist note", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	if p.Position != nil {
+		entry, err = rt.repository.MoveWatchlistEntry(ctx, userID, movieID, *p.Position)
+		if err != nil {
+			rt.logger.Error("failed to move watchlist entry", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	rt.respond(w, r, entry, http.StatusOK)
+}
+
+// @Summary Remove from watchlist
+// @Description Remove a movie from a user's watchlist
+// @Tags watchlist
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param movieId path string true "Movie ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/watchlist/{movieId} [delete]
+func (rt *router) removeFromWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	movieID := chi.URLParam(r, "movieId")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if err := rt.repository.RemoveFromWatchlist(ctx, userID, movieID); err != nil {
+		rt.logger.Error("failed to remove from watchlist", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary Find diary
+// @Description Get a page of a user's watch diary, most recently watched first, optionally of a single year (e.g. for year in review pages)
+// @Tags diary
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param year query int false "Year the movies were watched"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size, up to 100 (default 20)"
+// @Produce json
+// @Success 200 {object} response{response=[]domain.DiaryEntry}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/diary [get]
+func (rt *router) findDiaryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	year := 0
+	if y := r.URL.Query().Get("year"); y != "" {
+		var err error
+		if year, err = strconv.Atoi(y); err != nil || year < 1 {
+			rt.respond(w, r, "year must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := rt.repository.FindDiary(ctx, userID, year, page, limit)
+	if err != nil {
+		rt.logger.Error("failed to find diary", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, list, http.StatusOK)
+}
+
+// @Summary Log a watched movie
+// @Description Add an entry to a user's watch diary. If it has a rating value and is the latest rated entry of the movie, it becomes the user's rating of the movie
+// @Tags diary
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Accept json
+// @Produce json
+// @Param entry body diaryPayload true "Diary entry"
+// @Success 201 {object} response{response=domain.DiaryEntry}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 422 {object} response
+// @Failure 500 {object} response
+// @Router /user/{id}/diary [post]
+func (rt *router) logDiaryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p diaryPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.String("body", string(b)), log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	watchedOn := time.Now()
+	if p.WatchedOn != "" {
+		if watchedOn, err = time.Parse(time.DateOnly, p.WatchedOn); err != nil {
+			rt.respond(w, r, "watchedOn must be a date formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	vde, err := domain.NewValidatedDiaryEntry(domain.NewDiaryEntry(userID, p.MovieID, watchedOn, p.Value, p.Rewatch, p.Notes))
+	if err != nil {
+		rt.logger.Error("invalid diary entry data", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the movie is checked against the movie service, accepting the entry if the service can't tell
+	movie, err := rt.mc.FindByID(ctx, p.MovieID, context.GetAccessToken(ctx))
+	if err == movieClient.ErrMovieNotFound {
+		rt.respond(w, r, fmt.Sprintf("movie %s doesn't exist", p.MovieID), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		rt.logger.Warn("failed to verify movie existence", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+	}
+
+	// bursts of ratings from new accounts are held for moderation
+	entry, err := rt.repository.LogDiaryEntry(ctx, vde, rt.md.Policy(context.GetUserCreatedAt(ctx)))
+	if err != nil {
+		rt.logger.Error("failed to log diary entry", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// genres are kept along with the ratings to build the leaderboards
+	if movie != nil && entry.Value != nil {
+		if err := rt.repository.SaveMovieGenres(ctx, movie.ID, movie.Genres); err != nil {
+			rt.logger.Warn("failed to save movie genres", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		}
+	}
+
+	rt.respond(w, r, entry, http.StatusCreated)
+}
+
+// @Summary Remove a diary entry
+// @Description Remove an entry from a user's watch diary. If it was the latest rated entry of the movie, the user's rating falls back to the previous rated entry
+// @Tags diary
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Param id path string true "User ID"
+// @Param entryId path string true "Diary entry ID"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Router /user/{id}/diary/{entryId} [delete]
+func (rt *router) deleteDiaryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	if username := context.GetUserUsername(ctx); username == "" || username != userID {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	if err := rt.repository.DeleteDiaryEntry(ctx, userID, chi.URLParam(r, "entryId")); err != nil {
+		rt.logger.Error("failed to delete diary entry", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the "page" and "limit" query parameters of a request.
+func parsePagination(r *http.Request) (page, limit int, err error) {
+	page, limit = 1, defaultPageLimit
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		page, err = strconv.Atoi(p)
+		if err != nil || page < 1 {
+			return 0, 0, errors.New("page must be a positive integer")
+		}
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, errors.New("limit must be an integer from 1 to 100")
+		}
+	}
+
+	return page, limit, nil
+}
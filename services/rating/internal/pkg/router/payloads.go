@@ -1,7 +1,43 @@
 package router
 
+import "github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+
 type upsertPayload struct {
-	UserID  string  `json:"userId"`
-	MovieID string  `json:"movieId"`
-	Value   float32 `json:"value"`
+	UserID     string                  `json:"userId"`
+	MovieID    string                  `json:"movieId"`
+	Value      float32                 `json:"value"`
+	Dimensions *domain.DimensionScores `json:"dimensions"`
+}
+
+type lookupPayload struct {
+	MovieIDs []string `json:"movieIds"`
+}
+
+type reviewPayload struct {
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Spoiler  bool   `json:"spoiler"`
+	Language string `json:"language"`
+}
+
+type votePayload struct {
+	Helpful bool `json:"helpful"`
+}
+
+type watchlistPayload struct {
+	MovieID string `json:"movieId"`
+	Note    string `json:"note"`
+}
+
+type watchlistUpdatePayload struct {
+	Note     *string `json:"note"`
+	Position *int    `json:"position"`
+}
+
+type diaryPayload struct {
+	MovieID   string   `json:"movieId"`
+	WatchedOn string   `json:"watchedOn"`
+	Value     *float32 `json:"value"`
+	Rewatch   bool     `json:"rewatch"`
+	Notes     string   `json:"notes"`
 }
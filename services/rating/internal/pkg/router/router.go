@@ -2,6 +2,7 @@ package router
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -9,8 +10,14 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
 	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
+	movieClient "github.com/victorspringer/backend-coding-challenge/services/movie/pkg/client"
 	_ "github.com/victorspringer/backend-coding-challenge/services/rating/docs"
 	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/importer"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/live"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/internal/pkg/moderation"
+	cache "github.com/victorspringer/http-cache"
+	"github.com/victorspringer/http-cache/adapter/memory"
 )
 
 // @title Rating Service
@@ -27,14 +34,45 @@ type Router interface {
 }
 
 type router struct {
-	repository domain.Repository
-	logger     *log.Logger
-	ac         *authClient.Client
+	repository      domain.Repository
+	logger          *log.Logger
+	ac              *authClient.Client
+	mc              *movieClient.Client
+	im              *importer.Importer
+	md              *moderation.Detector
+	hub             *live.Hub
+	serviceKey      string // lets the other services of the system remove the data of users
+	cacheMiddleware func(next http.Handler) http.Handler
 }
 
 // New returns a new instance of Router.
-func New(repo domain.Repository, logger *log.Logger, ac *authClient.Client) Router {
-	return &router{repo, logger, ac}
+func New(
+	repo domain.Repository,
+	logger *log.Logger,
+	ac *authClient.Client,
+	mc *movieClient.Client,
+	im *importer.Importer,
+	md *moderation.Detector,
+	hub *live.Hub,
+	serviceKey string,
+) Router {
+	memcached, err := memory.NewAdapter(
+		memory.AdapterWithAlgorithm(memory.LRU),
+		memory.AdapterWithCapacity(10000),
+	)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	cacheClient, err := cache.NewClient(
+		cache.ClientWithAdapter(memcached),
+		cache.ClientWithTTL(5*time.Minute),
+		cache.ClientWithRefreshKey("opn"),
+	)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	return &router{repo, logger, ac, mc, im, md, hub, serviceKey, cacheClient.Middleware}
 }
 
 // GetHandler returns the router's http handler.
@@ -43,8 +81,8 @@ func (rt *router) GetHandler() http.Handler {
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "X-Forwarded-Proto"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "X-Service-Key", "X-Forwarded-Proto", "Last-Event-ID"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -63,8 +101,42 @@ func (rt *router) GetHandler() http.Handler {
 
 	// endpoints
 	r.Get("/user/{id}", rt.findByUserHandler)
+	r.Delete("/user/{id}", rt.deleteUserHandler)
+	r.Post("/user/{id}/lookup", rt.lookupHandler)
+	r.Get("/user/{id}/stats", rt.statsHandler)
 	r.Get("/movie/{id}", rt.findByMovieHandler)
+	r.Get("/movie/{id}/summary", rt.summaryHandler)
+	r.Get("/movie/{id}/events", rt.movieEventsHandler)
+	r.Get("/movie/{id}/reviews", rt.findReviewsByMovieHandler)
 	r.Post("/upsert", rt.upsertHandler)
+	r.Delete("/user/{id}/rating/{movieId}", rt.deleteHandler)
+	r.Put("/user/{id}/review/{movieId}", rt.updateReviewHandler)
+	r.Delete("/user/{id}/review/{movieId}", rt.deleteReviewHandler)
+	r.Put("/user/{id}/review/{movieId}/vote", rt.voteHandler)
+	r.Delete("/user/{id}/review/{movieId}/vote", rt.deleteVoteHandler)
+	r.Post("/user/{id}/import", rt.importHandler)
+	r.Get("/user/{id}/import/{importId}", rt.findImportHandler)
+	r.Get("/user/{id}/watchlist", rt.findWatchlistHandler)
+	r.Post("/user/{id}/watchlist", rt.addToWatchlistHandler)
+	r.Patch("/user/{id}/watchlist/{movieId}", rt.updateWatchlistEntryHandler)
+	r.Delete("/user/{id}/watchlist/{movieId}", rt.removeFromWatchlistHandler)
+	r.Get("/user/{id}/diary", rt.findDiaryHandler)
+	r.Post("/user/{id}/diary", rt.logDiaryEntryHandler)
+	r.Delete("/user/{id}/diary/{entryId}", rt.deleteDiaryEntryHandler)
+	r.Get("/recommendations/{userId}", rt.recommendationsHandler)
+	r.Get("/compare/{userA}/{userB}", rt.compareHandler)
+	r.Get("/moderation/ratings", rt.findHeldRatingsHandler)
+	r.Post("/moderation/ratings/{userId}/{movieId}/approve", rt.approveRatingHandler)
+	r.Post("/moderation/ratings/{userId}/{movieId}/reject", rt.rejectRatingHandler)
+
+	// cacheable endpoints
+	r.Route("/leaderboard", func(r chi.Router) {
+		r.Use(rt.cacheMiddleware)
+
+		r.Get("/top-rated", rt.topRatedHandler)
+		r.Get("/trending", rt.trendingHandler)
+		r.Get("/most-rated/{genre}", rt.mostRatedHandler)
+	})
 
 	return r
 }
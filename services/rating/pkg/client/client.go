@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+)
+
+// Common errors.
+var (
+	ErrUnauthorized = errors.New("not allowed by the rating service")
+	ErrUnavailable  = errors.New("rating service unavailable")
+)
+
+// Client is a struct representing the rating service client.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	logger     *log.Logger
+}
+
+// NewClient creates a new instance of the rating service client.
+func NewClient(baseURL string, timeout time.Duration, logger *log.Logger) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		logger: logger,
+	}
+}
+
+// DeleteUser removes all the data of a user, on behalf of the user themselves or an admin the access token belongs to.
+// It can be called again after a failure. It returns ErrUnauthorized if the access token is not allowed to
+// and ErrUnavailable if the rating service failed.
+func (c *Client) DeleteUser(ctx context.Context, userID, accessToken string) error {
+	return c.deleteUser(ctx, userID, "Authorization", "Bearer "+accessToken)
+}
+
+// DeleteUserAsService removes all the data of a user on behalf of another service of the system holding the
+// service key, e.g. when deleting an account in the background, after the requester's access token expired.
+// It can be called again after a failure. It returns ErrUnauthorized if the service key is wrong
+// and ErrUnavailable if the rating service failed.
+func (c *Client) DeleteUserAsService(ctx context.Context, userID, serviceKey string) error {
+	return c.deleteUser(ctx, userID, "X-Service-Key", serviceKey)
+}
+
+// deleteUser removes all the data of a user, authorized by the given header.
+func (c *Client) deleteUser(ctx context.Context, userID, header, value string) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/user/%s", c.baseURL, url.PathEscape(userID)), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", log.Error(err))
+		return err
+	}
+	r.Header.Set(header, value)
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		c.logger.Error("error from rating service", log.Error(err))
+		return ErrUnavailable
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	default:
+		c.logger.Error("unexpected status code from rating service", log.Int("statusCode", resp.StatusCode))
+		return ErrUnavailable
+	}
+}
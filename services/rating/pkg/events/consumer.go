@@ -0,0 +1,261 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+)
+
+const (
+	// batchSize is the maximum number of entries read at once.
+	batchSize = 100
+	// blockTimeout is how long a read waits for new entries.
+	blockTimeout = 5 * time.Second
+	// claimMinIdle is how long an entry must be pending before another consumer takes it over.
+	claimMinIdle = time.Minute
+)
+
+// Message is an event delivered to a consumer, along with its stream entry ID.
+type Message struct {
+	StreamID string
+	Event    Event
+}
+
+// Handler processes a message. Returning an error leaves the message pending, so it is delivered again,
+// until it was delivered too many times, see NewConsumer.
+type Handler func(ctx context.Context, m *Message) error
+
+// Consumer reads the stream as a member of a consumer group.
+// Each event is delivered to a single consumer of the group at least once,
+// so handlers must be idempotent (e.g. keyed by Event.ID).
+type Consumer struct {
+	client        *redis.Client
+	stream        string
+	group         string
+	name          string
+	logger        *log.Logger
+	maxDeliveries int64
+}
+
+// NewConsumer returns a new instance of Consumer.
+// name must be unique within the group, e.g. the replica's hostname. Entries delivered maxDeliveries times without
+// being handled, as well as malformed ones, are moved to the group's dead-letter stream, see DeadLetterStream.
+func NewConsumer(client *redis.Client, stream, group, name string, logger *log.Logger, maxDeliveries int) *Consumer {
+	return &Consumer{
+		client:        client,
+		stream:        stream,
+		group:         group,
+		name:          name,
+		logger:        logger,
+		maxDeliveries: int64(maxDeliveries),
+	}
+}
+
+// DeadLetterStream returns the name of the stream the entries a consumer group couldn't handle are moved to.
+// They keep their fields, along with the ID of the original entry ("streamId") and its number of deliveries
+// ("deliveries"). Once the cause is fixed, they can be delivered again by moving the group before them, see SetOffset.
+func DeadLetterStream(stream, group string) string {
+	return stream + ":" + group + ":dead-letter"
+}
+
+// CreateGroup creates the consumer group starting at offset, along with the stream if needed.
+// It does nothing if the group already exists.
+func (c *Consumer) CreateGroup(ctx context.Context, offset string) error {
+	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.group, offset).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// SetOffset moves the consumer group to offset, so the events after it are delivered again.
+func (c *Consumer) SetOffset(ctx context.Context, offset string) error {
+	return c.client.XGroupSetID(ctx, c.stream, c.group, offset).Err()
+}
+
+// Consume delivers the group's events to handler until ctx is done.
+// Entries left pending by consumers that failed or died are taken over once they are idle long enough.
+func (c *Consumer) Consume(ctx context.Context, handler Handler) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil
+		}
+
+		if err := c.claimPending(ctx, handler); err != nil {
+			return err
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.name,
+			Streams:  []string{c.stream, ">"},
+			Count:    batchSize,
+			Block:    blockTimeout,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, s := range streams {
+			c.handle(ctx, handler, s.Messages)
+		}
+	}
+}
+
+func (c *Consumer) claimPending(ctx context.Context, handler Handler) error {
+	start := "0-0"
+	for {
+		messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			Consumer: c.name,
+			MinIdle:  claimMinIdle,
+			Start:    start,
+			Count:    batchSize,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		// the entries are taken over again and again while their handling fails, until they are given up on
+		retried, err := c.dropExhausted(ctx, messages)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		c.handle(ctx, handler, retried)
+
+		if next == "0-0" || len(messages) == 0 {
+			return nil
+		}
+		start = next
+	}
+}
+
+// dropExhausted moves the claimed entries delivered more than maxDeliveries times to the dead-letter stream,
+// returning the other ones.
+func (c *Consumer) dropExhausted(ctx context.Context, messages []redis.XMessage) ([]redis.XMessage, error) {
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	cmds := make([]*redis.XPendingExtCmd, len(messages))
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, xm := range messages {
+			cmds[i] = p.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: c.stream,
+				Group:  c.group,
+				Start:  xm.ID,
+				End:    xm.ID,
+				Count:  1,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	retried := make([]redis.XMessage, 0, len(messages))
+	for i, xm := range messages {
+		pending := cmds[i].Val()
+		if len(pending) == 0 || pending[0].RetryCount <= c.maxDeliveries {
+			retried = append(retried, xm)
+			continue
+		}
+
+		c.logger.Error(
+			"giving up on event",
+			log.String("streamId", xm.ID),
+			log.Int("deliveries", int(pending[0].RetryCount)),
+		)
+		if err := c.deadLetter(ctx, xm, pending[0].RetryCount); err != nil {
+			return nil, err
+		}
+	}
+
+	return retried, nil
+}
+
+// deadLetter moves an entry to the dead-letter stream, acknowledging it so it is not delivered anymore.
+func (c *Consumer) deadLetter(ctx context.Context, xm redis.XMessage, deliveries int64) error {
+	values := make(map[string]interface{}, len(xm.Values)+2)
+	for k, v := range xm.Values {
+		values[k] = v
+	}
+	values["streamId"] = xm.ID
+	values["deliveries"] = deliveries
+
+	_, err := c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.XAdd(ctx, &redis.XAddArgs{
+			Stream: DeadLetterStream(c.stream, c.group),
+			Values: values,
+		})
+		p.XAck(ctx, c.stream, c.group, xm.ID)
+		return nil
+	})
+	return err
+}
+
+func (c *Consumer) handle(ctx context.Context, handler Handler, messages []redis.XMessage) {
+	for _, xm := range messages {
+		m, err := decode(xm)
+		if err != nil {
+			// a malformed entry would never be processed, so it is set aside to stop redelivering it
+			c.logger.Error("failed to decode event", log.String("streamId", xm.ID), log.Error(err))
+			if err := c.deadLetter(ctx, xm, 1); err != nil {
+				c.logger.Error("failed to move event to the dead-letter stream", log.String("streamId", xm.ID), log.Error(err))
+			}
+			continue
+		}
+
+		if err := handler(ctx, m); err != nil {
+			c.logger.Error(
+				"failed to handle event",
+				log.String("streamId", xm.ID),
+				log.String("eventId", m.Event.ID),
+				log.Error(err),
+			)
+			continue
+		}
+
+		c.ack(ctx, xm.ID)
+	}
+}
+
+func (c *Consumer) ack(ctx context.Context, id string) {
+	if err := c.client.XAck(ctx, c.stream, c.group, id).Err(); err != nil {
+		c.logger.Error("failed to acknowledge event", log.String("streamId", id), log.Error(err))
+	}
+}
+
+func decode(xm redis.XMessage) (*Message, error) {
+	raw, ok := xm.Values["event"].(string)
+	if !ok {
+		return nil, errors.New("missing event field")
+	}
+
+	m := &Message{StreamID: xm.ID}
+	if err := json.Unmarshal([]byte(raw), &m.Event); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,102 @@
+// Package events publishes and consumes the rating event stream.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event types.
+const (
+	RatingCreated = "RatingCreated"
+	RatingUpdated = "RatingUpdated"
+	RatingDeleted = "RatingDeleted"
+)
+
+// Stream offsets a consumer group can start from, besides any stream entry ID.
+const (
+	// OffsetOldest replays the whole stream.
+	OffsetOldest = "0"
+	// OffsetNewest only delivers the events published from now on.
+	OffsetNewest = "$"
+)
+
+// Event is a change of a rating, as published to the stream.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Rating     Rating    `json:"rating"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// Rating is the state of a rating after the change, or before it was deleted.
+type Rating struct {
+	ID         string      `json:"id"`
+	UserID     string      `json:"userId"`
+	MovieID    string      `json:"movieId"`
+	Value      float32     `json:"value"`
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+	Review     *Review     `json:"review,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+	// Held ratings are waiting for moderation, consumers keep them out of sight as if they were deleted.
+	Held bool `json:"held,omitempty"`
+}
+
+// Dimensions holds the optional scores of the aspects of a movie, from 0.5 to 5.
+type Dimensions struct {
+	Story         *float32 `json:"story,omitempty"`
+	Acting        *float32 `json:"acting,omitempty"`
+	Visuals       *float32 `json:"visuals,omitempty"`
+	Entertainment *float32 `json:"entertainment,omitempty"`
+}
+
+// Review is the written review of a rating.
+type Review struct {
+	Title          string    `json:"title,omitempty"`
+	Body           string    `json:"body"`
+	Spoiler        bool      `json:"spoiler"`
+	Language       string    `json:"language,omitempty"`
+	HelpfulVotes   int       `json:"helpfulVotes"`
+	UnhelpfulVotes int       `json:"unhelpfulVotes"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Publisher appends events to a Redis stream.
+type Publisher struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewPublisher returns a new instance of Publisher.
+// The stream is trimmed to approximately maxLen entries, 0 means it is never trimmed.
+func NewPublisher(client *redis.Client, stream string, maxLen int64) *Publisher {
+	return &Publisher{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+// Publish appends the event to the stream.
+func (p *Publisher) Publish(ctx context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":  e.Type,
+			"event": string(b),
+		},
+	}).Err()
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+)
+
+// Subscriber reads the stream on its own, without a consumer group, so every subscriber receives every event.
+// Nothing is acknowledged, a subscriber that stops misses the events published meanwhile unless it reads them again by ID.
+type Subscriber struct {
+	client *redis.Client
+	stream string
+	logger *log.Logger
+}
+
+// NewSubscriber returns a new instance of Subscriber.
+func NewSubscriber(client *redis.Client, stream string, logger *log.Logger) *Subscriber {
+	return &Subscriber{
+		client: client,
+		stream: stream,
+		logger: logger,
+	}
+}
+
+// Subscribe delivers the events published after offset to handler, in order, until ctx is done.
+func (s *Subscriber) Subscribe(ctx context.Context, offset string, handler func(ctx context.Context, m *Message)) error {
+	last := offset
+	if last == OffsetNewest {
+		// "$" would skip the events published between two reads, so the reads go on from the last entry ID
+		id, err := s.LastID(ctx)
+		if err != nil {
+			return err
+		}
+		last = id
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil
+		}
+
+		streams, err := s.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{s.stream, last},
+			Count:   batchSize,
+			Block:   blockTimeout,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, st := range streams {
+			for _, xm := range st.Messages {
+				last = xm.ID
+
+				m, err := decode(xm)
+				if err != nil {
+					s.logger.Error("failed to decode event", log.String("streamId", xm.ID), log.Error(err))
+					continue
+				}
+				handler(ctx, m)
+			}
+		}
+	}
+}
+
+// Range returns up to count events published after the given stream entry ID, in order.
+// Malformed entries are skipped, but still count towards count.
+// It returns the ID of the last entry read, which is after unless there were no entries.
+func (s *Subscriber) Range(ctx context.Context, after string, count int64) ([]*Message, string, error) {
+	entries, err := s.client.XRangeN(ctx, s.stream, "("+after, "+", count).Result()
+	if err != nil {
+		return nil, after, err
+	}
+
+	list := make([]*Message, 0, len(entries))
+	for _, xm := range entries {
+		after = xm.ID
+
+		m, err := decode(xm)
+		if err != nil {
+			s.logger.Error("failed to decode event", log.String("streamId", xm.ID), log.Error(err))
+			continue
+		}
+		list = append(list, m)
+	}
+
+	return list, after, nil
+}
+
+// LastID returns the ID of the latest stream entry, or OffsetOldest if the stream is empty.
+func (s *Subscriber) LastID(ctx context.Context) (string, error) {
+	entries, err := s.client.XRevRangeN(ctx, s.stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return OffsetOldest, nil
+	}
+	return entries[0].ID, nil
+}
+
+// IsValidID checks if id is a valid stream entry ID.
+func IsValidID(id string) bool {
+	_, _, err := parseID(id)
+	return err == nil
+}
+
+// CompareIDs compares two stream entry IDs, returning -1, 0 or 1 if a is before, the same as or after b.
+// It returns an error if any of them is not a valid stream entry ID.
+func CompareIDs(a, b string) (int, error) {
+	am, as, err := parseID(a)
+	if err != nil {
+		return 0, err
+	}
+	bm, bs, err := parseID(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case am < bm || (am == bm && as < bs):
+		return -1, nil
+	case am == bm && as == bs:
+		return 0, nil
+	default:
+		return 1, nil
+	}
+}
+
+// parseID splits a stream entry ID into its milliseconds and sequence number parts.
+func parseID(id string) (uint64, uint64, error) {
+	ms, seq, found := strings.Cut(id, "-")
+	m, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid stream entry ID")
+	}
+	if !found {
+		return m, 0, nil
+	}
+	s, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid stream entry ID")
+	}
+	return m, s, nil
+}
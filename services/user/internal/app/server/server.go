@@ -9,11 +9,20 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
 	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
+	ratingClient "github.com/victorspringer/backend-coding-challenge/services/rating/pkg/client"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/bootstrap"
 	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/config"
 	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/database"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/deletion"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/feed"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/mailer"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/recovery"
 	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/router"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/verification"
 )
 
 // Init starts the application server.
@@ -25,7 +34,15 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 		logger,
 		cfg.MongoDB.URI,
 		cfg.MongoDB.DBName,
-		cfg.MongoDB.Collection,
+		database.Collections{
+			Users:      cfg.MongoDB.Collection,
+			Follows:    cfg.MongoDB.FollowCollection,
+			Deletions:  cfg.MongoDB.DeletionCollection,
+			Activities: cfg.MongoDB.ActivityCollection,
+			Timelines:  cfg.MongoDB.TimelineCollection,
+			Resets:     cfg.MongoDB.ResetCollection,
+		},
+		cfg.Feed.Retention*time.Second,
 		cfg.MongoDB.Timeout*time.Second,
 	)
 	if err != nil {
@@ -42,15 +59,109 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 
 	logger.Debug("database connected")
 
+	err = bootstrap.Admin(ctx, db, logger, cfg.Admin.Username, cfg.Admin.Password, cfg.Admin.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to bootstrap admin")
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: cfg.Redis.Addr,
+	})
+
+	defer func() {
+		if err := redisClient.Close(); err != nil {
+			logger.Error("failed to close redis connection pool", log.Error(err))
+		} else {
+			logger.Debug("redis connection closed")
+		}
+	}()
+
+	logger.Debug("redis connected")
+
 	ac := authClient.NewClient(
 		cfg.AuthenticationService.URL,
 		cfg.AuthenticationService.Timeout*time.Second,
 		logger,
 	)
 
+	rc := ratingClient.NewClient(
+		cfg.RatingService.URL,
+		cfg.RatingService.Timeout*time.Second,
+		logger,
+	)
+
+	// background jobs run until the server stops
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+
+	if cfg.RatingService.ServiceKey == "" {
+		return errors.New("rating service key is required")
+	}
+	if cfg.AuthenticationService.ServiceKey == "" {
+		return errors.New("authentication service key is required")
+	}
+
+	dw := deletion.NewWorkflow(
+		db,
+		rc,
+		ac,
+		logger,
+		cfg.RatingService.ServiceKey,
+		cfg.AuthenticationService.ServiceKey,
+		cfg.Deletion.QueueSize,
+		cfg.Deletion.Attempts,
+		cfg.Deletion.Backoff*time.Second,
+		cfg.Deletion.Lease*time.Second,
+	)
+	go dw.Run(jobsCtx)
+
+	// replicas share the consumer group, so each event is handled by one of them
+	hostname, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "failed to get hostname")
+	}
+
+	fd := feed.NewFeed(
+		db,
+		events.NewConsumer(redisClient, cfg.Feed.Stream, cfg.Feed.Group, hostname, logger, cfg.Feed.MaxDeliveries),
+		logger,
+		cfg.Feed.CelebrityFollowers,
+		cfg.Feed.FanoutBatchSize,
+	)
+	go fd.Run(jobsCtx)
+
+	m, err := newMailer(cfg, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to set up mailer")
+	}
+
+	if cfg.EmailVerification.Secret == "" {
+		return errors.New("email verification secret is required")
+	}
+
+	ev := verification.NewVerifier(
+		db,
+		m,
+		cfg.EmailVerification.Secret,
+		cfg.EmailVerification.TTL*time.Second,
+		cfg.EmailVerification.Link,
+	)
+
+	pr := recovery.NewResetter(
+		db,
+		m,
+		ac,
+		logger,
+		cfg.AuthenticationService.ServiceKey,
+		cfg.PasswordReset.TTL*time.Second,
+		cfg.PasswordReset.Link,
+		cfg.PasswordReset.QueueSize,
+	)
+	go pr.Run(jobsCtx)
+
 	server := http.Server{
 		Addr:         cfg.UserService.Server.Port,
-		Handler:      router.New(db, logger, ac).GetHandler(),
+		Handler:      router.New(db, logger, ac, dw, fd, ev, pr).GetHandler(),
 		ReadTimeout:  cfg.UserService.Server.ReadTimeout * time.Second,
 		WriteTimeout: cfg.UserService.Server.WriteTimeout * time.Second,
 		IdleTimeout:  cfg.UserService.Server.IdleTimeout * time.Second,
@@ -84,3 +195,17 @@ func Init(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 
 	return nil
 }
+
+// newMailer returns the Mailer of the configured driver.
+func newMailer(cfg *config.Config, logger *log.Logger) (mailer.Mailer, error) {
+	switch cfg.Mailer.Driver {
+	case "smtp":
+		return mailer.NewSMTP(cfg.Mailer.SMTPAddr, cfg.Mailer.SMTPUsername, cfg.Mailer.SMTPPassword, cfg.Mailer.From)
+	case "file":
+		return mailer.NewFile(cfg.Mailer.File, cfg.Mailer.From), nil
+	case "log":
+		return mailer.NewLog(logger), nil
+	default:
+		return nil, errors.Errorf("unknown mailer driver %q", cfg.Mailer.Driver)
+	}
+}
@@ -0,0 +1,72 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+)
+
+// ErrUsernameNotAvailable is returned when the admin's username belonged to a deleted account.
+var ErrUsernameNotAvailable = errors.New("admin username is not available")
+
+// Admin creates the first admin, as only admins can promote users, unless username is empty.
+// An existing user is left as is, so it can be run on every start and by every replica.
+func Admin(ctx context.Context, repo domain.Repository, logger *log.Logger, username, password, name string) error {
+	if username == "" {
+		return nil
+	}
+
+	exists, err := userExists(ctx, repo, username)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err := repo.FindDeletion(ctx, username); err != domain.ErrDeletionNotFound {
+		if err == nil {
+			return ErrUsernameNotAvailable
+		}
+		return err
+	}
+
+	if err := domain.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	hash, err := domain.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	u := domain.NewUser(username, hash, name, "")
+	if err := u.SetLevel(authClient.AdminLevel); err != nil {
+		return err
+	}
+
+	vu, err := domain.NewValidatedUser(u)
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.Create(ctx, vu); err != nil {
+		// another replica may have created it meanwhile
+		if exists, _ := userExists(ctx, repo, username); exists {
+			return nil
+		}
+		return err
+	}
+
+	logger.Info("admin created", log.String("username", username))
+
+	return nil
+}
+
+func userExists(ctx context.Context, repo domain.Repository, username string) (bool, error) {
+	_, err := repo.FindByID(ctx, username)
+	if err == domain.ErrUserNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
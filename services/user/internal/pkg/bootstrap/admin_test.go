@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+)
+
+// fakeRepository implements the methods of domain.Repository the bootstrap uses.
+type fakeRepository struct {
+	domain.Repository
+	users     map[string]*domain.User
+	deletions map[string]*domain.Deletion
+}
+
+func (f *fakeRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (f *fakeRepository) FindDeletion(ctx context.Context, userID string) (*domain.Deletion, error) {
+	if d, ok := f.deletions[userID]; ok {
+		return d, nil
+	}
+	return nil, domain.ErrDeletionNotFound
+}
+
+func (f *fakeRepository) Create(ctx context.Context, vu *domain.ValidatedUser) (*domain.User, error) {
+	u := vu.User
+	f.users[u.ID] = &u
+	return &u, nil
+}
+
+func TestAdmin(t *testing.T) {
+	repo := &fakeRepository{
+		users:     map[string]*domain.User{"bob": domain.NewUser("bob", "hash", "Bob", "")},
+		deletions: map[string]*domain.Deletion{"carol": domain.NewDeletion("carol", "carol")},
+	}
+	logger := log.New("fatal")
+
+	require.NoError(t, Admin(context.Background(), repo, logger, "", "", ""))
+	assert.Len(t, repo.users, 1)
+
+	require.NoError(t, Admin(context.Background(), repo, logger, "root", "root-secret", "Root"))
+	require.Contains(t, repo.users, "root")
+	assert.Equal(t, "admin", repo.users["root"].Level)
+	assert.NotEqual(t, "root-secret", repo.users["root"].Password)
+
+	// existing users are left as is
+	require.NoError(t, Admin(context.Background(), repo, logger, "bob", "bob-secret", "Bob"))
+	assert.Equal(t, "user", repo.users["bob"].Level)
+
+	assert.Equal(t, ErrUsernameNotAvailable, Admin(context.Background(), repo, logger, "carol", "carol-secret", "Carol"))
+	assert.Error(t, Admin(context.Background(), repo, logger, "dave", "", "Dave"))
+}
@@ -20,15 +20,66 @@ type Config struct {
 		} `mapstructure:"server"`
 	} `mapstructure:"user_service"`
 	MongoDB struct {
-		URI        string        `mapstructure:"uri"`
-		DBName     string        `mapstructure:"db_name"`
-		Collection string        `mapstructure:"collection"`
-		Timeout    time.Duration `mapstructure:"timeout"`
+		URI                string        `mapstructure:"uri"`
+		DBName             string        `mapstructure:"db_name"`
+		Collection         string        `mapstructure:"collection"`
+		FollowCollection   string        `mapstructure:"follow_collection"`
+		DeletionCollection string        `mapstructure:"deletion_collection"`
+		ActivityCollection string        `mapstructure:"activity_collection"`
+		TimelineCollection string        `mapstructure:"timeline_collection"`
+		ResetCollection    string        `mapstructure:"reset_collection"`
+		Timeout            time.Duration `mapstructure:"timeout"`
 	} `mapstructure:"mongodb"`
+	Redis struct {
+		Addr string `mapstructure:"addr"`
+	} `mapstructure:"redis"`
 	AuthenticationService struct {
-		URL     string        `mapstructure:"url"`
-		Timeout time.Duration `mapstructure:"timeout"`
+		URL        string        `mapstructure:"url"`
+		Timeout    time.Duration `mapstructure:"timeout"`
+		ServiceKey string        `mapstructure:"service_key"`
 	} `mapstructure:"authentication_service"`
+	RatingService struct {
+		URL        string        `mapstructure:"url"`
+		Timeout    time.Duration `mapstructure:"timeout"`
+		ServiceKey string        `mapstructure:"service_key"`
+	} `mapstructure:"rating_service"`
+	Deletion struct {
+		QueueSize int           `mapstructure:"queue_size"`
+		Attempts  int           `mapstructure:"attempts"`
+		Backoff   time.Duration `mapstructure:"backoff"`
+		Lease     time.Duration `mapstructure:"lease"`
+	} `mapstructure:"deletion"`
+	Feed struct {
+		Stream             string        `mapstructure:"stream"`
+		Group              string        `mapstructure:"group"`
+		CelebrityFollowers int           `mapstructure:"celebrity_followers"`
+		FanoutBatchSize    int           `mapstructure:"fanout_batch_size"`
+		Retention          time.Duration `mapstructure:"retention"`
+		MaxDeliveries      int           `mapstructure:"max_deliveries"`
+	} `mapstructure:"feed"`
+	Mailer struct {
+		Driver       string `mapstructure:"driver"`
+		From         string `mapstructure:"from"`
+		SMTPAddr     string `mapstructure:"smtp_addr"`
+		SMTPUsername string `mapstructure:"smtp_username"`
+		SMTPPassword string `mapstructure:"smtp_password"`
+		File         string `mapstructure:"file"`
+	} `mapstructure:"mailer"`
+	EmailVerification struct {
+		Secret string        `mapstructure:"secret"`
+		TTL    time.Duration `mapstructure:"ttl"`
+		Link   string        `mapstructure:"link"`
+	} `mapstructure:"email_verification"`
+	PasswordReset struct {
+		TTL       time.Duration `mapstructure:"ttl"`
+		Link      string        `mapstructure:"link"`
+		QueueSize int           `mapstructure:"queue_size"`
+	} `mapstructure:"password_reset"`
+	Admin struct {
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		Name     string `mapstructure:"name"`
+	} `mapstructure:"admin"`
 }
 
 // New returns a new instance of Config.
@@ -3,26 +3,59 @@ package database
 import (
 	"context"
 	"errors"
-	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
 	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// indexNotFoundCode is the MongoDB error code returned when dropping an index that doesn't exist.
+const indexNotFoundCode = 27
+
+// emailIndex is the name of the unique index of the email addresses, which tells the duplicates apart.
+const emailIndex = "email_1"
+
 type database struct {
 	logger     *log.Logger
 	client     *mongo.Client
 	name       string
 	collection *mongo.Collection
+	follows    *mongo.Collection
+	deletions  *mongo.Collection
+	activities *mongo.Collection
+	timelines  *mongo.Collection
+	resets     *mongo.Collection
 	timeout    time.Duration
 }
 
+// Collections contains the names of the collections used by the database.
+type Collections struct {
+	Users      string
+	Follows    string
+	Deletions  string
+	Activities string
+	Timelines  string
+	Resets     string
+}
+
 // New returns a new instance of database.
-func New(ctx context.Context, logger *log.Logger, uri, name, collection string, timeout time.Duration) (domain.Repository, error) {
+// The activities are kept in the feeds for feedRetention after their last change.
+func New(
+	ctx context.Context,
+	logger *log.Logger,
+	uri,
+	name string,
+	collections Collections,
+	feedRetention,
+	timeout time.Duration,
+) (domain.Repository, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -36,7 +69,7 @@ func New(ctx context.Context, logger *log.Logger, uri, name, collection string,
 		return nil, err
 	}
 
-	coll := client.Database(name).Collection(collection)
+	coll := client.Database(name).Collection(collections.Users)
 
 	// create unique index on the "id" field
 	idIndex := mongo.IndexModel{
@@ -47,15 +80,81 @@ func New(ctx context.Context, logger *log.Logger, uri, name, collection string,
 	if err != nil {
 		return nil, err
 	}
-	// create unique compound index on the "id" and "password" fields
-	compoundIndex := mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "id", Value: 1},
-			{Key: "password", Value: 1},
+	// drop the index credentials used to be looked up with, as passwords are now verified against their hash
+	_, err = coll.Indexes().DropOne(ctx, "id_1_password_1")
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != indexNotFoundCode {
+			return nil, err
+		}
+	}
+
+	// an email address belongs to a single user, the ones without any are left out of the index
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().
+			SetName(emailIndex).
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"email": bson.M{"$gt": ""}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the directory lists the users by username, and searches them by prefix of their keys, see Search,
+	// while admins list them by level
+	_, err = coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "unlisted", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "searchKeys", Value: 1}, {Key: "unlisted", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "level", Value: 1}, {Key: "id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// users created before the directory have no search keys yet
+	err = backfillSearchKeys(ctx, coll)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := client.Database(name).Collection(collections.Follows)
+
+	// an user follows another once, and the lists of followers and followed users are read sorted by username
+	_, err = follows.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "followerId", Value: 1}, {Key: "followeeId", Value: 1}},
+			Options: options.Index().SetUnique(true),
 		},
+		{Keys: bson.D{{Key: "followeeId", Value: 1}, {Key: "followerId", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deletions := client.Database(name).Collection(collections.Deletions)
+
+	// a user has a single deletion, which StartDeletion relies on to detect the locked ones
+	_, err = deletions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}},
 		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resets := client.Database(name).Collection(collections.Resets)
+
+	// a user has a single password reset, looked up by the hash of its token, which expires on its own
+	_, err = resets.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, err
 	}
-	_, err = coll.Indexes().CreateOne(ctx, compoundIndex)
+
+	activities, timelines, err := feedCollections(ctx, client.Database(name), collections, feedRetention)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +164,11 @@ func New(ctx context.Context, logger *log.Logger, uri, name, collection string,
 		client:     client,
 		name:       name,
 		collection: coll,
+		follows:    follows,
+		deletions:  deletions,
+		activities: activities,
+		timelines:  timelines,
+		resets:     resets,
 		timeout:    timeout,
 	}, nil
 }
@@ -77,6 +181,21 @@ func (db *database) Close(ctx context.Context) error {
 	return nil
 }
 
+// withTransaction runs fn in a transaction, retrying it on transient errors.
+func (db *database) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := db.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+
+	return err
+}
+
 // Create implements domain.Repository interface's Create method.
 func (db *database) Create(ctx context.Context, user *domain.ValidatedUser) (*domain.User, error) {
 	if user.IsValid() {
@@ -85,6 +204,9 @@ func (db *database) Create(ctx context.Context, user *domain.ValidatedUser) (*do
 
 		_, err := db.collection.InsertOne(ctx, user.User)
 		if err != nil {
+			if isDuplicateEmail(err) {
+				return nil, domain.ErrEmailNotAvailable
+			}
 			return nil, err
 		}
 
@@ -105,7 +227,47 @@ func (db *database) FindByID(ctx context.Context, id string) (*domain.User, erro
 
 	if err := db.collection.FindOne(ctx, filter).Decode(&u); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("user %s doesn't exist", id)
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// Update implements domain.Repository interface's Update method.
+func (db *database) Update(ctx context.Context, user *domain.ValidatedUser) (*domain.User, error) {
+	if !user.IsValid() {
+		return nil, errors.New("invalid user data")
+	}
+
+	filter := bson.D{{Key: "id", Value: user.ID}}
+
+	// only the profile is replaced, so a password rehashed meanwhile is kept
+	update := bson.M{
+		"$set": bson.M{
+			"name":           user.Name,
+			"picture":        user.Picture,
+			"email":          user.Email,
+			"emailVerified":  user.EmailVerified,
+			"emailUpdatedAt": user.EmailUpdatedAt,
+			"unlisted":       user.Unlisted,
+			"searchKeys":     user.SearchKeys,
+			"updatedAt":      user.UpdatedAt,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var u domain.User
+	err := db.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&u)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
+		}
+		if isDuplicateEmail(err) {
+			return nil, domain.ErrEmailNotAvailable
 		}
 		return nil, err
 	}
@@ -113,9 +275,9 @@ func (db *database) FindByID(ctx context.Context, id string) (*domain.User, erro
 	return &u, nil
 }
 
-// FindByCredentials implements domain.Repository interface's FindByCredentials method.
-func (db *database) FindByCredentials(ctx context.Context, id, password string) (*domain.User, error) {
-	filter := bson.D{{Key: "id", Value: id}, {Key: "password", Value: password}}
+// FindByEmail implements domain.Repository interface's FindByEmail method.
+func (db *database) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	filter := bson.D{{Key: "email", Value: email}}
 
 	var u domain.User
 
@@ -124,10 +286,230 @@ func (db *database) FindByCredentials(ctx context.Context, id, password string)
 
 	if err := db.collection.FindOne(ctx, filter).Decode(&u); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("invalid credentials")
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// VerifyEmail implements domain.Repository interface's VerifyEmail method.
+func (db *database) VerifyEmail(ctx context.Context, id, email string, emailUpdatedAt time.Time) (*domain.User, error) {
+	// the address may have changed since the verification was sent, even if back to the same one
+	filter := bson.D{
+		{Key: "id", Value: id},
+		{Key: "email", Value: email},
+		{Key: "emailUpdatedAt", Value: emailUpdatedAt},
+	}
+
+	update := bson.M{"$set": bson.M{"emailVerified": true}}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var u domain.User
+	err := db.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&u)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
 
 	return &u, nil
 }
+
+// UpdateAccess implements domain.Repository interface's UpdateAccess method.
+func (db *database) UpdateAccess(ctx context.Context, user *domain.User) (*domain.User, error) {
+	filter := bson.D{{Key: "id", Value: user.ID}}
+
+	update := bson.M{
+		"$set": bson.M{
+			"level":     user.Level,
+			"disabled":  user.Disabled,
+			"updatedAt": user.UpdatedAt,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var former domain.User
+	err := db.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&former)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	u := former
+	u.Level, u.Disabled, u.UpdatedAt = user.Level, user.Disabled, user.UpdatedAt
+
+	if !former.IsAdmin() || u.IsAdmin() {
+		return &u, nil
+	}
+
+	// The admins left are counted once the change is stored, and it is undone if there is none: of concurrent
+	// changes removing the last admins, the one counting last sees them all, so at least that one is undone.
+	n, err := db.collection.CountDocuments(ctx, bson.D{{Key: "level", Value: authClient.AdminLevel}, {Key: "disabled", Value: false}})
+	if err == nil && n > 0 {
+		return &u, nil
+	}
+
+	// unless the access was changed again meanwhile
+	undo := bson.M{
+		"$set": bson.M{
+			"level":     former.Level,
+			"disabled":  former.Disabled,
+			"updatedAt": former.UpdatedAt,
+		},
+	}
+	if _, undoErr := db.collection.UpdateOne(ctx, bson.D{{Key: "id", Value: user.ID}, {Key: "updatedAt", Value: user.UpdatedAt}}, undo); undoErr != nil {
+		return nil, undoErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, domain.ErrLastAdmin
+}
+
+// ListByLevel implements domain.Repository interface's ListByLevel method.
+func (db *database) ListByLevel(ctx context.Context, level, cursor string, limit int) ([]*domain.User, error) {
+	filter := bson.M{"level": level}
+	if cursor != "" {
+		filter["id"] = bson.M{"$gt": cursor}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "id", Value: 1}}).SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*domain.User{}
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Search implements domain.Repository interface's Search method.
+func (db *database) Search(ctx context.Context, query, cursor string, limit int) ([]*domain.User, error) {
+	filter := bson.M{"unlisted": false, "disabled": bson.M{"$ne": true}}
+	if query = domain.NormalizeSearch(query); query != "" {
+		// anchored and case sensitive, as the keys are lowercase, so the index bounds the scan
+		filter["searchKeys"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query)}
+	}
+	if cursor != "" {
+		filter["id"] = bson.M{"$gt": cursor}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "id", Value: 1}}).SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*domain.User{}
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// isDuplicateEmail checks if err is a duplicate key error of the email addresses' index, rather than of another one.
+func isDuplicateEmail(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), emailIndex)
+}
+
+// backfillSearchKeys sets the search keys, and lists in the directory, the users which have none yet.
+func backfillSearchKeys(ctx context.Context, coll *mongo.Collection) error {
+	cur, err := coll.Find(ctx, bson.M{"searchKeys": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var u domain.User
+		if err = cur.Decode(&u); err != nil {
+			return err
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				"searchKeys": domain.SearchKeys(u.Username, u.Name),
+				"unlisted":   u.Unlisted,
+			},
+		}
+		if _, err = coll.UpdateOne(ctx, bson.M{"id": u.ID}, update); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
+
+// Delete implements domain.Repository interface's Delete method.
+func (db *database) Delete(ctx context.Context, id string) error {
+	// the follows, the timeline and the password reset go first, so they are still removed when the deletion is run again after a failure
+	if err := db.deleteFollows(ctx, id); err != nil {
+		return err
+	}
+	if err := db.deleteTimeline(ctx, id, ""); err != nil {
+		return err
+	}
+	if err := db.deletePasswordReset(ctx, bson.M{"userId": id}); err != nil {
+		return err
+	}
+
+	filter := bson.D{{Key: "id", Value: id}}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	res, err := db.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdatePassword implements domain.Repository interface's UpdatePassword method.
+func (db *database) UpdatePassword(ctx context.Context, id, current, hash string) error {
+	filter := bson.D{{Key: "id", Value: id}, {Key: "password", Value: current}}
+
+	update := bson.M{
+		"$set": bson.M{"password": hash},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	res, err := db.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrPasswordChanged
+	}
+
+	return nil
+}
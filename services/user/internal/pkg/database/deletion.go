@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StartDeletion implements domain.Repository interface's StartDeletion method.
+func (db *database) StartDeletion(ctx context.Context, deletion *domain.Deletion, lease time.Duration) (*domain.Deletion, error) {
+	now := time.Now()
+
+	// deletions which are not completed nor locked by another run
+	filter := bson.M{
+		"userId":      deletion.UserID,
+		"status":      bson.M{"$ne": domain.DeletionCompleted},
+		"lockedUntil": bson.M{"$lt": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":      domain.DeletionRunning,
+			"lockedUntil": now.Add(lease),
+			"runId":       primitive.NewObjectID().Hex(),
+			"updatedAt":   now,
+		},
+		"$setOnInsert": bson.M{
+			"requestedBy":    deletion.RequestedBy,
+			"completedSteps": deletion.CompletedSteps,
+			"attempts":       0,
+			"createdAt":      deletion.CreatedAt,
+		},
+	}
+
+	updateOptions := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var d domain.Deletion
+	err := db.deletions.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&d)
+	if err == nil {
+		return &d, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	// the user's deletion exists but didn't match, so it is either completed or locked
+	if err := db.deletions.FindOne(ctx, bson.M{"userId": deletion.UserID}).Decode(&d); err != nil {
+		return nil, err
+	}
+	if d.Status == domain.DeletionCompleted {
+		return &d, nil
+	}
+	return &d, domain.ErrDeletionInProgress
+}
+
+// SaveDeletion implements domain.Repository interface's SaveDeletion method.
+func (db *database) SaveDeletion(ctx context.Context, deletion *domain.Deletion) error {
+	// only the run that has the deletion saves it, as the others were given up on
+	filter := bson.M{
+		"userId": deletion.UserID,
+		"runId":  deletion.RunID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	res, err := db.deletions.ReplaceOne(ctx, filter, deletion)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrDeletionInProgress
+	}
+
+	return nil
+}
+
+// FindDeletion implements domain.Repository interface's FindDeletion method.
+func (db *database) FindDeletion(ctx context.Context, userID string) (*domain.Deletion, error) {
+	filter := bson.M{"userId": userID}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	var d domain.Deletion
+	if err := db.deletions.FindOne(ctx, filter).Decode(&d); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrDeletionNotFound
+		}
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// FindStaleDeletions implements domain.Repository interface's FindStaleDeletions method.
+func (db *database) FindStaleDeletions(ctx context.Context, limit int) ([]*domain.Deletion, error) {
+	// the same deletions StartDeletion resumes
+	filter := bson.M{
+		"status":      bson.M{"$ne": domain.DeletionCompleted},
+		"lockedUntil": bson.M{"$lt": time.Now()},
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}}).SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.deletions.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	deletions := []*domain.Deletion{}
+	if err = cur.All(ctx, &deletions); err != nil {
+		return nil, err
+	}
+
+	return deletions, nil
+}
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// timelineEntry is an Activity in the timeline of one of the followers of its user.
+type timelineEntry struct {
+	OwnerID         string `bson:"ownerId"`
+	domain.Activity `bson:",inline"`
+}
+
+// feedCollections returns the activities and timelines collections, creating their indexes.
+// Both expire their documents retention after the last change of the activity.
+func feedCollections(
+	ctx context.Context,
+	db *mongo.Database,
+	collections Collections,
+	retention time.Duration,
+) (*mongo.Collection, *mongo.Collection, error) {
+	ttl := options.Index().SetExpireAfterSeconds(int32(retention.Seconds()))
+
+	activities := db.Collection(collections.Activities)
+
+	// an activity per rating, read by user newest first when their followers' feeds are built on read
+	_, err := activities.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "occurredAt", Value: -1}, {Key: "id", Value: -1}}},
+		{Keys: bson.D{{Key: "occurredAt", Value: 1}}, Options: ttl},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timelines := db.Collection(collections.Timelines)
+
+	// an entry per activity and follower, read by follower newest first,
+	// and removed by activity when a rating is deleted or by user when they are unfollowed
+	_, err = timelines.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "occurredAt", Value: -1}, {Key: "id", Value: -1}}},
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "occurredAt", Value: 1}}, Options: ttl},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return activities, timelines, nil
+}
+
+// SaveActivity implements domain.Repository interface's SaveActivity method.
+func (db *database) SaveActivity(ctx context.Context, a *domain.Activity) (bool, error) {
+	// matches the same or older states only, so the unique index rejects the upsert when a newer one is stored
+	filter := bson.M{"id": a.ID, "occurredAt": bson.M{"$lte": a.OccurredAt}}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.activities.ReplaceOne(ctx, filter, a, options.Replace().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteActivity implements domain.Repository interface's DeleteActivity method.
+func (db *database) DeleteActivity(ctx context.Context, id string, deletedAt time.Time) error {
+	filter := bson.M{"id": id, "occurredAt": bson.M{"$lte": deletedAt}}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if _, err := db.activities.DeleteOne(ctx, filter); err != nil {
+		return err
+	}
+
+	_, err := db.timelines.DeleteMany(ctx, filter)
+	return err
+}
+
+// FollowerIDs implements domain.Repository interface's FollowerIDs method.
+func (db *database) FollowerIDs(ctx context.Context, id, cursor string, limit int) ([]string, error) {
+	filter := bson.M{"followeeId": id}
+	if cursor != "" {
+		filter["followerId"] = bson.M{"$gt": cursor}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "followerId", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"followerId": 1})
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.follows.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []*domain.Follow
+	if err = cur.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(follows))
+	for _, f := range follows {
+		ids = append(ids, f.FollowerID)
+	}
+
+	return ids, nil
+}
+
+// FanOut implements domain.Repository interface's FanOut method.
+func (db *database) FanOut(ctx context.Context, a *domain.Activity, ownerIDs []string) error {
+	if len(ownerIDs) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"ownerId": ownerID, "id": a.ID, "occurredAt": bson.M{"$lte": a.OccurredAt}}).
+			SetReplacement(&timelineEntry{OwnerID: ownerID, Activity: *a}).
+			SetUpsert(true))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.timelines.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeys(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Timeline implements domain.Repository interface's Timeline method.
+func (db *database) Timeline(ctx context.Context, ownerID string, cursor *domain.FeedCursor, limit int) ([]*domain.Activity, error) {
+	filter := feedFilter(cursor)
+	filter["ownerId"] = ownerID
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.timelines.Find(ctx, filter, feedOptions(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*timelineEntry
+	if err = cur.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	activities := make([]*domain.Activity, 0, len(entries))
+	for _, e := range entries {
+		a := e.Activity
+		activities = append(activities, &a)
+	}
+
+	return activities, nil
+}
+
+// FollowedCelebrities implements domain.Repository interface's FollowedCelebrities method.
+func (db *database) FollowedCelebrities(ctx context.Context, id string, minFollowers int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	followeeIDs, err := db.follows.Distinct(ctx, "followeeId", bson.M{"followerId": id})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	if len(followeeIDs) == 0 {
+		return ids, nil
+	}
+
+	filter := bson.M{"id": bson.M{"$in": followeeIDs}, "followers": bson.M{"$gte": minFollowers}}
+
+	cur, err := db.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var users []*domain.User
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+
+	return ids, nil
+}
+
+// Activities implements domain.Repository interface's Activities method.
+func (db *database) Activities(ctx context.Context, userIDs []string, cursor *domain.FeedCursor, limit int) ([]*domain.Activity, error) {
+	activities := []*domain.Activity{}
+	if len(userIDs) == 0 {
+		return activities, nil
+	}
+
+	filter := feedFilter(cursor)
+	filter["userId"] = bson.M{"$in": userIDs}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.activities.Find(ctx, filter, feedOptions(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cur.All(ctx, &activities); err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
+
+// deleteTimeline removes an user's timeline, or only the activities of another user from it if given.
+func (db *database) deleteTimeline(ctx context.Context, ownerID, userID string) error {
+	filter := bson.M{"ownerId": ownerID}
+	if userID != "" {
+		filter["userId"] = userID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.timelines.DeleteMany(ctx, filter)
+	return err
+}
+
+// feedFilter returns the filter of the activities after cursor in a feed, all of them if it is nil.
+func feedFilter(cursor *domain.FeedCursor) bson.M {
+	if cursor == nil {
+		return bson.M{}
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{"occurredAt": bson.M{"$lt": cursor.OccurredAt}},
+		bson.M{"occurredAt": cursor.OccurredAt, "id": bson.M{"$lt": cursor.ID}},
+	}}
+}
+
+// feedOptions returns the options to read up to limit activities of a feed, newest first.
+func feedOptions(limit int) *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: "occurredAt", Value: -1}, {Key: "id", Value: -1}}).
+		SetLimit(int64(limit))
+}
+
+// onlyDuplicateKeys checks if all the errors of a bulk write are duplicate key errors.
+func onlyDuplicateKeys(err error) bool {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return false
+	}
+
+	for _, we := range bwe.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we) {
+			return false
+		}
+	}
+
+	return true
+}
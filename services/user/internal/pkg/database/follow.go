@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Follow implements domain.Repository interface's Follow method.
+func (db *database) Follow(ctx context.Context, follow *domain.Follow) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	// the edge and the counts change together, and the unique index on the edges rejects a duplicate
+	// before anything is counted
+	return db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := db.follows.InsertOne(sc, follow); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return domain.ErrAlreadyFollowing
+			}
+			return err
+		}
+
+		return db.countFollow(sc, follow.FollowerID, follow.FolloweeID, 1)
+	})
+}
+
+// Unfollow implements domain.Repository interface's Unfollow method.
+func (db *database) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	return db.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		res, err := db.follows.DeleteOne(sc, bson.M{"followerId": followerID, "followeeId": followeeID})
+		if err != nil {
+			return err
+		}
+		if res.DeletedCount == 0 {
+			return domain.ErrNotFollowing
+		}
+
+		if err := db.countFollow(sc, followerID, followeeID, -1); err != nil {
+			return err
+		}
+
+		// the activities fanned out before are not the follower's business anymore
+		return db.deleteTimeline(sc, followerID, followeeID)
+	})
+}
+
+// Followers implements domain.Repository interface's Followers method.
+func (db *database) Followers(ctx context.Context, id, cursor string, limit int) ([]*domain.User, error) {
+	return db.followUsers(ctx, "followeeId", "followerId", id, cursor, limit)
+}
+
+// Following implements domain.Repository interface's Following method.
+func (db *database) Following(ctx context.Context, id, cursor string, limit int) ([]*domain.User, error) {
+	return db.followUsers(ctx, "followerId", "followeeId", id, cursor, limit)
+}
+
+// followUsers retrieves the users on the other end of the edges of an user, sorted by username.
+// The edges are looked up on the index starting with the user's end, which is already sorted by the other one.
+func (db *database) followUsers(ctx context.Context, end, otherEnd, id, cursor string, limit int) ([]*domain.User, error) {
+	filter := bson.M{end: id}
+	if cursor != "" {
+		filter[otherEnd] = bson.M{"$gt": cursor}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: otherEnd, Value: 1}}).
+		SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.follows.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []*domain.Follow
+	if err = cur.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(follows))
+	for _, f := range follows {
+		if end == "followerId" {
+			ids = append(ids, f.FolloweeID)
+		} else {
+			ids = append(ids, f.FollowerID)
+		}
+	}
+
+	users := []*domain.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	cur, err = db.collection.Find(
+		ctx,
+		bson.M{"id": bson.M{"$in": ids}},
+		options.Find().SetSort(bson.D{{Key: "id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// countFollow adds delta to the following count of the follower and the followers count of the followee.
+func (db *database) countFollow(ctx context.Context, followerID, followeeID string, delta int) error {
+	_, err := db.collection.UpdateOne(ctx, bson.M{"id": followerID}, bson.M{"$inc": bson.M{"following": delta}})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.collection.UpdateOne(ctx, bson.M{"id": followeeID}, bson.M{"$inc": bson.M{"followers": delta}})
+	return err
+}
+
+// deleteFollows removes every follow from and to an user, uncounting them on the users on the other end.
+// Each follow is uncounted once it is removed, so an interrupted run can simply be run again.
+func (db *database) deleteFollows(ctx context.Context, id string) error {
+	follows, err := db.findFollows(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range follows {
+		if err = db.Unfollow(ctx, f.FollowerID, f.FolloweeID); err != nil && err != domain.ErrNotFollowing {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findFollows retrieves every follow from and to an user.
+func (db *database) findFollows(ctx context.Context, id string) ([]*domain.Follow, error) {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	cur, err := db.follows.Find(ctx, bson.M{"$or": bson.A{bson.M{"followerId": id}, bson.M{"followeeId": id}}})
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []*domain.Follow
+	if err = cur.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	return follows, nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SavePasswordReset implements domain.Repository interface's SavePasswordReset method.
+func (db *database) SavePasswordReset(ctx context.Context, reset *domain.PasswordReset) error {
+	filter := bson.M{"userId": reset.UserID}
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.resets.ReplaceOne(ctx, filter, reset, options.Replace().SetUpsert(true))
+	return err
+}
+
+// FindPasswordReset implements domain.Repository interface's FindPasswordReset method.
+func (db *database) FindPasswordReset(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	// the expired ones are only removed once in a while
+	filter := bson.M{"tokenHash": tokenHash, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	var r domain.PasswordReset
+
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	if err := db.resets.FindOne(ctx, filter).Decode(&r); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrInvalidResetToken
+		}
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// DeletePasswordReset implements domain.Repository interface's DeletePasswordReset method.
+func (db *database) DeletePasswordReset(ctx context.Context, tokenHash string) error {
+	return db.deletePasswordReset(ctx, bson.M{"tokenHash": tokenHash})
+}
+
+// deletePasswordReset removes the password reset matching filter, if any.
+func (db *database) deletePasswordReset(ctx context.Context, filter bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	_, err := db.resets.DeleteOne(ctx, filter)
+	return err
+}
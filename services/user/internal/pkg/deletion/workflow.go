@@ -0,0 +1,271 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
+	ratingClient "github.com/victorspringer/backend-coding-challenge/services/rating/pkg/client"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+)
+
+// RatingService removes the data a user left in the rating service on behalf of this service, see
+// ratingClient.Client.
+type RatingService interface {
+	DeleteUserAsService(ctx context.Context, userID, serviceKey string) error
+}
+
+// AuthenticationService revokes the sessions of a user on behalf of this service, see authClient.Client.
+type AuthenticationService interface {
+	RevokeUserAsService(ctx context.Context, username, serviceKey string) error
+}
+
+// ErrQueueFull is returned when too many deletions are waiting to run.
+var ErrQueueFull = errors.New("too many account deletions in progress, try again later")
+
+// Workflow runs the deletions of users' accounts across the services in the background, one step after the other.
+// Every step can be run again after a failure, so a failed or interrupted Deletion is resumed by starting it again,
+// which the Workflow does by itself too. The other services are called with their service keys rather than the
+// requester's access token, which may expire before the Deletion is done.
+type Workflow struct {
+	repository domain.Repository
+	ratings    RatingService
+	auth       AuthenticationService
+	logger     *log.Logger
+	ratingKey  string
+	authKey    string
+	attempts   int
+	backoff    time.Duration
+	lease      time.Duration
+	queue      chan *job
+}
+
+type job struct {
+	deletion   *domain.Deletion
+	onFinished func(d *domain.Deletion)
+}
+
+// NewWorkflow returns a new instance of Workflow.
+// ratingKey and authKey are the service keys of the rating and authentication services.
+// queueSize is the number of deletions that can wait to run, and each step is attempted up to attempts times,
+// waiting backoff times the number of failed attempts in between. A run has its Deletion to itself for the lease
+// duration, extended when it leaves the queue, after every step and over every wait before a retry, so one that was
+// interrupted (e.g. by a crash) can be resumed once it is over. A run that outlived its lease stops as soon as it
+// finds out another one took the Deletion over.
+func NewWorkflow(
+	repo domain.Repository,
+	ratings RatingService,
+	auth AuthenticationService,
+	logger *log.Logger,
+	ratingKey,
+	authKey string,
+	queueSize,
+	attempts int,
+	backoff,
+	lease time.Duration,
+) *Workflow {
+	return &Workflow{
+		repository: repo,
+		ratings:    ratings,
+		auth:       auth,
+		logger:     logger,
+		ratingKey:  ratingKey,
+		authKey:    authKey,
+		attempts:   attempts,
+		backoff:    backoff,
+		lease:      lease,
+		queue:      make(chan *job, queueSize),
+	}
+}
+
+// Start stores the Deletion of a user's account, or resumes it if it failed or was interrupted, and schedules it
+// to run in the background. onFinished is called once it stops running.
+// A completed Deletion is returned as is, and ErrDeletionInProgress is returned if another run has it.
+// Nothing is stored when ErrQueueFull is returned, so the account is left as it was.
+func (wf *Workflow) Start(
+	ctx context.Context,
+	userID,
+	requestedBy string,
+	onFinished func(d *domain.Deletion),
+) (*domain.Deletion, error) {
+	if len(wf.queue) == cap(wf.queue) {
+		return nil, ErrQueueFull
+	}
+
+	d, err := wf.repository.StartDeletion(ctx, domain.NewDeletion(userID, requestedBy), wf.lease)
+	if err != nil || d.Status == domain.DeletionCompleted {
+		return d, err
+	}
+
+	wf.enqueue(ctx, d, onFinished)
+	return d, nil
+}
+
+// Run processes the queued deletions until ctx is done.
+// The failed and interrupted deletions are resumed when it starts, and then every lease duration.
+func (wf *Workflow) Run(ctx context.Context) {
+	ticker := time.NewTicker(wf.lease)
+	defer ticker.Stop()
+
+	wf.resume(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wf.resume(ctx)
+		case j := <-wf.queue:
+			// the lease may have run out while the job was waiting, and the Deletion resumed by another run
+			j.deletion.LockedUntil = time.Now().Add(wf.lease)
+			if wf.save(ctx, j.deletion) {
+				wf.process(ctx, j.deletion)
+			}
+			if j.onFinished != nil {
+				j.onFinished(j.deletion)
+			}
+		}
+	}
+}
+
+// resume schedules the failed and interrupted deletions to run again, as many as there is room for in the queue.
+// The others are resumed next time.
+func (wf *Workflow) resume(ctx context.Context) {
+	room := cap(wf.queue) - len(wf.queue)
+	if room == 0 {
+		return
+	}
+
+	stale, err := wf.repository.FindStaleDeletions(ctx, room)
+	if err != nil {
+		wf.logger.Error("failed to find account deletions to resume", log.Error(err))
+		return
+	}
+
+	for _, s := range stale {
+		// another replica may have resumed it meanwhile
+		d, err := wf.repository.StartDeletion(ctx, s, wf.lease)
+		if err != nil || d.Status == domain.DeletionCompleted {
+			continue
+		}
+
+		if !wf.enqueue(ctx, d, nil) {
+			return
+		}
+		wf.logger.Info("account deletion resumed", log.String("userId", d.UserID), log.String("step", d.NextStep()))
+	}
+}
+
+// enqueue schedules a started Deletion to run. If the queue filled up meanwhile, it is released instead,
+// and left to be resumed later.
+func (wf *Workflow) enqueue(ctx context.Context, d *domain.Deletion, onFinished func(d *domain.Deletion)) bool {
+	select {
+	case wf.queue <- &job{deletion: d, onFinished: onFinished}:
+		return true
+	default:
+		d.LockedUntil = time.Time{}
+		wf.save(ctx, d)
+		return false
+	}
+}
+
+// process runs the steps of a Deletion not completed yet, until they are all completed or one is given up on.
+func (wf *Workflow) process(ctx context.Context, d *domain.Deletion) {
+	for step := d.NextStep(); step != ""; step = d.NextStep() {
+		err := wf.runStep(ctx, d, step)
+		if err == domain.ErrDeletionInProgress {
+			return
+		}
+		if err != nil {
+			wf.logger.Error(
+				"account deletion failed",
+				log.String("userId", d.UserID),
+				log.String("step", step),
+				log.Error(err),
+			)
+			d.Fail()
+			d.LockedUntil = time.Time{}
+			wf.save(ctx, d)
+			return
+		}
+
+		d.CompleteStep()
+		d.LockedUntil = time.Now().Add(wf.lease)
+		if d.Status == domain.DeletionCompleted {
+			d.LockedUntil = time.Time{}
+		}
+		if !wf.save(ctx, d) {
+			return
+		}
+	}
+
+	wf.logger.Info("account deleted", log.String("userId", d.UserID), log.String("requestedBy", d.RequestedBy))
+}
+
+// runStep attempts a step until it succeeds, the attempts are exhausted or retrying can't help.
+// It returns domain.ErrDeletionInProgress if another run took the Deletion over meanwhile.
+func (wf *Workflow) runStep(ctx context.Context, d *domain.Deletion, step string) error {
+	for {
+		err := wf.step(ctx, step, d.UserID)
+		if err == nil {
+			return nil
+		}
+
+		d.FailAttempt(err)
+		if d.Attempts >= wf.attempts || isPermanent(err) {
+			return err
+		}
+
+		wait := wf.backoff * time.Duration(d.Attempts)
+		d.LockedUntil = time.Now().Add(wait + wf.lease)
+		if !wf.save(ctx, d) {
+			return domain.ErrDeletionInProgress
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (wf *Workflow) step(ctx context.Context, step, userID string) error {
+	switch step {
+	case domain.DeleteRatingsStep:
+		return wf.ratings.DeleteUserAsService(ctx, userID, wf.ratingKey)
+	case domain.DeleteUserStep:
+		err := wf.repository.Delete(ctx, userID)
+		if err == domain.ErrUserNotFound {
+			// deleted by a previous attempt
+			return nil
+		}
+		return err
+	case domain.RevokeSessionsStep:
+		return wf.auth.RevokeUserAsService(ctx, userID, wf.authKey)
+	default:
+		return errors.New("unknown account deletion step " + step)
+	}
+}
+
+// save stores the progress of a Deletion, which is only lost on failure: the steps are simply run again when resumed.
+// It returns false if another run took the Deletion over, which this one must leave it to.
+func (wf *Workflow) save(ctx context.Context, d *domain.Deletion) bool {
+	// progress is saved even when stopping, so the Deletion can be resumed right away
+	err := wf.repository.SaveDeletion(context.WithoutCancel(ctx), d)
+	switch {
+	case err == domain.ErrDeletionInProgress:
+		wf.logger.Warn("account deletion taken over by another run", log.String("userId", d.UserID))
+		return false
+	case err != nil:
+		wf.logger.Error("failed to save account deletion", log.String("userId", d.UserID), log.Error(err))
+	}
+	return true
+}
+
+// isPermanent checks if an error can't go away by retrying, e.g. when a service key was refused.
+func isPermanent(err error) bool {
+	return errors.Is(err, ratingClient.ErrUnauthorized) || errors.Is(err, authClient.ErrUnauthorized)
+}
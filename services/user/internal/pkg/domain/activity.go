@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Activity types.
+const (
+	RatedActivity    = "rated"
+	ReviewedActivity = "reviewed"
+)
+
+// ErrInvalidFeedCursor is returned when a feed cursor can't be parsed.
+var ErrInvalidFeedCursor = errors.New("invalid feed cursor")
+
+// Activity is something a user did, shown in the feeds of their followers.
+// It is the latest state of one of their ratings, so a rating shows once however many times it changes.
+type Activity struct {
+	ID          string    `json:"id" bson:"id"` // the rating's ID
+	UserID      string    `json:"userId" bson:"userId"`
+	Type        string    `json:"type" bson:"type"`
+	MovieID     string    `json:"movieId" bson:"movieId"`
+	Value       float32   `json:"value" bson:"value"`
+	ReviewTitle string    `json:"reviewTitle,omitempty" bson:"reviewTitle,omitempty"`
+	Spoiler     bool      `json:"spoiler,omitempty" bson:"spoiler,omitempty"`
+	OccurredAt  time.Time `json:"occurredAt" bson:"occurredAt"`
+}
+
+// FeedPage is a page of a user's feed, newest first.
+// NextCursor is passed as the cursor to get the next page, and is empty on the last one.
+type FeedPage struct {
+	Activities []*Activity `json:"activities"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// FeedCursor is the position of an Activity in a feed, which is sorted by time and then ID, newest first.
+type FeedCursor struct {
+	OccurredAt time.Time
+	ID         string
+}
+
+// CursorOf returns the FeedCursor of an Activity.
+func CursorOf(a *Activity) *FeedCursor {
+	return &FeedCursor{OccurredAt: a.OccurredAt, ID: a.ID}
+}
+
+// String returns the FeedCursor as passed in requests, see ParseFeedCursor.
+func (c *FeedCursor) String() string {
+	return strconv.FormatInt(c.OccurredAt.UnixNano(), 10) + "_" + c.ID
+}
+
+// ParseFeedCursor parses a FeedCursor, see FeedCursor.String.
+// It returns ErrInvalidFeedCursor if s is not one.
+func ParseFeedCursor(s string) (*FeedCursor, error) {
+	nanos, id, ok := strings.Cut(s, "_")
+	if !ok || id == "" {
+		return nil, ErrInvalidFeedCursor
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidFeedCursor
+	}
+
+	return &FeedCursor{OccurredAt: time.Unix(0, n).UTC(), ID: id}, nil
+}
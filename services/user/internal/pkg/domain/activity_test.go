@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedCursor(t *testing.T) {
+	a := &Activity{ID: "0b7c-4f1e", OccurredAt: time.Date(2024, 5, 1, 10, 30, 0, 123000000, time.UTC)}
+
+	c, err := ParseFeedCursor(CursorOf(a).String())
+	assert.NoError(t, err)
+	assert.Equal(t, a.ID, c.ID)
+	assert.True(t, a.OccurredAt.Equal(c.OccurredAt))
+
+	for _, s := range []string{"", "123", "123_", "abc_0b7c"} {
+		_, err := ParseFeedCursor(s)
+		assert.Equal(t, ErrInvalidFeedCursor, err, s)
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Deletion steps, in the order they are run.
+// The sessions are revoked last, so the user can follow the deletion until the rest of their account is gone.
+const (
+	DeleteRatingsStep  = "ratings"
+	DeleteUserStep     = "user"
+	RevokeSessionsStep = "sessions"
+)
+
+var deletionSteps = []string{DeleteRatingsStep, DeleteUserStep, RevokeSessionsStep}
+
+// Deletion statuses.
+const (
+	DeletionRunning   = "running"
+	DeletionFailed    = "failed"
+	DeletionCompleted = "completed"
+)
+
+// Deletion errors.
+var (
+	ErrDeletionNotFound   = errors.New("account deletion not found")
+	ErrDeletionInProgress = errors.New("account deletion already in progress")
+)
+
+// Deletion tracks the deletion of a user's account across the services, so a failed one can be resumed
+// from the step it failed at. Once a user has one, they can't log in anymore and their username is not available again.
+type Deletion struct {
+	UserID         string     `json:"userId" bson:"userId"`
+	RequestedBy    string     `json:"requestedBy" bson:"requestedBy"`
+	Status         string     `json:"status" bson:"status"`
+	CompletedSteps []string   `json:"completedSteps" bson:"completedSteps"`
+	Attempts       int        `json:"attempts" bson:"attempts"` // attempts of the current step
+	LastError      string     `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt" bson:"updatedAt"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	LockedUntil    time.Time  `json:"-" bson:"lockedUntil"` // until when a run has it to itself
+	RunID          string     `json:"-" bson:"runId"`       // the run that last locked it
+}
+
+// NewDeletion returns an instance of the Deletion entity, requested by the user themselves or an admin.
+func NewDeletion(userID, requestedBy string) *Deletion {
+	return &Deletion{
+		UserID:         userID,
+		RequestedBy:    requestedBy,
+		Status:         DeletionRunning,
+		CompletedSteps: []string{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+// NextStep returns the first step not completed yet, or an empty string if they all are.
+func (d *Deletion) NextStep() string {
+	if len(d.CompletedSteps) < len(deletionSteps) {
+		return deletionSteps[len(d.CompletedSteps)]
+	}
+	return ""
+}
+
+// CompleteStep records that the next step succeeded, completing the Deletion after the last one.
+func (d *Deletion) CompleteStep() {
+	if step := d.NextStep(); step != "" {
+		d.CompletedSteps = append(d.CompletedSteps, step)
+	}
+	d.Attempts = 0
+	d.LastError = ""
+	d.UpdatedAt = time.Now()
+
+	if d.NextStep() == "" {
+		d.Status = DeletionCompleted
+		d.CompletedAt = &d.UpdatedAt
+	}
+}
+
+// FailAttempt records a failed attempt of the next step.
+func (d *Deletion) FailAttempt(err error) {
+	d.Attempts++
+	d.LastError = err.Error()
+	d.UpdatedAt = time.Now()
+}
+
+// Fail records that the next step was given up on, until the Deletion is resumed.
+func (d *Deletion) Fail() {
+	d.Status = DeletionFailed
+	d.UpdatedAt = time.Now()
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletion_Steps(t *testing.T) {
+	d := NewDeletion("user123", "admin1")
+
+	assert.Equal(t, DeletionRunning, d.Status)
+	assert.Equal(t, DeleteRatingsStep, d.NextStep())
+
+	d.FailAttempt(errors.New("rating service unavailable"))
+	d.FailAttempt(errors.New("rating service unavailable"))
+	assert.Equal(t, 2, d.Attempts)
+	assert.Equal(t, "rating service unavailable", d.LastError)
+
+	d.Fail()
+	assert.Equal(t, DeletionFailed, d.Status)
+	assert.Equal(t, DeleteRatingsStep, d.NextStep())
+
+	d.CompleteStep()
+	assert.Equal(t, 0, d.Attempts)
+	assert.Empty(t, d.LastError)
+	assert.Equal(t, DeleteUserStep, d.NextStep())
+	assert.Nil(t, d.CompletedAt)
+
+	d.CompleteStep()
+	assert.Equal(t, RevokeSessionsStep, d.NextStep())
+
+	d.CompleteStep()
+	assert.Equal(t, "", d.NextStep())
+	assert.Equal(t, DeletionCompleted, d.Status)
+	assert.Equal(t, []string{DeleteRatingsStep, DeleteUserStep, RevokeSessionsStep}, d.CompletedSteps)
+	assert.NotNil(t, d.CompletedAt)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "strings"
+
+// UserPage is a page of a list of users sorted by username, e.g. the directory or the followers of a user.
+// NextCursor is passed as the cursor to get the next page, and is empty on the last one.
+type UserPage struct {
+	Users      []*PublicProfile `json:"users"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// SearchKeys returns the keys an User is found by in the directory: the username, the name and every word of
+// the name on, all lowercase, so "john doe" is found by "jo", "john d" and "do".
+func SearchKeys(username, name string) []string {
+	keys := []string{strings.ToLower(username)}
+
+	words := strings.Fields(strings.ToLower(name))
+	for i := range words {
+		key := strings.Join(words[i:], " ")
+		if !contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// NormalizeSearch returns the query to search the keys of the directory by prefix with, see SearchKeys.
+func NormalizeSearch(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
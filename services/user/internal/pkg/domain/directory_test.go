@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		fullName string
+		expected []string
+	}{
+		{"single word", "User123", "John", []string{"user123", "john"}},
+		{"every word on", "jdoe", "John  Ronald Doe", []string{"jdoe", "john ronald doe", "ronald doe", "doe"}},
+		{"name equal to username", "john", "John", []string{"john"}},
+		{"no name", "john", "", []string{"john"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SearchKeys(tt.username, tt.fullName))
+		})
+	}
+}
+
+func TestNormalizeSearch(t *testing.T) {
+	assert.Equal(t, "john d", NormalizeSearch("  John   D"))
+	assert.Equal(t, "", NormalizeSearch(" "))
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ErrInvalidVerificationToken is returned when a verification token is malformed, forged or expired.
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// verificationPurpose is signed along with the tokens, so a signature made for anything else is not a valid one.
+const verificationPurpose = "email-verification"
+
+// EmailVerification is what a verification token states: the user it was sent to, at which address set when,
+// and until when it can be confirmed. The address and the time it was set are part of it, so changing it voids
+// the tokens sent before, even if it is changed back to the same address.
+type EmailVerification struct {
+	Username       string    `json:"u"`
+	Email          string    `json:"e"`
+	EmailUpdatedAt time.Time `json:"t"`
+	ExpiresAt      time.Time `json:"x"`
+}
+
+// NewEmailVerification returns the EmailVerification of the current address of an User, valid for ttl.
+func NewEmailVerification(u *User, ttl time.Duration) *EmailVerification {
+	return &EmailVerification{
+		Username:       u.Username,
+		Email:          u.Email,
+		EmailUpdatedAt: u.EmailUpdatedAt,
+		ExpiresAt:      time.Now().Add(ttl).UTC().Truncate(time.Second),
+	}
+}
+
+// Token returns the EmailVerification signed with secret, as sent to the user, see ParseEmailVerification.
+func (v *EmailVerification) Token(secret []byte) string {
+	payload, _ := json.Marshal(v) // it only has strings and a time, which always marshal
+
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + base64.RawURLEncoding.EncodeToString(sign(secret, p))
+}
+
+// ParseEmailVerification returns the EmailVerification a token signed with secret states, see Token.
+// It returns ErrInvalidVerificationToken if the token is not one, is signed with another secret or has expired.
+func ParseEmailVerification(secret []byte, token string) (*EmailVerification, error) {
+	p, s, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || !hmac.Equal(signature, sign(secret, p)) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var v EmailVerification
+	if err := json.Unmarshal(payload, &v); err != nil || v.Username == "" || v.Email == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+	if time.Now().After(v.ExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	return &v, nil
+}
+
+// NormalizeEmail returns an email address as stored, so the same address is always unique, see SetEmail.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// isValidEmail checks if email is a bare address, without a display name nor angle brackets.
+func isValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(verificationPurpose + "." + payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_SetEmail(t *testing.T) {
+	user := NewUser("user123", "password", "John Doe", "")
+
+	user.SetEmail(" John@Example.com ")
+	assert.Equal(t, "john@example.com", user.Email)
+	assert.NoError(t, user.validate(false))
+
+	// the same address stays verified, a new one has to be verified again
+	user.EmailVerified = true
+	user.SetEmail("JOHN@example.com")
+	assert.True(t, user.EmailVerified)
+	updatedAt := user.EmailUpdatedAt
+	user.SetEmail("johnny@example.com")
+	assert.False(t, user.EmailVerified)
+	assert.True(t, user.EmailUpdatedAt.After(updatedAt))
+
+	for _, email := range []string{"john", "John Doe <john@example.com>", "john@example.com, jane@example.com"} {
+		user.Email = email
+		assert.Equal(t, ErrInvalidEmail, user.validate(false), email)
+	}
+
+	user.SetEmail("")
+	assert.NoError(t, user.validate(false))
+}
+
+func TestEmailVerification(t *testing.T) {
+	secret := []byte("secret")
+	user := NewUser("user123", "password", "John Doe", "")
+	user.SetEmail("john@example.com")
+
+	token := NewEmailVerification(user, time.Hour).Token(secret)
+
+	v, err := ParseEmailVerification(secret, token)
+	require.NoError(t, err)
+	assert.Equal(t, "user123", v.Username)
+	assert.Equal(t, "john@example.com", v.Email)
+	assert.True(t, user.EmailUpdatedAt.Equal(v.EmailUpdatedAt))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), v.ExpiresAt, time.Second)
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged := NewEmailVerification(NewUser("admin", "password", "Admin", ""), time.Hour).Token([]byte("other"))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	invalid := map[string]string{
+		"empty":              "",
+		"unsigned":           payload,
+		"other secret":       forged,
+		"other payload":      forgedPayload + "." + signature,
+		"bad signature":      payload + ".!",
+		"expired":            NewEmailVerification(user, -time.Second).Token(secret),
+		"without an address": NewEmailVerification(NewUser("user123", "password", "John Doe", ""), time.Hour).Token(secret),
+	}
+	for name, token := range invalid {
+		_, err := ParseEmailVerification(secret, token)
+		assert.Equal(t, ErrInvalidVerificationToken, err, name)
+	}
+}
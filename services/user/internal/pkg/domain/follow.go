@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Follow errors.
+var (
+	ErrSelfFollow       = errors.New("users can't follow themselves")
+	ErrAlreadyFollowing = errors.New("user is already followed")
+	ErrNotFollowing     = errors.New("user is not followed")
+)
+
+// Follow is an edge of the follow graph, from the follower to the followed user.
+type Follow struct {
+	FollowerID string    `json:"followerId" bson:"followerId"`
+	FolloweeID string    `json:"followeeId" bson:"followeeId"`
+	CreatedAt  time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// NewFollow returns an instance of the Follow entity. It returns ErrSelfFollow if both users are the same.
+func NewFollow(followerID, followeeID string) (*Follow, error) {
+	if followerID == followeeID {
+		return nil, ErrSelfFollow
+	}
+
+	return &Follow{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		CreatedAt:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFollow(t *testing.T) {
+	f, err := NewFollow("alice", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", f.FollowerID)
+	assert.Equal(t, "bob", f.FolloweeID)
+	assert.WithinDuration(t, time.Now(), f.CreatedAt, time.Second)
+
+	f, err = NewFollow("alice", "alice")
+	assert.Equal(t, ErrSelfFollow, err)
+	assert.Nil(t, f)
+}
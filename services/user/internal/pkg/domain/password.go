@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"regexp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// passwordCost is the bcrypt cost of the password hashes.
+	passwordCost = 12
+	// minPasswordLength is the minimum number of bytes of a password.
+	minPasswordLength = 8
+	// maxPasswordLength is the maximum number of bytes of a password, bcrypt ignores the ones after it.
+	maxPasswordLength = 72
+)
+
+// dummyPasswordHash is a bcrypt hash of passwordCost no password is checked against in earnest,
+// see VerifyDummyPassword.
+const dummyPasswordHash = "$2a$12$T6HEOKq9G1qWAbis22S6/uhUp5.1iPr8sIYsC5gbONvh.yM5aWtqW"
+
+// ErrPasswordChanged is returned when a password hash is replaced after another one was set meanwhile.
+var ErrPasswordChanged = errors.New("password was changed meanwhile")
+
+// legacyPasswordHash matches the unsalted MD5 digests passwords used to be stored as.
+var legacyPasswordHash = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ValidatePassword checks if a plaintext password is acceptable for a new User.
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return errors.New("password must have from 8 to 72 bytes")
+	}
+	return nil
+}
+
+// HashPassword hashes a plaintext password with bcrypt.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// VerifyPassword checks a plaintext password against a stored hash, which may be a legacy MD5 digest.
+// It also tells whether the hash is outdated and should be replaced by a fresh one, which is only
+// meaningful if the password matches.
+func VerifyPassword(hash, password string) (match, rehash bool) {
+	if legacyPasswordHash.MatchString(hash) {
+		sum := md5.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(hash)) == 1, true
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || cost < passwordCost
+}
+
+// VerifyDummyPassword takes as long as checking a password with VerifyPassword, and never matches.
+// It is checked instead when there is no User to check against, so the time taken doesn't tell whether they exist.
+func VerifyDummyPassword(password string) {
+	VerifyPassword(dummyPasswordHash, password)
+}
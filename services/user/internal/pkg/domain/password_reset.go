@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// resetTokenBytes is the number of random bytes of a password reset token.
+const resetTokenBytes = 32
+
+// ErrInvalidResetToken is returned when a password reset token doesn't exist, was used or has expired.
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordReset lets a user who forgot their password set a new one, once and until it expires.
+// Only the hash of its token is stored, so the stored resets can't be used by whoever reads them.
+type PasswordReset struct {
+	UserID    string    `json:"userId" bson:"userId"`
+	TokenHash string    `json:"-" bson:"tokenHash"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// NewPasswordReset returns a PasswordReset of an User valid for ttl, along with its token, which is only sent
+// to the user.
+func NewPasswordReset(userID string, ttl time.Duration) (*PasswordReset, string, error) {
+	b := make([]byte, resetTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	return &PasswordReset{
+		UserID:    userID,
+		TokenHash: HashResetToken(token),
+		// the database stores milliseconds
+		ExpiresAt: now.Add(ttl).UTC().Truncate(time.Millisecond),
+		CreatedAt: now.UTC().Truncate(time.Millisecond),
+	}, token, nil
+}
+
+// HashResetToken returns the hash a password reset token is stored and looked up by.
+// The tokens are random, so a fast unsalted hash is enough.
+func HashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
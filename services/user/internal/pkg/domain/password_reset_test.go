@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPasswordReset(t *testing.T) {
+	reset, token, err := NewPasswordReset("user123", time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, "user123", reset.UserID)
+	assert.Len(t, token, 43) // 32 bytes, base64 encoded
+	assert.Equal(t, HashResetToken(token), reset.TokenHash)
+	assert.NotContains(t, reset.TokenHash, token)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), reset.ExpiresAt, time.Second)
+
+	other, otherToken, err := NewPasswordReset("user123", time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, otherToken)
+	assert.NotEqual(t, reset.TokenHash, other.TokenHash)
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		wantError bool
+	}{
+		{name: "valid password", password: "correct horse", wantError: false},
+		{name: "too short", password: "short", wantError: true},
+		{name: "too long", password: strings.Repeat("a", 73), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password)
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "correct horse", hash)
+
+	match, rehash := VerifyPassword(hash, "correct horse")
+	assert.True(t, match)
+	assert.False(t, rehash)
+
+	match, _ = VerifyPassword(hash, "wrong horse")
+	assert.False(t, match)
+}
+
+func TestVerifyPassword_Outdated(t *testing.T) {
+	sum := md5.Sum([]byte("correct horse"))
+	legacy := hex.EncodeToString(sum[:])
+
+	match, rehash := VerifyPassword(legacy, "correct horse")
+	assert.True(t, match)
+	assert.True(t, rehash)
+
+	match, _ = VerifyPassword(legacy, "wrong horse")
+	assert.False(t, match)
+
+	// hashes of a lower cost are replaced too
+	cheap, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	match, rehash = VerifyPassword(string(cheap), "correct horse")
+	assert.True(t, match)
+	assert.True(t, rehash)
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	// checking against it must cost as much as against a fresh hash
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	assert.NoError(t, err)
+	assert.Equal(t, passwordCost, cost)
+}
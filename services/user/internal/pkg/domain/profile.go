@@ -0,0 +1,94 @@
+package domain
+
+import "time"
+
+// Password hash schemes, as shown in the AdminProfile.
+const (
+	bcryptScheme = "bcrypt"
+	md5Scheme    = "md5"
+)
+
+// PublicProfile is the representation of an User shown to other users.
+type PublicProfile struct {
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	Picture   string    `json:"picture"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PrivateProfile is the representation of an User shown to the user themselves, without any secret.
+type PrivateProfile struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Name          string    `json:"name"`
+	Picture       string    `json:"picture"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"emailVerified"`
+	Level         string    `json:"level"`
+	Unlisted      bool      `json:"unlisted"`
+	Followers     int       `json:"followers"`
+	Following     int       `json:"following"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// AdminProfile is the representation of an User shown to admins for auditing.
+// It tells how the password is stored, e.g. to follow the migration off MD5, but never the hash itself.
+type AdminProfile struct {
+	PrivateProfile
+	Disabled       bool   `json:"disabled"`
+	PasswordScheme string `json:"passwordScheme"`
+}
+
+// AdminPage is a page of a list of users sorted by username, as shown to admins.
+// NextCursor is passed as the cursor to get the next page, and is empty on the last one.
+type AdminPage struct {
+	Users      []*AdminProfile `json:"users"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// Public returns the PublicProfile of the User.
+func (u *User) Public() *PublicProfile {
+	return &PublicProfile{
+		Username:  u.Username,
+		Name:      u.Name,
+		Picture:   u.Picture,
+		Followers: u.Followers,
+		Following: u.Following,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+// Private returns the PrivateProfile of the User.
+func (u *User) Private() *PrivateProfile {
+	return &PrivateProfile{
+		ID:            u.ID,
+		Username:      u.Username,
+		Name:          u.Name,
+		Picture:       u.Picture,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Level:         u.Level,
+		Unlisted:      u.Unlisted,
+		Followers:     u.Followers,
+		Following:     u.Following,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+// Admin returns the AdminProfile of the User.
+func (u *User) Admin() *AdminProfile {
+	scheme := bcryptScheme
+	if legacyPasswordHash.MatchString(u.Password) {
+		scheme = md5Scheme
+	}
+
+	return &AdminProfile{
+		PrivateProfile: *u.Private(),
+		Disabled:       u.Disabled,
+		PasswordScheme: scheme,
+	}
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_Profiles(t *testing.T) {
+	user := NewUser("user123", "$2a$12$hash", "John Doe", "http://example.com/picture.jpg")
+	user.Followers, user.Following = 3, 1
+	user.SetEmail("john@example.com")
+
+	public := user.Public()
+	assert.Equal(t, "user123", public.Username)
+	assert.Equal(t, "John Doe", public.Name)
+	assert.Equal(t, user.CreatedAt, public.CreatedAt)
+	assert.Equal(t, 3, public.Followers)
+	assert.Equal(t, 1, public.Following)
+
+	private := user.Private()
+	assert.Equal(t, "user123", private.ID)
+	assert.Equal(t, userLevel, private.Level)
+	assert.Equal(t, "john@example.com", private.Email)
+	assert.False(t, private.EmailVerified)
+	assert.Equal(t, user.UpdatedAt, private.UpdatedAt)
+
+	assert.Equal(t, *private, user.Admin().PrivateProfile)
+	assert.Equal(t, "bcrypt", user.Admin().PasswordScheme)
+	assert.False(t, user.Admin().Disabled)
+
+	user.Password = "5f4dcc3b5aa765d61d8327deb882cf99"
+	assert.Equal(t, "md5", user.Admin().PasswordScheme)
+}
@@ -1,15 +1,92 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Repository is the interface for the domain's repository (e.g. some database).
 type Repository interface {
 	// Create receives a validated input and creates a new User.
+	// It returns ErrEmailNotAvailable if another User has the email address.
 	Create(ctx context.Context, user *ValidatedUser) (*User, error)
-	// FindById retrieves an User by a given unique ID.
+	// FindByID retrieves an User by a given unique ID. It returns ErrUserNotFound if there is none.
 	FindByID(ctx context.Context, id string) (*User, error)
-	// FindById retrieves an User by a given unique ID.
-	FindByCredentials(ctx context.Context, id string, password string) (*User, error)
+	// Update receives a validated input and replaces the profile of an User (name, picture, email address and
+	// directory listing), bumping its update time.
+	// It returns ErrUserNotFound if there is none, and ErrEmailNotAvailable if another User has the email address.
+	Update(ctx context.Context, user *ValidatedUser) (*User, error)
+	// Search retrieves up to limit listed and enabled Users sorted by username, whose SearchKeys start with the
+	// normalized query (see NormalizeSearch), all of them if it is empty.
+	// Only the usernames after cursor are retrieved, if given.
+	Search(ctx context.Context, query, cursor string, limit int) ([]*User, error)
+	// FindByEmail retrieves the User of an email address, see NormalizeEmail. It returns ErrUserNotFound if there is none.
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	// VerifyEmail marks the email address of an User as verified, as long as it is still the current one and was
+	// set at emailUpdatedAt. It returns ErrUserNotFound if there is no such User.
+	VerifyEmail(ctx context.Context, id, email string, emailUpdatedAt time.Time) (*User, error)
+	// UpdateAccess replaces the level of an User, as validated by SetLevel, and whether it is disabled, bumping its
+	// update time. The rest of the User is not validated again, so access can be changed whatever its profile.
+	// It returns ErrUserNotFound if there is none, and ErrLastAdmin if no admin would be left, even when the other
+	// ones are changed at the same time.
+	UpdateAccess(ctx context.Context, user *User) (*User, error)
+	// ListByLevel retrieves up to limit Users of a level sorted by username, disabled and unlisted ones included.
+	// Only the usernames after cursor are retrieved, if given.
+	ListByLevel(ctx context.Context, level, cursor string, limit int) ([]*User, error)
+	// UpdatePassword replaces the password hash of an User, as long as it is still the current one.
+	// It returns ErrPasswordChanged otherwise.
+	UpdatePassword(ctx context.Context, id, current, hash string) error
+	// SavePasswordReset stores a PasswordReset, replacing the former one of its User, so only the last one is valid.
+	SavePasswordReset(ctx context.Context, reset *PasswordReset) error
+	// FindPasswordReset retrieves the PasswordReset of a token hash, see HashResetToken.
+	// It returns ErrInvalidResetToken if there is none or it has expired.
+	FindPasswordReset(ctx context.Context, tokenHash string) (*PasswordReset, error)
+	// DeletePasswordReset removes the PasswordReset of a token hash, once used.
+	DeletePasswordReset(ctx context.Context, tokenHash string) error
+	// Delete removes an User along with their follows, timeline and password reset, updating the counts of the users
+	// on the other end.
+	// It returns ErrUserNotFound if there is none.
+	Delete(ctx context.Context, id string) error
+	// Follow stores a Follow and counts it on both users. It returns ErrAlreadyFollowing if it already exists.
+	Follow(ctx context.Context, follow *Follow) error
+	// Unfollow removes the Follow between two users and uncounts it, along with the activities of the followee in the
+	// follower's timeline. It returns ErrNotFollowing if there is none.
+	Unfollow(ctx context.Context, followerID, followeeID string) error
+	// Followers retrieves up to limit Users following an User, sorted by username.
+	// Only the usernames after cursor are retrieved, if given.
+	Followers(ctx context.Context, id, cursor string, limit int) ([]*User, error)
+	// Following retrieves up to limit Users an User follows, sorted by username.
+	// Only the usernames after cursor are retrieved, if given.
+	Following(ctx context.Context, id, cursor string, limit int) ([]*User, error)
+	// FollowerIDs retrieves up to limit IDs of the Users following an User, sorted.
+	// Only the IDs after cursor are retrieved, if given.
+	FollowerIDs(ctx context.Context, id, cursor string, limit int) ([]string, error)
+	// FollowedCelebrities retrieves the IDs of the Users an User follows having at least minFollowers followers.
+	FollowedCelebrities(ctx context.Context, id string, minFollowers int) ([]string, error)
+	// SaveActivity stores the state of an Activity, unless a newer one is already stored. It returns whether it was.
+	SaveActivity(ctx context.Context, activity *Activity) (bool, error)
+	// DeleteActivity removes an Activity, from the timelines too, unless its state is newer than deletedAt.
+	DeleteActivity(ctx context.Context, id string, deletedAt time.Time) error
+	// Activities retrieves up to limit Activities of the given Users, newest first.
+	// Only the ones after cursor are retrieved, if given.
+	Activities(ctx context.Context, userIDs []string, cursor *FeedCursor, limit int) ([]*Activity, error)
+	// FanOut stores the state of an Activity in the timelines of the given Users, unless they have a newer one.
+	FanOut(ctx context.Context, activity *Activity, ownerIDs []string) error
+	// Timeline retrieves up to limit Activities of the timeline of an User, newest first.
+	// Only the ones after cursor are retrieved, if given.
+	Timeline(ctx context.Context, ownerID string, cursor *FeedCursor, limit int) ([]*Activity, error)
+	// StartDeletion stores a new Deletion, or resumes the one of the same user if it failed or its run was interrupted,
+	// locking it to a new run for the lease duration. A completed Deletion is returned as is.
+	// It returns ErrDeletionInProgress along with the Deletion if another run has it locked.
+	StartDeletion(ctx context.Context, deletion *Deletion, lease time.Duration) (*Deletion, error)
+	// SaveDeletion stores the progress of a Deletion, including its lock.
+	// It returns ErrDeletionInProgress if another run locked the Deletion meanwhile, so nothing is stored.
+	SaveDeletion(ctx context.Context, deletion *Deletion) error
+	// FindDeletion retrieves the Deletion of a user. It returns ErrDeletionNotFound if there is none.
+	FindDeletion(ctx context.Context, userID string) (*Deletion, error)
+	// FindStaleDeletions retrieves up to limit Deletions neither completed nor locked by a run, i.e. the failed
+	// and interrupted ones, least recently updated first.
+	FindStaleDeletions(ctx context.Context, limit int) ([]*Deletion, error)
 	// Close disconnects the database connection pool.
 	Close(ctx context.Context) error
 }
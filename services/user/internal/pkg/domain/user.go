@@ -5,39 +5,127 @@ import (
 	"time"
 
 	"github.com/victorspringer/backend-coding-challenge/lib/image"
+	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
 )
 
 // User entity.
+// It is never sent as is, see PublicProfile, PrivateProfile and AdminProfile.
 type User struct {
 	ID        string    `json:"id" bson:"id"`
 	Username  string    `json:"username" bson:"username"`
-	Password  string    `json:"password" bson:"password"`
+	Password  string    `json:"-" bson:"password"`
 	Name      string    `json:"name" bson:"name"`
 	Picture   string    `json:"picture" bson:"picture"`
 	Level     string    `json:"level" bson:"level"`
+	Unlisted  bool      `json:"unlisted" bson:"unlisted"` // left out of the directory, see Repository.Search
+	Disabled  bool      `json:"disabled" bson:"disabled"` // can't log in, set by admins
+	Followers int       `json:"followers" bson:"followers"`
+	Following int       `json:"following" bson:"following"`
 	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	// SearchKeys are the lowercase username, name and words of the name the directory is searched by prefix on.
+	SearchKeys []string `json:"-" bson:"searchKeys"`
+	// Email is optional and unique among users, see SetEmail.
+	// EmailVerified tells if the user confirmed they own it, see EmailVerification.
+	// EmailUpdatedAt is when it was last changed, which voids the verification tokens sent before.
+	Email          string    `json:"email" bson:"email"`
+	EmailVerified  bool      `json:"emailVerified" bson:"emailVerified"`
+	EmailUpdatedAt time.Time `json:"-" bson:"emailUpdatedAt"`
 }
 
+// User errors.
+var (
+	ErrUserNotFound = errors.New("user doesn't exist")
+	ErrInvalidLevel = errors.New("invalid user level")
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailNotAvailable is returned when storing an User with the email address of another one.
+	ErrEmailNotAvailable = errors.New("email address is not available")
+	// ErrLastAdmin is returned when changing the access of the only admin left who can log in.
+	ErrLastAdmin = errors.New("the last admin can't be demoted nor disabled")
+)
+
+// User levels, the access levels of the authentication service but the anonymous one, which users have without an account.
 const (
-	adminLevel = "admin" // not used yet
-	userLevel  = "user"
+	adminLevel = authClient.AdminLevel
+	userLevel  = authClient.UserLevel
 )
 
 // NewUser returns an instance of the User entity.
+// password is the hash of the user's password, see HashPassword.
 func NewUser(username, password, name, picture string) *User {
 	return &User{
-		ID:        username, // id and username are the same, as the username is unique
-		Username:  username,
-		Password:  password,
-		Name:      name,
-		Picture:   picture,
-		Level:     userLevel,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         username, // id and username are the same, as the username is unique
+		Username:   username,
+		Password:   password,
+		Name:       name,
+		Picture:    picture,
+		Level:      userLevel,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		SearchKeys: SearchKeys(username, name),
 	}
 }
 
+// UpdateProfile changes the name, picture and directory listing of the User, when given, and bumps its update time.
+// The result must be validated again, see NewValidatedUser.
+func (u *User) UpdateProfile(name, picture *string, unlisted *bool) {
+	if name != nil {
+		u.Name = *name
+		u.SearchKeys = SearchKeys(u.Username, u.Name)
+	}
+	if picture != nil {
+		u.Picture = *picture
+	}
+	if unlisted != nil {
+		u.Unlisted = *unlisted
+	}
+	u.UpdatedAt = time.Now()
+}
+
+// SetEmail changes the email address of the User, normalized, and bumps its update time.
+// A new address has to be verified again, see EmailVerification. An empty one removes it.
+// The result must be validated again, see NewValidatedUser.
+func (u *User) SetEmail(email string) {
+	email = NormalizeEmail(email)
+	if email != u.Email {
+		u.Email = email
+		u.EmailVerified = false
+		// the database stores milliseconds, and the tokens tell the changes apart by it
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		if !now.After(u.EmailUpdatedAt) {
+			now = u.EmailUpdatedAt.Add(time.Millisecond)
+		}
+		u.EmailUpdatedAt = now
+	}
+	u.UpdatedAt = time.Now()
+}
+
+// SetLevel changes the level of the User, and bumps its update time.
+// It returns ErrInvalidLevel if level is not a level of users.
+func (u *User) SetLevel(level string) error {
+	if !isValidLevel(level) {
+		return ErrInvalidLevel
+	}
+	u.Level = level
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetDisabled disables or enables the User, and bumps its update time.
+func (u *User) SetDisabled(disabled bool) {
+	u.Disabled = disabled
+	u.UpdatedAt = time.Now()
+}
+
+// IsAdmin checks if the User is an admin who can log in.
+func (u *User) IsAdmin() bool {
+	return u.Level == adminLevel && !u.Disabled
+}
+
+func isValidLevel(level string) bool {
+	return level == adminLevel || level == userLevel
+}
+
 func (u *User) validate(validateImageContent ...bool) error {
 	vc := true
 	if len(validateImageContent) > 0 {
@@ -59,12 +147,15 @@ func (u *User) validate(validateImageContent ...bool) error {
 	if u.Name == "" {
 		return errors.New("name is required")
 	}
-	if u.Level != userLevel {
-		return errors.New("invalid user level")
+	if !isValidLevel(u.Level) {
+		return ErrInvalidLevel
 	}
 	if u.CreatedAt.After(u.UpdatedAt) {
 		return errors.New("created_at must be before updated_at")
 	}
+	if u.Email != "" && !isValidEmail(u.Email) {
+		return ErrInvalidEmail
+	}
 	if u.Picture != "" && !image.IsValidSource(u.Picture, vc) {
 		return errors.New("provided picture image source is invalid or too slow to load")
 	}
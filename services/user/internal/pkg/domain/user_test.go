@@ -27,6 +27,44 @@ func TestNewUser(t *testing.T) {
 	assert.WithinDuration(t, time.Now(), user.UpdatedAt, time.Second)
 }
 
+func TestUser_UpdateProfile(t *testing.T) {
+	user := NewUser("user123", "password", "John Doe", "http://example.com/picture.jpg")
+	updatedAt := user.UpdatedAt
+
+	name := "Johnny"
+	user.UpdateProfile(&name, nil, nil)
+	assert.Equal(t, "Johnny", user.Name)
+	assert.Equal(t, "http://example.com/picture.jpg", user.Picture)
+	assert.Equal(t, []string{"user123", "johnny"}, user.SearchKeys)
+	assert.False(t, user.Unlisted)
+	assert.False(t, user.UpdatedAt.Before(updatedAt))
+
+	picture := ""
+	unlisted := true
+	user.UpdateProfile(nil, &picture, &unlisted)
+	assert.Equal(t, "Johnny", user.Name)
+	assert.Empty(t, user.Picture)
+	assert.True(t, user.Unlisted)
+}
+
+func TestUser_SetLevel(t *testing.T) {
+	user := NewUser("user123", "password", "John Doe", "")
+	updatedAt := user.UpdatedAt
+
+	assert.NoError(t, user.SetLevel("admin"))
+	assert.Equal(t, adminLevel, user.Level)
+	assert.False(t, user.UpdatedAt.Before(updatedAt))
+
+	assert.Equal(t, ErrInvalidLevel, user.SetLevel("anonymous"))
+	assert.Equal(t, ErrInvalidLevel, user.SetLevel("root"))
+	assert.Equal(t, adminLevel, user.Level)
+	assert.True(t, user.IsAdmin())
+
+	user.SetDisabled(true)
+	assert.True(t, user.Disabled)
+	assert.False(t, user.IsAdmin())
+}
+
 func TestUser_Validate(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -169,7 +207,7 @@ func TestUser_Validate(t *testing.T) {
 			expectedError: errors.New("invalid user level"),
 		},
 		{
-			name: "admin Level", // not used yet, thus invalid
+			name: "admin Level",
 			user: &User{
 				ID:        "user123",
 				Username:  "user123",
@@ -180,6 +218,20 @@ func TestUser_Validate(t *testing.T) {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			},
+			expectedError: nil,
+		},
+		{
+			name: "anonymous Level", // only for tokens generated without an account
+			user: &User{
+				ID:        "user123",
+				Username:  "user123",
+				Password:  "password",
+				Name:      "John Doe",
+				Picture:   "http://example.com/picture.jpg",
+				Level:     "anonymous",
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
 			expectedError: errors.New("invalid user level"),
 		},
 	}
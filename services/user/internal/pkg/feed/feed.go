@@ -0,0 +1,211 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+)
+
+// retryInterval is how long the feed waits before consuming the stream again after a failure.
+const retryInterval = time.Second
+
+// ErrInvalidOffset is returned when moving the feeds to an offset which is not one of the stream.
+var ErrInvalidOffset = errors.New("offset must be 0, $ or a stream entry ID")
+
+// Source delivers the rating events to a consumer group, see events.Consumer.
+type Source interface {
+	CreateGroup(ctx context.Context, offset string) error
+	SetOffset(ctx context.Context, offset string) error
+	Consume(ctx context.Context, handler events.Handler) error
+}
+
+// Feed builds the feeds of the users out of the ratings of the users they follow.
+// The activities of most users are fanned out on write to the timelines of their followers,
+// while the ones of users with at least celebrityFollowers followers are only read along with the timelines,
+// so a single rating doesn't write to a huge number of timelines.
+type Feed struct {
+	repository         domain.Repository
+	source             Source
+	logger             *log.Logger
+	celebrityFollowers int
+	batchSize          int
+}
+
+// NewFeed returns a new instance of Feed.
+// batchSize is the number of timelines an activity is fanned out to at once.
+func NewFeed(repo domain.Repository, source Source, logger *log.Logger, celebrityFollowers, batchSize int) *Feed {
+	return &Feed{
+		repository:         repo,
+		source:             source,
+		logger:             logger,
+		celebrityFollowers: celebrityFollowers,
+		batchSize:          batchSize,
+	}
+}
+
+// Run consumes the rating events until ctx is done. The feeds start with the events published once it first runs.
+func (f *Feed) Run(ctx context.Context) {
+	for {
+		err := f.source.CreateGroup(ctx, events.OffsetNewest)
+		if err == nil {
+			err = f.source.Consume(ctx, f.handle)
+		}
+		if err == nil {
+			return
+		}
+
+		// the events left pending are delivered again once consuming again
+		f.logger.Error("failed to consume rating events", log.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// SetOffset moves the feeds to an offset of the stream, e.g. to handle again the events set aside in the
+// dead-letter stream once the cause is fixed. The events after it are delivered again, which is harmless.
+func (f *Feed) SetOffset(ctx context.Context, offset string) error {
+	if offset != events.OffsetNewest && !events.IsValidID(offset) {
+		return ErrInvalidOffset
+	}
+
+	return f.source.SetOffset(ctx, offset)
+}
+
+// Page returns up to limit activities of the feed of an User, newest first, after cursor if given.
+func (f *Feed) Page(ctx context.Context, userID string, cursor *domain.FeedCursor, limit int) (*domain.FeedPage, error) {
+	// one more activity than asked tells if there is a next page
+	timeline, err := f.repository.Timeline(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	celebrities, err := f.repository.FollowedCelebrities(ctx, userID, f.celebrityFollowers)
+	if err != nil {
+		return nil, err
+	}
+
+	read, err := f.repository.Activities(ctx, celebrities, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	activities := merge(timeline, read)
+
+	page := &domain.FeedPage{Activities: activities}
+	if len(activities) > limit {
+		page.Activities = activities[:limit]
+		page.NextCursor = domain.CursorOf(activities[limit-1]).String()
+	}
+
+	return page, nil
+}
+
+// handle applies a rating event to the feeds. Every state of an activity is stored at most once, in order,
+// so events delivered again or late are harmless.
+func (f *Feed) handle(ctx context.Context, m *events.Message) error {
+	switch {
+	case m.Event.Type == events.RatingDeleted, m.Event.Rating.Held:
+		// held ratings leave the feeds until they are approved
+		return f.repository.DeleteActivity(ctx, m.Event.Rating.ID, m.Event.OccurredAt)
+	case m.Event.Type == events.RatingCreated || m.Event.Type == events.RatingUpdated:
+		a := activityOf(&m.Event)
+
+		stored, err := f.repository.SaveActivity(ctx, a)
+		if err != nil || !stored {
+			return err
+		}
+
+		u, err := f.repository.FindByID(ctx, a.UserID)
+		if err != nil {
+			if err == domain.ErrUserNotFound {
+				// deleted meanwhile, the ratings' deletion events remove the activity
+				return nil
+			}
+			return err
+		}
+		if u.Followers >= f.celebrityFollowers {
+			return nil
+		}
+
+		return f.fanOut(ctx, a)
+	default:
+		return nil
+	}
+}
+
+// fanOut stores an activity in the timelines of all the followers of its user.
+func (f *Feed) fanOut(ctx context.Context, a *domain.Activity) error {
+	cursor := ""
+	for {
+		ids, err := f.repository.FollowerIDs(ctx, a.UserID, cursor, f.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if err := f.repository.FanOut(ctx, a, ids); err != nil {
+			return err
+		}
+
+		if len(ids) < f.batchSize {
+			return nil
+		}
+		cursor = ids[len(ids)-1]
+	}
+}
+
+// activityOf returns the Activity a rating event leaves in the feeds.
+func activityOf(e *events.Event) *domain.Activity {
+	a := &domain.Activity{
+		ID:      e.Rating.ID,
+		UserID:  e.Rating.UserID,
+		Type:    domain.RatedActivity,
+		MovieID: e.Rating.MovieID,
+		Value:   e.Rating.Value,
+		// the database stores milliseconds, which the cursors must match
+		OccurredAt: e.OccurredAt.UTC().Truncate(time.Millisecond),
+	}
+
+	if e.Rating.Review != nil {
+		a.Type = domain.ReviewedActivity
+		a.ReviewTitle = e.Rating.Review.Title
+		a.Spoiler = e.Rating.Review.Spoiler
+	}
+
+	return a
+}
+
+// merge returns the activities of both lists newest first, keeping the newest state of the ones in both
+// (e.g. fanned out before their user had enough followers to be read along with the timelines).
+func merge(a, b []*domain.Activity) []*domain.Activity {
+	newest := make(map[string]*domain.Activity, len(a)+len(b))
+	for _, list := range [][]*domain.Activity{a, b} {
+		for _, activity := range list {
+			if n, ok := newest[activity.ID]; !ok || activity.OccurredAt.After(n.OccurredAt) {
+				newest[activity.ID] = activity
+			}
+		}
+	}
+
+	merged := make([]*domain.Activity, 0, len(newest))
+	for _, activity := range newest {
+		merged = append(merged, activity)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].OccurredAt.Equal(merged[j].OccurredAt) {
+			return merged[i].ID > merged[j].ID
+		}
+		return merged[i].OccurredAt.After(merged[j].OccurredAt)
+	})
+
+	return merged
+}
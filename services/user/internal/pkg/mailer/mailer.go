@@ -0,0 +1,162 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+)
+
+// ErrInvalidHeader is returned when a message's recipient or subject would break its headers.
+var ErrInvalidHeader = errors.New("invalid message header")
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to the users.
+type Mailer interface {
+	Send(ctx context.Context, m *Message) error
+}
+
+// SMTP is a Mailer sending through an SMTP server, authenticating if given a username.
+type SMTP struct {
+	addr   string
+	from   string
+	sender string // the address of from, which the server is given as the sender
+	auth   smtp.Auth
+}
+
+// NewSMTP returns a new instance of SMTP. addr is the host:port of the server,
+// and from the sender of the messages, with or without a name, e.g. "Movie Rating System <no-reply@example.com>".
+func NewSMTP(addr, username, password, from string) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMTP{addr: addr, from: from, sender: sender.Address}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s, nil
+}
+
+// Send implements Mailer interface's Send method.
+// The connection is not bound to ctx, which is only checked before sending.
+func (s *SMTP) Send(ctx context.Context, m *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b, err := format(s.from, m)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(s.addr, s.auth, s.sender, []string{m.To}, b)
+}
+
+// File is a Mailer appending the messages to a file instead of sending them, for development and tests.
+type File struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+// NewFile returns a new instance of File.
+func NewFile(path, from string) *File {
+	return &File{path: path, from: from}
+}
+
+// Send implements Mailer interface's Send method.
+func (f *File) Send(ctx context.Context, m *Message) error {
+	b, err := format(f.from, m)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(b, "\r\n"...)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Log is a Mailer logging the messages instead of sending them, for development.
+// The messages carry tokens, so it must not be used in production.
+type Log struct {
+	logger *log.Logger
+}
+
+// NewLog returns a new instance of Log.
+func NewLog(logger *log.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+// Send implements Mailer interface's Send method.
+func (l *Log) Send(ctx context.Context, m *Message) error {
+	if !isValidHeader(m.To) || !isValidHeader(m.Subject) {
+		return ErrInvalidHeader
+	}
+
+	l.logger.Info(
+		"email not sent, logged instead",
+		log.String("to", m.To),
+		log.String("subject", m.Subject),
+		log.String("body", m.Body),
+	)
+
+	return nil
+}
+
+// format returns a Message as sent over SMTP.
+func format(from string, m *Message) ([]byte, error) {
+	if !isValidHeader(from) || !isValidHeader(m.To) || !isValidHeader(m.Subject) {
+		return nil, ErrInvalidHeader
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return []byte(b.String()), nil
+}
+
+// isValidHeader checks if a header value is on a single line, so it can't add headers of its own.
+func isValidHeader(value string) bool {
+	return value != "" && !strings.ContainsAny(value, "\r\n")
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	f := NewFile(path, "Movie Rating System <no-reply@example.com>")
+
+	require.NoError(t, f.Send(context.Background(), &Message{To: "john@example.com", Subject: "Hi", Body: "first\nsecond"}))
+	require.NoError(t, f.Send(context.Background(), &Message{To: "jane@example.com", Subject: "Hi", Body: "third"}))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	mails := string(b)
+
+	assert.Contains(t, mails, "From: Movie Rating System <no-reply@example.com>\r\nTo: john@example.com\r\nSubject: Hi\r\n")
+	assert.Contains(t, mails, "\r\n\r\nfirst\r\nsecond\r\n")
+	assert.Contains(t, mails, "To: jane@example.com\r\n")
+	assert.Equal(t, 2, strings.Count(mails, "MIME-Version: 1.0\r\n"))
+}
+
+func TestSend_InvalidHeader(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "mail.log"), "no-reply@example.com")
+
+	for _, m := range []*Message{
+		{To: "", Subject: "Hi"},
+		{To: "john@example.com\r\nBcc: jane@example.com", Subject: "Hi"},
+		{To: "john@example.com", Subject: "Hi\nBcc: jane@example.com"},
+	} {
+		assert.Equal(t, ErrInvalidHeader, f.Send(context.Background(), m))
+	}
+}
+
+func TestNewSMTP(t *testing.T) {
+	s, err := NewSMTP("smtp.example.com:587", "user", "password", "Movie Rating System <no-reply@example.com>")
+	require.NoError(t, err)
+	assert.Equal(t, "no-reply@example.com", s.sender)
+	assert.NotNil(t, s.auth)
+
+	_, err = NewSMTP("smtp.example.com", "", "", "no-reply@example.com")
+	assert.Error(t, err)
+	_, err = NewSMTP("smtp.example.com:587", "", "", "no reply")
+	assert.Error(t, err)
+}
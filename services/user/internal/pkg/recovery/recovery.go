@@ -0,0 +1,178 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/mailer"
+)
+
+// AuthenticationService revokes the sessions of a user on behalf of this service, see authClient.Client.
+type AuthenticationService interface {
+	RevokeUserAsService(ctx context.Context, username, serviceKey string) error
+}
+
+// Recovery errors.
+var (
+	// ErrSessionsNotRevoked is returned when the password was reset but the former sessions are still valid.
+	// The reset can be done again with the same token.
+	ErrSessionsNotRevoked = errors.New("password reset, but the former sessions couldn't be revoked, try again")
+	// ErrQueueFull is returned when too many password reset requests are waiting to be sent.
+	ErrQueueFull = errors.New("too many password reset requests in progress, try again later")
+)
+
+// Resetter lets the users who forgot their password set a new one: it emails them a single use token to their
+// verified address, which they send back along with the new password. The emails are sent in the background, so
+// a request takes as long whether or not the address has an account.
+type Resetter struct {
+	repository domain.Repository
+	mailer     mailer.Mailer
+	auth       AuthenticationService
+	logger     *log.Logger
+	serviceKey string
+	ttl        time.Duration
+	link       string
+	queue      chan string
+}
+
+// NewResetter returns a new instance of Resetter.
+// The tokens are valid for ttl, and the sessions are revoked with the service key of the authentication service.
+// link is the URL the token is appended to in the emails, e.g. the page of a client resetting the password,
+// the token is sent alone if it is empty. queueSize is the number of requests that can wait to be sent.
+func NewResetter(
+	repo domain.Repository,
+	m mailer.Mailer,
+	auth AuthenticationService,
+	logger *log.Logger,
+	serviceKey string,
+	ttl time.Duration,
+	link string,
+	queueSize int,
+) *Resetter {
+	return &Resetter{
+		repository: repo,
+		mailer:     m,
+		auth:       auth,
+		logger:     logger,
+		serviceKey: serviceKey,
+		ttl:        ttl,
+		link:       link,
+		queue:      make(chan string, queueSize),
+	}
+}
+
+// Request schedules a password reset token to be emailed to the User of an email address, voiding the ones sent
+// before. Nothing is sent unless the address is verified and the User is enabled, which is not told apart from an
+// unknown address, so the requests don't tell which addresses have accounts.
+func (rs *Resetter) Request(email string) error {
+	select {
+	case rs.queue <- domain.NormalizeEmail(email):
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run sends the requested password reset tokens until ctx is done.
+func (rs *Resetter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case email := <-rs.queue:
+			if err := rs.send(ctx, email); err != nil {
+				rs.logger.Error("failed to send password reset", log.Error(err))
+			}
+		}
+	}
+}
+
+// send emails a password reset token to the User of an email address, if they can reset their password.
+func (rs *Resetter) send(ctx context.Context, email string) error {
+	u, err := rs.repository.FindByEmail(ctx, email)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+	if !u.EmailVerified || u.Disabled {
+		rs.logger.Info("password reset not sent", log.String("username", u.Username))
+		return nil
+	}
+
+	reset, token, err := domain.NewPasswordReset(u.ID, rs.ttl)
+	if err != nil {
+		return err
+	}
+
+	if err := rs.repository.SavePasswordReset(ctx, reset); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nSet a new password for your account %s with the reset token below, valid once for %s.\n\n%s%s\n\n"+
+			"If you didn't ask for it, just ignore this email, your password is unchanged.\n",
+		u.Name,
+		u.Username,
+		rs.ttl,
+		rs.link,
+		token,
+	)
+
+	return rs.mailer.Send(ctx, &mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+}
+
+// Reset sets a new password for the User a token was sent to, and revokes all their sessions.
+// The token is used up once the sessions are revoked, so the reset can be done again if they couldn't be,
+// in which case ErrSessionsNotRevoked is returned. It returns domain.ErrInvalidResetToken if the token is not valid.
+// The password must be validated, see domain.ValidatePassword.
+func (rs *Resetter) Reset(ctx context.Context, token, password string) (*domain.User, error) {
+	tokenHash := domain.HashResetToken(token)
+
+	reset, err := rs.repository.FindPasswordReset(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := rs.repository.FindByID(ctx, reset.UserID)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil, domain.ErrInvalidResetToken
+		}
+		return nil, err
+	}
+
+	hash, err := domain.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// only one of concurrent resets with the same token changes the password, the token is used up for the others
+	if err := rs.repository.UpdatePassword(ctx, u.ID, u.Password, hash); err != nil {
+		if err == domain.ErrPasswordChanged {
+			return nil, domain.ErrInvalidResetToken
+		}
+		return nil, err
+	}
+
+	if err := rs.auth.RevokeUserAsService(ctx, u.Username, rs.serviceKey); err != nil {
+		rs.logger.Error("failed to revoke sessions", log.String("username", u.Username), log.Error(err))
+		return nil, ErrSessionsNotRevoked
+	}
+
+	if err := rs.repository.DeletePasswordReset(ctx, tokenHash); err != nil {
+		return nil, err
+	}
+
+	u.Password = hash
+	return u, nil
+}
@@ -2,27 +2,95 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/victorspringer/backend-coding-challenge/lib/context"
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/deletion"
 	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/feed"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/recovery"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/verification"
+)
+
+var (
+	// errInvalidCredentials is returned whether the user doesn't exist or the password doesn't match,
+	// so the response doesn't tell which usernames exist.
+	errInvalidCredentials   = errors.New("invalid credentials")
+	errUsernameNotAvailable = errors.New("username is not available")
+	errLevelRequired        = errors.New("level is required")
+	errOwnAccess            = errors.New("admins can't change their own access")
+	errSessionsNotRevoked   = errors.New("access changed, but the sessions of the user couldn't be revoked, try again")
+	errEmailNotSent         = errors.New("the verification email couldn't be sent, try again later")
+	errTokenRequired        = errors.New("token is required")
+	errEmailRequired        = errors.New("email is required")
+	errOffsetRequired       = errors.New("offset is required")
 )
 
 func (rt *router) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
 }
 
+// rootHandler serves the directory when it is searched or paged through, and the health check otherwise.
+func (rt *router) rootHandler(w http.ResponseWriter, r *http.Request) {
+	if query := r.URL.Query(); query.Has("q") || query.Has("cursor") {
+		rt.directoryHandler(w, r)
+		return
+	}
+
+	rt.healthCheckHandler(w, r)
+}
+
+// @Summary List users
+// @Description List the users sorted by username, searched by prefix of their username, name or any word of their name, case insensitively. Users who chose to be unlisted are left out. The q or cursor parameter must be given, even empty, as the path alone is the health check
+// @ID list-users
+// @Param q query string true "Prefix to search the users by, empty to list them all"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Number of users per page, from 1 to 100 (default 20)"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.UserPage}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router / [get]
+func (rt *router) directoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// one more user than asked tells if there is a next page
+	users, err := rt.repository.Search(ctx, r.URL.Query().Get("q"), r.URL.Query().Get("cursor"), limit+1)
+	if err != nil {
+		rt.logger.Error("failed to search users", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, userPage(users, limit), http.StatusOK)
+}
+
 // @Summary Get user by username
-// @Description Get user information by username
+// @Description Get user information by username. Other users get the public profile, while the user themselves get the private one (domain.PrivateProfile) and admins the audit one (domain.AdminProfile)
 // @ID get-user-by-username
 // @Param username path string true "Username of the user"
 // @Security ApiKeyAuth
 // @Param Authorization header string true "Insert your access token"
 // @Produce json
-// @Success 200 {object} response{response=domain.User}
+// @Success 200 {object} response{response=domain.PublicProfile}
 // @Failure 401 {object} response
 // @Failure 404 {object} response
 // @Failure 500 {object} response
@@ -44,7 +112,99 @@ func (rt *router) findHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rt.respond(w, r, u, http.StatusOK)
+	rt.respond(w, r, profile(r, u), http.StatusOK)
+}
+
+// @Summary Update user profile
+// @Description Update the name, picture and directory listing of a user, only the given fields are changed and an empty picture removes it. Allowed to the user themselves and admins, who get the audit profile back (domain.AdminProfile)
+// @ID update-user
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Accept json
+// @Produce json
+// @Param user body updatePayload true "Profile fields to be changed"
+// @Success 200 {object} response{response=domain.PrivateProfile}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 409 {object} response
+// @Failure 500 {object} response
+// @Router /{username} [patch]
+func (rt *router) updateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if profileView(r) == publicView {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p updatePayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+
+	u, err := rt.repository.FindByID(ctx, username)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	email := u.Email
+
+	u.UpdateProfile(p.Name, p.Picture, p.Unlisted)
+	if p.Email != nil {
+		u.SetEmail(*p.Email)
+	}
+
+	vu, err := domain.NewValidatedUser(u)
+	if err != nil {
+		rt.logger.Error("invalid user data", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err = rt.repository.Update(ctx, vu)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == domain.ErrEmailNotAvailable {
+			rt.respond(w, r, err.Error(), http.StatusConflict)
+			return
+		}
+		rt.logger.Error("failed to update user", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.evictPublicProfile(u.Username)
+
+	if u.Email != email {
+		rt.sendVerification(r, u)
+	}
+
+	rt.respond(w, r, profile(r, u), http.StatusOK)
 }
 
 // @Summary Create a new user
@@ -53,8 +213,9 @@ func (rt *router) findHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param user body createPayload true "User object to be created"
-// @Success 201 {object} response{response=domain.User}
+// @Success 201 {object} response{response=domain.PrivateProfile}
 // @Failure 400 {object} response
+// @Failure 409 {object} response
 // @Failure 500 {object} response
 // @Router /create [post]
 func (rt *router) createHandler(w http.ResponseWriter, r *http.Request) {
@@ -77,7 +238,31 @@ func (rt *router) createHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := domain.NewUser(p.Username, p.MD5Password, p.Name, p.Picture)
+	if err := domain.ValidatePassword(p.Password); err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := domain.HashPassword(p.Password)
+	if err != nil {
+		rt.logger.Error("failed to hash password", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the usernames of deleted accounts are not given out again, other services may still refer to them
+	if _, err := rt.repository.FindDeletion(ctx, p.Username); err != domain.ErrDeletionNotFound {
+		if err != nil {
+			rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rt.respond(w, r, errUsernameNotAvailable.Error(), http.StatusConflict)
+		return
+	}
+
+	u := domain.NewUser(p.Username, hash, p.Name, p.Picture)
+	u.SetEmail(p.Email)
 
 	vu, err := domain.NewValidatedUser(u)
 	if err != nil {
@@ -88,12 +273,20 @@ func (rt *router) createHandler(w http.ResponseWriter, r *http.Request) {
 
 	u, err = rt.repository.Create(ctx, vu)
 	if err != nil {
+		if err == domain.ErrEmailNotAvailable {
+			rt.respond(w, r, err.Error(), http.StatusConflict)
+			return
+		}
 		rt.logger.Error("failed to create user", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
 		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	rt.respond(w, r, u, http.StatusCreated)
+	if u.Email != "" {
+		rt.sendVerification(r, u)
+	}
+
+	rt.respond(w, r, u.Private(), http.StatusCreated)
 }
 
 // @Summary Get user by credentials (username and password)
@@ -101,7 +294,7 @@ func (rt *router) createHandler(w http.ResponseWriter, r *http.Request) {
 // @ID get-user-by-credentials
 // @Param user body credentialsPayload true "User object to be found"
 // @Produce json
-// @Success 200 {object} response{response=domain.User}
+// @Success 200 {object} response{response=domain.PrivateProfile}
 // @Failure 400 {object} response
 // @Failure 404 {object} response
 // @Failure 500 {object} response
@@ -126,10 +319,189 @@ func (rt *router) findByCredentialsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	u, err := rt.repository.FindByCredentials(ctx, p.Username, p.MD5Password)
+	u, err := rt.repository.FindByID(ctx, p.Username)
+	if err != nil && err != domain.ErrUserNotFound {
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var match, rehash bool
+	if u != nil && !u.Disabled {
+		match, rehash = domain.VerifyPassword(u.Password, p.Password)
+	} else {
+		// unknown and disabled users take as long as wrong passwords
+		domain.VerifyDummyPassword(p.Password)
+	}
+	if match {
+		// accounts being deleted can't log in anymore
+		_, err := rt.repository.FindDeletion(ctx, u.ID)
+		if err != nil && err != domain.ErrDeletionNotFound {
+			rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		match = err == domain.ErrDeletionNotFound
+	}
+	if !match {
+		rt.logger.Info("user not found", log.Error(errInvalidCredentials), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, errInvalidCredentials.Error(), http.StatusNotFound)
+		return
+	}
+
+	// outdated hashes (e.g. legacy MD5 digests) are replaced now that the plaintext password is known
+	if rehash {
+		if hash, err := domain.HashPassword(p.Password); err != nil {
+			rt.logger.Warn("failed to rehash password", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		} else if err := rt.repository.UpdatePassword(ctx, u.ID, u.Password, hash); err != nil {
+			rt.logger.Warn("failed to rehash password", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		} else {
+			u.Password = hash
+		}
+	}
+
+	rt.respond(w, r, u.Private(), http.StatusOK)
+}
+
+// @Summary Delete user
+// @Description Start deleting a user's account: their ratings and the rest of their data in the rating service are removed, then the user along with their follows, then all their sessions are revoked. The account can't log in anymore from the start, and its username is not given out again. The deletion goes on in the background, every step being retried, and can be followed through its status. Failed and interrupted deletions are resumed from the step they stopped at, by themselves or by deleting the user again. Nothing is started when too many deletions are waiting to run. Allowed to the user themselves and admins
+// @ID delete-user
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.Deletion} "Already deleted"
+// @Success 202 {object} response{response=domain.Deletion}
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 409 {object} response
+// @Failure 500 {object} response
+// @Failure 503 {object} response
+// @Router /{username} [delete]
+func (rt *router) deleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if profileView(r) == publicView {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+
+	// a deletion may be resumed after the user was removed
+	_, err := rt.repository.FindDeletion(ctx, username)
+	if err == domain.ErrDeletionNotFound {
+		_, err = rt.repository.FindByID(ctx, username)
+	}
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the cached public profile is evicted again once the user is removed, as it may have been cached meanwhile
+	rt.evictPublicProfile(username)
+	onFinished := func(d *domain.Deletion) {
+		rt.evictPublicProfile(d.UserID)
+	}
+
+	d, err := rt.dw.Start(ctx, username, context.GetUserUsername(ctx), onFinished)
+	if err != nil {
+		switch err {
+		case domain.ErrDeletionInProgress:
+			rt.respond(w, r, err.Error(), http.StatusConflict)
+		case deletion.ErrQueueFull:
+			rt.respond(w, r, err.Error(), http.StatusServiceUnavailable)
+		default:
+			rt.logger.Error("failed to start account deletion", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if d.Status == domain.DeletionCompleted {
+		rt.respond(w, r, d, http.StatusOK)
+		return
+	}
+
+	rt.respond(w, r, d, http.StatusAccepted)
+}
+
+// @Summary Get user deletion status
+// @Description Get the status of the deletion of a user's account, including the steps completed so far. Allowed to the user themselves, until their sessions are revoked, and admins
+// @ID get-user-deletion
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.Deletion}
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /{username}/deletion [get]
+func (rt *router) findDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if profileView(r) == publicView {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	d, err := rt.repository.FindDeletion(ctx, chi.URLParam(r, "username"))
+	if err != nil {
+		if err == domain.ErrDeletionNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, d, http.StatusOK)
+}
+
+// @Summary Follow user
+// @Description Follow a user as the authenticated user. Users can't follow themselves, nor the same user twice
+// @ID follow-user
+// @Param username path string true "Username of the user to follow"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 201 {object} response{response=domain.Follow}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 409 {object} response
+// @Failure 500 {object} response
+// @Router /{username}/follow [post]
+func (rt *router) followHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	f, err := domain.NewFollow(context.GetUserUsername(ctx), chi.URLParam(r, "username"))
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// accounts being deleted can't be followed anymore, as their follows may already be removed
+	_, err = rt.repository.FindDeletion(ctx, f.FolloweeID)
+	if err == nil {
+		err = domain.ErrUserNotFound
+	} else if err == domain.ErrDeletionNotFound {
+		_, err = rt.repository.FindByID(ctx, f.FolloweeID)
+	}
 	if err != nil {
-		if err.Error() == "invalid credentials" {
-			rt.logger.Info("user not found", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		if err == domain.ErrUserNotFound {
 			rt.respond(w, r, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -138,5 +510,656 @@ func (rt *router) findByCredentialsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	rt.respond(w, r, u, http.StatusOK)
+	if err := rt.repository.Follow(ctx, f); err != nil {
+		if err == domain.ErrAlreadyFollowing {
+			rt.respond(w, r, err.Error(), http.StatusConflict)
+			return
+		}
+		rt.logger.Error("failed to follow user", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the counts changed on both profiles
+	rt.evictPublicProfile(f.FollowerID)
+	rt.evictPublicProfile(f.FolloweeID)
+
+	rt.respond(w, r, f, http.StatusCreated)
+}
+
+// @Summary Unfollow user
+// @Description Stop following a user as the authenticated user
+// @ID unfollow-user
+// @Param username path string true "Username of the user to unfollow"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /{username}/follow [delete]
+func (rt *router) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	follower, followee := context.GetUserUsername(ctx), chi.URLParam(r, "username")
+
+	if err := rt.repository.Unfollow(ctx, follower, followee); err != nil {
+		if err == domain.ErrNotFollowing {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("failed to unfollow user", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the counts changed on both profiles
+	rt.evictPublicProfile(follower)
+	rt.evictPublicProfile(followee)
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// @Summary List followers
+// @Description List the public profiles of the users following a user, sorted by username
+// @ID list-followers
+// @Param username path string true "Username of the user"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Number of users per page, from 1 to 100 (default 20)"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.UserPage}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /{username}/followers [get]
+func (rt *router) followersHandler(w http.ResponseWriter, r *http.Request) {
+	rt.followListHandler(w, r, true)
+}
+
+// @Summary List followed users
+// @Description List the public profiles of the users a user follows, sorted by username
+// @ID list-following
+// @Param username path string true "Username of the user"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Number of users per page, from 1 to 100 (default 20)"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.UserPage}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /{username}/following [get]
+func (rt *router) followingHandler(w http.ResponseWriter, r *http.Request) {
+	rt.followListHandler(w, r, false)
+}
+
+// followListHandler serves a page of the followers of the user in the path, or of the users they follow.
+func (rt *router) followListHandler(w http.ResponseWriter, r *http.Request, followers bool) {
+	ctx := r.Context()
+
+	list := rt.repository.Following
+	if followers {
+		list = rt.repository.Followers
+	}
+
+	if level := context.GetUserLevel(ctx); level == "anonymous" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+
+	if _, err := rt.repository.FindByID(ctx, username); err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// one more user than asked tells if there is a next page
+	users, err := list(ctx, username, r.URL.Query().Get("cursor"), limit+1)
+	if err != nil {
+		rt.logger.Error("failed to list follows", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, userPage(users, limit), http.StatusOK)
+}
+
+// @Summary Get user feed
+// @Description Get the feed of a user: the latest ratings and reviews of the users they follow, newest first. A rating shows once, as of its last change, and the ones changed before following their user may be missing. Allowed to the user themselves and admins
+// @ID get-user-feed
+// @Param username path string true "Username of the user"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Number of activities per page, from 1 to 100 (default 20)"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.FeedPage}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /{username}/feed [get]
+func (rt *router) feedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if profileView(r) == publicView {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cursor *domain.FeedCursor
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cursor, err = domain.ParseFeedCursor(c)
+		if err != nil {
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := rt.feed.Page(ctx, chi.URLParam(r, "username"), cursor, limit)
+	if err != nil {
+		rt.logger.Error("failed to get feed", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, page, http.StatusOK)
+}
+
+// @Summary List users by level
+// @Description List the audit profiles of the users of a level sorted by username, disabled and unlisted ones included. Admins only
+// @ID list-users-by-level
+// @Param level query string true "Level of the users (admin or user)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Number of users per page, from 1 to 100 (default 20)"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.AdminPage}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /admin/users [get]
+func (rt *router) adminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level != "admin" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		rt.respond(w, r, errLevelRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// one more user than asked tells if there is a next page
+	users, err := rt.repository.ListByLevel(ctx, level, r.URL.Query().Get("cursor"), limit+1)
+	if err != nil {
+		rt.logger.Error("failed to list users", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, adminPage(users, limit), http.StatusOK)
+}
+
+// @Summary Set user level
+// @Description Promote a user to admin or demote them to user. Their sessions are revoked, so the new level applies from their next login. Admins only, who can't change their own level. The last admin who can log in can't be demoted
+// @ID set-user-level
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Accept json
+// @Produce json
+// @Param level body levelPayload true "New level of the user (admin or user)"
+// @Success 200 {object} response{response=domain.AdminProfile}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 409 {object} response
+// @Failure 500 {object} response
+// @Failure 503 {object} response
+// @Router /admin/users/{username}/level [put]
+func (rt *router) setLevelHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p levelPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rt.updateAccess(w, r, true, func(u *domain.User) error {
+		return u.SetLevel(p.Level)
+	})
+}
+
+// @Summary Disable user
+// @Description Disable a user's account: they can't log in anymore, their sessions are revoked and they are left out of the directory. Admins only, who can't disable themselves. The last admin who can log in can't be disabled
+// @ID disable-user
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.AdminProfile}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 409 {object} response
+// @Failure 500 {object} response
+// @Failure 503 {object} response
+// @Router /admin/users/{username}/disable [post]
+func (rt *router) disableHandler(w http.ResponseWriter, r *http.Request) {
+	rt.updateAccess(w, r, true, func(u *domain.User) error {
+		u.SetDisabled(true)
+		return nil
+	})
+}
+
+// @Summary Enable user
+// @Description Enable a disabled user's account, so they can log in again. Admins only
+// @ID enable-user
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 200 {object} response{response=domain.AdminProfile}
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 500 {object} response
+// @Router /admin/users/{username}/enable [post]
+func (rt *router) enableHandler(w http.ResponseWriter, r *http.Request) {
+	// disabled users have no sessions left to revoke
+	rt.updateAccess(w, r, false, func(u *domain.User) error {
+		u.SetDisabled(false)
+		return nil
+	})
+}
+
+// @Summary Move the feeds in the event stream
+// @Description Move the consumer group of the feeds to an offset of the rating event stream, so the events after it are handled again, e.g. the ones set aside in its dead-letter stream once the cause is fixed. Replaying events is harmless. Admins only
+// @ID set-feed-offset
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Accept json
+// @Produce json
+// @Param offset body offsetPayload true "Stream entry ID to resume after, 0 for the whole stream or $ for its end"
+// @Success 200 {object} response
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Router /admin/feed/offset [put]
+func (rt *router) setFeedOffsetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level != "admin" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p offsetPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if p.Offset == "" {
+		rt.respond(w, r, errOffsetRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := rt.feed.SetOffset(ctx, p.Offset); err != nil {
+		if err == feed.ErrInvalidOffset {
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rt.logger.Error("failed to set feed offset", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.logger.Info("feed offset set", log.String("offset", p.Offset), log.String("requestId", context.GetRequestID(ctx)))
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
+}
+
+// updateAccess applies a change of access to the user in the path on behalf of an admin, and then revokes the
+// sessions of the user if asked, so none goes on with the former access. The change can be applied again as is
+// when the sessions couldn't be revoked.
+func (rt *router) updateAccess(w http.ResponseWriter, r *http.Request, revoke bool, change func(u *domain.User) error) {
+	ctx := r.Context()
+
+	if level := context.GetUserLevel(ctx); level != "admin" {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+
+	// so there is always an admin left
+	if username == context.GetUserUsername(ctx) {
+		rt.respond(w, r, errOwnAccess.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := rt.repository.FindByID(ctx, username)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := change(u); err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err = rt.repository.UpdateAccess(ctx, u)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == domain.ErrLastAdmin {
+			rt.respond(w, r, err.Error(), http.StatusConflict)
+			return
+		}
+		rt.logger.Error("failed to update user access", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.logger.Info(
+		"user access changed",
+		log.String("username", u.Username),
+		log.String("level", u.Level),
+		log.String("changedBy", context.GetUserUsername(ctx)),
+		log.String("requestId", context.GetRequestID(ctx)),
+	)
+
+	if revoke {
+		if err := rt.sessions.RevokeUser(ctx, u.Username, context.GetAccessToken(ctx)); err != nil {
+			rt.logger.Error("failed to revoke sessions", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, errSessionsNotRevoked.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	rt.respond(w, r, u.Admin(), http.StatusOK)
+}
+
+// @Summary Send email verification
+// @Description Send a verification token to the user's email address, to be confirmed back. Any token sent for the current address can be confirmed until it expires
+// @ID send-email-verification
+// @Param username path string true "Username of the user"
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Insert your access token"
+// @Produce json
+// @Success 202 {object} response
+// @Failure 400 {object} response
+// @Failure 401 {object} response
+// @Failure 404 {object} response
+// @Failure 409 {object} response
+// @Failure 500 {object} response
+// @Failure 503 {object} response
+// @Router /{username}/email/verification [post]
+func (rt *router) sendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if profileView(r) == publicView {
+		rt.respond(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	u, err := rt.repository.FindByID(ctx, chi.URLParam(r, "username"))
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			rt.respond(w, r, err.Error(), http.StatusNotFound)
+			return
+		}
+		rt.logger.Error("internal error", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := rt.verifier.Send(ctx, u); err != nil {
+		switch err {
+		case verification.ErrNoEmail:
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		case verification.ErrAlreadyVerified:
+			rt.respond(w, r, err.Error(), http.StatusConflict)
+		default:
+			rt.logger.Error("failed to send verification email", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, errEmailNotSent.Error(), http.StatusServiceUnavailable)
+		}
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusAccepted), http.StatusAccepted)
+}
+
+// @Summary Confirm email verification
+// @Description Verify the email address a verification token was sent to. The token is enough, so it can be confirmed from any device
+// @ID confirm-email-verification
+// @Accept json
+// @Produce json
+// @Param token body verificationPayload true "Verification token sent by email"
+// @Success 200 {object} response{response=domain.PrivateProfile}
+// @Failure 400 {object} response
+// @Failure 500 {object} response
+// @Router /email/verification [post]
+func (rt *router) confirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p verificationPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if p.Token == "" {
+		rt.respond(w, r, errTokenRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := rt.verifier.Confirm(ctx, p.Token)
+	if err != nil {
+		if err == domain.ErrInvalidVerificationToken {
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rt.logger.Error("failed to verify email", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt.respond(w, r, u.Private(), http.StatusOK)
+}
+
+// sendVerification emails a verification token to a new address of an User. It only logs a failure, as the user can
+// ask for another one.
+func (rt *router) sendVerification(r *http.Request, u *domain.User) {
+	ctx := r.Context()
+
+	if err := rt.verifier.Send(ctx, u); err != nil && err != verification.ErrNoEmail {
+		rt.logger.Error("failed to send verification email", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+	}
+}
+
+// @Summary Forgot password
+// @Description Email a single use password reset token to the user of a verified email address, voiding the ones sent before. The email is sent in the background, so the response is the same whether the address has an account or not
+// @ID forgot-password
+// @Accept json
+// @Produce json
+// @Param email body forgotPasswordPayload true "Email address of the account"
+// @Success 202 {object} response
+// @Failure 400 {object} response
+// @Failure 503 {object} response
+// @Router /password/forgot [post]
+func (rt *router) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p forgotPasswordPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if p.Email == "" {
+		rt.respond(w, r, errEmailRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the email is sent in the background, so the response doesn't tell which addresses have accounts
+	if err := rt.resetter.Request(p.Email); err != nil {
+		rt.logger.Error("failed to request password reset", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	rt.respond(w, r, http.StatusText(http.StatusAccepted), http.StatusAccepted)
+}
+
+// @Summary Reset password
+// @Description Set a new password with a password reset token, which revokes all the sessions of the user. The token can't be used again once the sessions are revoked
+// @ID reset-password
+// @Accept json
+// @Produce json
+// @Param reset body resetPasswordPayload true "Reset token sent by email and new password"
+// @Success 200 {object} response
+// @Failure 400 {object} response
+// @Failure 500 {object} response
+// @Failure 503 {object} response
+// @Router /password/reset [post]
+func (rt *router) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.logger.Error("failed to read request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p resetPasswordPayload
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		rt.logger.Error("failed to parse request body", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if p.Token == "" {
+		rt.respond(w, r, errTokenRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := domain.ValidatePassword(p.Password); err != nil {
+		rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := rt.resetter.Reset(ctx, p.Token, p.Password)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidResetToken:
+			rt.respond(w, r, err.Error(), http.StatusBadRequest)
+		case recovery.ErrSessionsNotRevoked:
+			rt.respond(w, r, err.Error(), http.StatusServiceUnavailable)
+		default:
+			rt.logger.Error("failed to reset password", log.Error(err), log.String("requestId", context.GetRequestID(ctx)))
+			rt.respond(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	rt.logger.Info("password reset", log.String("username", u.Username), log.String("requestId", context.GetRequestID(ctx)))
+
+	rt.respond(w, r, http.StatusText(http.StatusOK), http.StatusOK)
 }
@@ -0,0 +1,1332 @@
+package router
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	libCtx "github.com/victorspringer/backend-coding-challenge/lib/context"
+	"github.com/victorspringer/backend-coding-challenge/lib/log"
+	"github.com/victorspringer/backend-coding-challenge/services/rating/pkg/events"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/deletion"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/feed"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/mailer"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/recovery"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/verification"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type memoryRepository struct {
+	mu        sync.Mutex
+	users     map[string]*domain.User
+	follows   []*domain.Follow
+	deletions map[string]*domain.Deletion
+	runs      int
+
+	activities map[string]*domain.Activity
+	timelines  map[string]map[string]*domain.Activity // by owner and activity ID
+	resets     map[string]*domain.PasswordReset       // by user ID
+}
+
+func newMemoryRepository(users ...*domain.User) *memoryRepository {
+	m := &memoryRepository{
+		users:      make(map[string]*domain.User),
+		deletions:  make(map[string]*domain.Deletion),
+		activities: make(map[string]*domain.Activity),
+		timelines:  make(map[string]map[string]*domain.Activity),
+		resets:     make(map[string]*domain.PasswordReset),
+	}
+	for _, u := range users {
+		m.users[u.ID] = u
+	}
+	return m
+}
+
+func (m *memoryRepository) Create(ctx context.Context, vu *domain.ValidatedUser) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := vu.User
+	if m.emailTaken(u.ID, u.Email) {
+		return nil, domain.ErrEmailNotAvailable
+	}
+	m.users[u.ID] = &u
+	return &u, nil
+}
+
+func (m *memoryRepository) emailTaken(id, email string) bool {
+	for _, u := range m.users {
+		if email != "" && u.Email == email && u.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	c := *u
+	return &c, nil
+}
+
+func (m *memoryRepository) Update(ctx context.Context, vu *domain.ValidatedUser) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[vu.ID]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	if m.emailTaken(u.ID, vu.Email) {
+		return nil, domain.ErrEmailNotAvailable
+	}
+	u.Name, u.Picture, u.Unlisted, u.SearchKeys, u.UpdatedAt = vu.Name, vu.Picture, vu.Unlisted, vu.SearchKeys, vu.UpdatedAt
+	u.Email, u.EmailVerified, u.EmailUpdatedAt = vu.Email, vu.EmailVerified, vu.EmailUpdatedAt
+	c := *u
+	return &c, nil
+}
+
+func (m *memoryRepository) VerifyEmail(ctx context.Context, id, email string, emailUpdatedAt time.Time) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok || u.Email != email || !u.EmailUpdatedAt.Equal(emailUpdatedAt) {
+		return nil, domain.ErrUserNotFound
+	}
+	u.EmailVerified = true
+	c := *u
+	return &c, nil
+}
+
+func (m *memoryRepository) Search(ctx context.Context, query, cursor string, limit int) ([]*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = domain.NormalizeSearch(query)
+
+	users := []*domain.User{}
+	for _, u := range m.users {
+		if u.Unlisted || u.Disabled || u.ID <= cursor {
+			continue
+		}
+		for _, key := range u.SearchKeys {
+			if strings.HasPrefix(key, query) {
+				c := *u
+				users = append(users, &c)
+				break
+			}
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *memoryRepository) UpdateAccess(ctx context.Context, user *domain.User) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[user.ID]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	if u.IsAdmin() && !user.IsAdmin() {
+		admins := 0
+		for _, other := range m.users {
+			if other.IsAdmin() {
+				admins++
+			}
+		}
+		if admins == 1 {
+			return nil, domain.ErrLastAdmin
+		}
+	}
+	u.Level, u.Disabled, u.UpdatedAt = user.Level, user.Disabled, user.UpdatedAt
+	c := *u
+	return &c, nil
+}
+
+func (m *memoryRepository) ListByLevel(ctx context.Context, level, cursor string, limit int) ([]*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := []*domain.User{}
+	for _, u := range m.users {
+		if u.Level == level && u.ID > cursor {
+			c := *u
+			users = append(users, &c)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *memoryRepository) UpdatePassword(ctx context.Context, id, current, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.users[id].Password != current {
+		return domain.ErrPasswordChanged
+	}
+	m.users[id].Password = hash
+	return nil
+}
+
+func (m *memoryRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Email != "" && u.Email == email {
+			c := *u
+			return &c, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *memoryRepository) SavePasswordReset(ctx context.Context, r *domain.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := *r
+	m.resets[r.UserID] = &c
+	return nil
+}
+
+func (m *memoryRepository) FindPasswordReset(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.resets {
+		if r.TokenHash == tokenHash && time.Now().Before(r.ExpiresAt) {
+			c := *r
+			return &c, nil
+		}
+	}
+	return nil, domain.ErrInvalidResetToken
+}
+
+func (m *memoryRepository) DeletePasswordReset(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, r := range m.resets {
+		if r.TokenHash == tokenHash {
+			delete(m.resets, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepository) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[id]; !ok {
+		return domain.ErrUserNotFound
+	}
+	for _, f := range append([]*domain.Follow{}, m.follows...) {
+		if f.FollowerID == id || f.FolloweeID == id {
+			m.unfollow(f.FollowerID, f.FolloweeID)
+		}
+	}
+	delete(m.users, id)
+	delete(m.resets, id)
+	return nil
+}
+
+func (m *memoryRepository) Follow(ctx context.Context, f *domain.Follow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.follows {
+		if e.FollowerID == f.FollowerID && e.FolloweeID == f.FolloweeID {
+			return domain.ErrAlreadyFollowing
+		}
+	}
+	m.follows = append(m.follows, f)
+	m.users[f.FollowerID].Following++
+	m.users[f.FolloweeID].Followers++
+	return nil
+}
+
+func (m *memoryRepository) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.unfollow(followerID, followeeID)
+}
+
+func (m *memoryRepository) unfollow(followerID, followeeID string) error {
+	for i, e := range m.follows {
+		if e.FollowerID == followerID && e.FolloweeID == followeeID {
+			m.follows = append(m.follows[:i], m.follows[i+1:]...)
+			m.users[followerID].Following--
+			m.users[followeeID].Followers--
+			for id, a := range m.timelines[followerID] {
+				if a.UserID == followeeID {
+					delete(m.timelines[followerID], id)
+				}
+			}
+			return nil
+		}
+	}
+	return domain.ErrNotFollowing
+}
+
+func (m *memoryRepository) Followers(ctx context.Context, id, cursor string, limit int) ([]*domain.User, error) {
+	return m.followUsers(func(f *domain.Follow) string {
+		if f.FolloweeID == id {
+			return f.FollowerID
+		}
+		return ""
+	}, cursor, limit)
+}
+
+func (m *memoryRepository) Following(ctx context.Context, id, cursor string, limit int) ([]*domain.User, error) {
+	return m.followUsers(func(f *domain.Follow) string {
+		if f.FollowerID == id {
+			return f.FolloweeID
+		}
+		return ""
+	}, cursor, limit)
+}
+
+func (m *memoryRepository) followUsers(otherEnd func(f *domain.Follow) string, cursor string, limit int) ([]*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := []*domain.User{}
+	for _, f := range m.follows {
+		if id := otherEnd(f); id != "" && id > cursor {
+			c := *m.users[id]
+			users = append(users, &c)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *memoryRepository) FollowerIDs(ctx context.Context, id, cursor string, limit int) ([]string, error) {
+	users, err := m.Followers(ctx, id, cursor, limit)
+	ids := []string{}
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids, err
+}
+
+func (m *memoryRepository) FollowedCelebrities(ctx context.Context, id string, minFollowers int) ([]string, error) {
+	users, err := m.Following(ctx, id, "", len(m.users))
+	ids := []string{}
+	for _, u := range users {
+		if u.Followers >= minFollowers {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, err
+}
+
+func (m *memoryRepository) SaveActivity(ctx context.Context, a *domain.Activity) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.activities[a.ID]; ok && stored.OccurredAt.After(a.OccurredAt) {
+		return false, nil
+	}
+	m.activities[a.ID] = a
+	return true, nil
+}
+
+func (m *memoryRepository) DeleteActivity(ctx context.Context, id string, deletedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, activities := range append([]map[string]*domain.Activity{m.activities}, timelinesOf(m.timelines)...) {
+		if a, ok := activities[id]; ok && !a.OccurredAt.After(deletedAt) {
+			delete(activities, id)
+		}
+	}
+	return nil
+}
+
+func timelinesOf(timelines map[string]map[string]*domain.Activity) []map[string]*domain.Activity {
+	list := []map[string]*domain.Activity{}
+	for _, t := range timelines {
+		list = append(list, t)
+	}
+	return list
+}
+
+func (m *memoryRepository) Activities(ctx context.Context, userIDs []string, cursor *domain.FeedCursor, limit int) ([]*domain.Activity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activities := []*domain.Activity{}
+	for _, a := range m.activities {
+		for _, id := range userIDs {
+			if a.UserID == id {
+				activities = append(activities, a)
+			}
+		}
+	}
+	return feedOf(activities, cursor, limit), nil
+}
+
+func (m *memoryRepository) FanOut(ctx context.Context, a *domain.Activity, ownerIDs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ownerIDs {
+		if m.timelines[id] == nil {
+			m.timelines[id] = make(map[string]*domain.Activity)
+		}
+		if stored, ok := m.timelines[id][a.ID]; !ok || !stored.OccurredAt.After(a.OccurredAt) {
+			m.timelines[id][a.ID] = a
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepository) Timeline(ctx context.Context, ownerID string, cursor *domain.FeedCursor, limit int) ([]*domain.Activity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activities := []*domain.Activity{}
+	for _, a := range m.timelines[ownerID] {
+		activities = append(activities, a)
+	}
+	return feedOf(activities, cursor, limit), nil
+}
+
+// feedOf sorts activities newest first, keeping up to limit of the ones after cursor.
+func feedOf(activities []*domain.Activity, cursor *domain.FeedCursor, limit int) []*domain.Activity {
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].OccurredAt.Equal(activities[j].OccurredAt) {
+			return activities[i].ID > activities[j].ID
+		}
+		return activities[i].OccurredAt.After(activities[j].OccurredAt)
+	})
+
+	list := []*domain.Activity{}
+	for _, a := range activities {
+		if cursor != nil && (a.OccurredAt.After(cursor.OccurredAt) ||
+			a.OccurredAt.Equal(cursor.OccurredAt) && a.ID >= cursor.ID) {
+			continue
+		}
+		if len(list) < limit {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+func (m *memoryRepository) StartDeletion(ctx context.Context, d *domain.Deletion, lease time.Duration) (*domain.Deletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if prev, ok := m.deletions[d.UserID]; ok {
+		if prev.Status == domain.DeletionCompleted {
+			return prev, nil
+		}
+		if prev.LockedUntil.After(time.Now()) {
+			return prev, domain.ErrDeletionInProgress
+		}
+		d = prev
+	}
+	d.Status = domain.DeletionRunning
+	d.LockedUntil = time.Now().Add(lease)
+	m.runs++
+	d.RunID = strconv.Itoa(m.runs)
+	m.deletions[d.UserID] = d
+	c := *d
+	return &c, nil
+}
+
+func (m *memoryRepository) SaveDeletion(ctx context.Context, d *domain.Deletion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if prev, ok := m.deletions[d.UserID]; ok && prev.RunID != d.RunID {
+		return domain.ErrDeletionInProgress
+	}
+	c := *d
+	m.deletions[d.UserID] = &c
+	return nil
+}
+
+func (m *memoryRepository) FindDeletion(ctx context.Context, userID string) (*domain.Deletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.deletions[userID]
+	if !ok {
+		return nil, domain.ErrDeletionNotFound
+	}
+	c := *d
+	return &c, nil
+}
+
+func (m *memoryRepository) FindStaleDeletions(ctx context.Context, limit int) ([]*domain.Deletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := []*domain.Deletion{}
+	for _, d := range m.deletions {
+		if d.Status != domain.DeletionCompleted && d.LockedUntil.Before(time.Now()) && len(list) < limit {
+			c := *d
+			list = append(list, &c)
+		}
+	}
+	return list, nil
+}
+
+func (m *memoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+// newTestHandler mounts the user handlers the way GetHandler does, with the caller set in the context
+// instead of authenticated by the authentication service. Handlers of the same router share its cache.
+func newTestHandler(rt *router, level, username string) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), libCtx.CTX_USER_LEVEL, level)
+			ctx = context.WithValue(ctx, libCtx.CTX_USER_USERNAME, username)
+			ctx = context.WithValue(ctx, libCtx.CTX_ACCESS_TOKEN, username+"-token")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Get("/", rt.rootHandler)
+	r.Post("/create", rt.createHandler)
+	r.Post("/credentials", rt.findByCredentialsHandler)
+	r.Post("/email/verification", rt.confirmEmailHandler)
+	r.Post("/password/forgot", rt.forgotPasswordHandler)
+	r.Post("/password/reset", rt.resetPasswordHandler)
+	r.Patch("/{username}", rt.updateHandler)
+	r.Delete("/{username}", rt.deleteHandler)
+	r.Get("/{username}/deletion", rt.findDeletionHandler)
+	r.Post("/{username}/follow", rt.followHandler)
+	r.Post("/{username}/email/verification", rt.sendVerificationHandler)
+	r.Delete("/{username}/follow", rt.unfollowHandler)
+	r.Get("/{username}/followers", rt.followersHandler)
+	r.Get("/{username}/following", rt.followingHandler)
+	r.Get("/{username}/feed", rt.feedHandler)
+	r.Get("/admin/users", rt.adminUsersHandler)
+	r.Put("/admin/users/{username}/level", rt.setLevelHandler)
+	r.Post("/admin/users/{username}/disable", rt.disableHandler)
+	r.Post("/admin/users/{username}/enable", rt.enableHandler)
+	r.Put("/admin/feed/offset", rt.setFeedOffsetHandler)
+	r.With(rt.publicCacheMiddleware).Get("/{username}", rt.findHandler)
+
+	return r
+}
+
+func TestHandlers_NeverEmitPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bob-secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	md5Sum := md5.Sum([]byte("carol-secret"))
+
+	bob := domain.NewUser("bob", string(bcryptHash), "Bob", "")
+	bob.SetEmail("bob@example.com")
+	repo := newMemoryRepository(bob, domain.NewUser("carol", hex.EncodeToString(md5Sum[:]), "Carol", ""))
+	follow, err := domain.NewFollow("carol", "bob")
+	require.NoError(t, err)
+	require.NoError(t, repo.Follow(context.Background(), follow))
+
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+	rt.sessions = &fakeServices{}
+	rt.verifier = verification.NewVerifier(repo, mailer.NewFile(filepath.Join(t.TempDir(), "mail.log"), "no-reply@example.com"), "secret", time.Hour, "https://example.com/verify?token=")
+	token := domain.NewEmailVerification(bob, time.Hour).Token([]byte("secret"))
+
+	tests := []struct {
+		name     string
+		level    string
+		username string
+		method   string
+		path     string
+		body     string
+		code     int
+		contains string
+	}{
+		{"create", "anonymous", "", http.MethodPost, "/create", `{"username":"alice","password":"alice-secret","name":"Alice"}`, http.StatusCreated, `"level":"user"`},
+		{"credentials", "anonymous", "", http.MethodPost, "/credentials", `{"username":"bob","password":"bob-secret"}`, http.StatusOK, `"createdAt"`},
+		{"credentials with legacy hash", "anonymous", "", http.MethodPost, "/credentials", `{"username":"carol","password":"carol-secret"}`, http.StatusOK, `"createdAt"`},
+		{"public profile", "user", "alice", http.MethodGet, "/bob", "", http.StatusOK, `"name":"Bob"`},
+		{"public profile from cache", "user", "carol", http.MethodGet, "/bob", "", http.StatusOK, `"name":"Bob"`},
+		{"private profile", "user", "bob", http.MethodGet, "/bob", "", http.StatusOK, `"level":"user"`},
+		{"admin profile", "admin", "root", http.MethodGet, "/bob", "", http.StatusOK, `"passwordScheme":"bcrypt"`},
+		{"update", "user", "bob", http.MethodPatch, "/bob", `{"name":"Robert"}`, http.StatusOK, `"name":"Robert"`},
+		{"directory", "user", "alice", http.MethodGet, "/?q=", "", http.StatusOK, `"username":"bob"`},
+		{"followers", "user", "alice", http.MethodGet, "/bob/followers", "", http.StatusOK, `"username":"carol"`},
+		{"following", "user", "alice", http.MethodGet, "/carol/following", "", http.StatusOK, `"username":"bob"`},
+		{"admin users", "admin", "root", http.MethodGet, "/admin/users?level=user", "", http.StatusOK, `"username":"bob"`},
+		{"level", "admin", "root", http.MethodPut, "/admin/users/carol/level", `{"level":"admin"}`, http.StatusOK, `"level":"admin"`},
+		{"disable", "admin", "root", http.MethodPost, "/admin/users/bob/disable", "", http.StatusOK, `"disabled":true`},
+		{"enable", "admin", "root", http.MethodPost, "/admin/users/bob/enable", "", http.StatusOK, `"disabled":false`},
+		{"email verification", "anonymous", "", http.MethodPost, "/email/verification", `{"token":"` + token + `"}`, http.StatusOK, `"emailVerified":true`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			newTestHandler(rt, tt.level, tt.username).ServeHTTP(rec, req)
+
+			body := rec.Body.String()
+			assert.Equal(t, tt.code, rec.Code, body)
+			assert.Contains(t, body, tt.contains)
+			assert.NotContains(t, strings.ToLower(body), `"password"`)
+			for _, u := range repo.users {
+				assert.NotContains(t, body, u.Password)
+			}
+		})
+	}
+}
+
+func TestFindHandler_CachesOnlyPublicProfiles(t *testing.T) {
+	repo := newMemoryRepository(domain.NewUser("bob", "hash", "Bob", ""))
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+
+	get := func(level, username string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, username).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bob", nil))
+		return rec
+	}
+
+	// the public profile is cached first, the other callers must still get their own representations
+	assert.NotContains(t, get("user", "alice").Body.String(), `"level"`)
+	assert.Contains(t, get("user", "bob").Body.String(), `"level"`)
+	assert.Contains(t, get("admin", "root").Body.String(), `"passwordScheme"`)
+	assert.Equal(t, http.StatusUnauthorized, get("anonymous", "").Code)
+	assert.NotContains(t, get("user", "carol").Body.String(), `"level"`)
+}
+
+func TestUpdateHandler(t *testing.T) {
+	repo := newMemoryRepository(domain.NewUser("bob", "hash", "Bob", "http://example.com/bob.jpg"))
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+
+	do := func(level, username, method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/bob", strings.NewReader(body))
+		newTestHandler(rt, level, username).ServeHTTP(rec, req)
+		return rec
+	}
+
+	// warm up the cache with the public profile
+	assert.Contains(t, do("user", "alice", http.MethodGet, "").Body.String(), `"name":"Bob"`)
+
+	assert.Equal(t, http.StatusUnauthorized, do("anonymous", "", http.MethodPatch, `{"name":"Robert"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("user", "alice", http.MethodPatch, `{"name":"Robert"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("user", "bob", http.MethodPatch, `{"name":""}`).Code)
+
+	updatedAt := repo.users["bob"].UpdatedAt
+	rec := do("user", "bob", http.MethodPatch, `{"name":"Robert","picture":""}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "Robert", repo.users["bob"].Name)
+	assert.Empty(t, repo.users["bob"].Picture)
+	assert.True(t, repo.users["bob"].UpdatedAt.After(updatedAt))
+
+	// the cached public profile was evicted
+	assert.Contains(t, do("user", "alice", http.MethodGet, "").Body.String(), `"name":"Robert"`)
+
+	rec = do("admin", "root", http.MethodPatch, `{"name":"Bobby"}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"passwordScheme"`)
+	assert.Equal(t, "Bobby", repo.users["bob"].Name)
+}
+
+func TestDirectoryHandler(t *testing.T) {
+	repo := newMemoryRepository(
+		domain.NewUser("jdoe", "hash", "John Doe", ""),
+		domain.NewUser("jane", "hash", "Jane Roe", ""),
+		domain.NewUser("joe", "hash", "Joe", ""),
+		domain.NewUser("mary", "hash", "Mary Doe", ""),
+	)
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+
+	get := func(level, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, "alice").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	page := func(path string) domain.UserPage {
+		rec := get("user", path)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Response domain.UserPage `json:"response"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Response
+	}
+	usernames := func(p domain.UserPage) []string {
+		list := []string{}
+		for _, u := range p.Users {
+			list = append(list, u.Username)
+		}
+		return list
+	}
+
+	// without the query parameters it is still the health check
+	assert.Equal(t, http.StatusOK, get("anonymous", "/").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("anonymous", "/?q=").Code)
+	assert.Equal(t, http.StatusBadRequest, get("user", "/?q=&limit=0").Code)
+
+	assert.Equal(t, []string{"jane", "jdoe", "joe", "mary"}, usernames(page("/?q=")))
+	assert.Equal(t, []string{"jane", "jdoe", "joe"}, usernames(page("/?q=J")))
+	assert.Equal(t, []string{"jdoe", "mary"}, usernames(page("/?q=DOE")))
+	assert.Equal(t, []string{"jdoe"}, usernames(page("/?q=john%20d")))
+	assert.Empty(t, usernames(page("/?q=oe")))
+
+	first := page("/?q=j&limit=2")
+	assert.Equal(t, []string{"jane", "jdoe"}, usernames(first))
+	require.Equal(t, "jdoe", first.NextCursor)
+	last := page("/?q=j&limit=2&cursor=" + first.NextCursor)
+	assert.Equal(t, []string{"joe"}, usernames(last))
+	assert.Empty(t, last.NextCursor)
+
+	// only public profiles are listed
+	assert.NotContains(t, get("user", "/?q=").Body.String(), `"level"`)
+
+	// unlisted users are left out
+	rec := httptest.NewRecorder()
+	newTestHandler(rt, "user", "jdoe").ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/jdoe", strings.NewReader(`{"unlisted":true}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"unlisted":true`)
+	assert.Equal(t, []string{"mary"}, usernames(page("/?q=doe")))
+}
+
+func TestFollowHandlers(t *testing.T) {
+	repo := newMemoryRepository(
+		domain.NewUser("alice", "hash", "Alice", ""),
+		domain.NewUser("bob", "hash", "Bob", ""),
+		domain.NewUser("carol", "hash", "Carol", ""),
+		domain.NewUser("dave", "hash", "Dave", ""),
+	)
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+
+	do := func(level, username, method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, username).ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	page := func(path string) (usernames []string, nextCursor string) {
+		rec := do("user", "alice", http.MethodGet, path)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Response domain.UserPage `json:"response"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		usernames = []string{}
+		for _, u := range res.Response.Users {
+			usernames = append(usernames, u.Username)
+		}
+		return usernames, res.Response.NextCursor
+	}
+
+	// warm up the cache with the public profile
+	assert.Contains(t, do("user", "alice", http.MethodGet, "/bob").Body.String(), `"followers":0`)
+
+	assert.Equal(t, http.StatusUnauthorized, do("anonymous", "", http.MethodPost, "/bob/follow").Code)
+	assert.Equal(t, http.StatusBadRequest, do("user", "bob", http.MethodPost, "/bob/follow").Code)
+	assert.Equal(t, http.StatusNotFound, do("user", "alice", http.MethodPost, "/nobody/follow").Code)
+
+	for _, follower := range []string{"dave", "alice", "carol"} {
+		rec := do("user", follower, http.MethodPost, "/bob/follow")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	assert.Equal(t, http.StatusCreated, do("user", "bob", http.MethodPost, "/alice/follow").Code)
+	assert.Equal(t, http.StatusConflict, do("user", "alice", http.MethodPost, "/bob/follow").Code)
+
+	// the counts are on the profiles, and the cached one was evicted
+	rec := do("user", "alice", http.MethodGet, "/bob")
+	assert.Contains(t, rec.Body.String(), `"followers":3`)
+	assert.Contains(t, rec.Body.String(), `"following":1`)
+
+	followers, next := page("/bob/followers?limit=2")
+	assert.Equal(t, []string{"alice", "carol"}, followers)
+	followers, next = page("/bob/followers?limit=2&cursor=" + next)
+	assert.Equal(t, []string{"dave"}, followers)
+	assert.Empty(t, next)
+	following, _ := page("/bob/following")
+	assert.Equal(t, []string{"alice"}, following)
+	assert.Equal(t, http.StatusNotFound, do("user", "alice", http.MethodGet, "/nobody/followers").Code)
+
+	assert.Equal(t, http.StatusOK, do("user", "alice", http.MethodDelete, "/bob/follow").Code)
+	assert.Equal(t, http.StatusNotFound, do("user", "alice", http.MethodDelete, "/bob/follow").Code)
+	assert.Equal(t, 2, repo.users["bob"].Followers)
+	assert.Equal(t, 0, repo.users["alice"].Following)
+
+	// removing an user removes their follows too
+	require.NoError(t, repo.Delete(context.Background(), "dave"))
+	followers, _ = page("/bob/followers")
+	assert.Equal(t, []string{"carol"}, followers)
+	assert.Equal(t, 1, repo.users["bob"].Followers)
+}
+
+// fakeSource delivers the given rating events once, then stops.
+type fakeSource []events.Event
+
+func (s fakeSource) CreateGroup(ctx context.Context, offset string) error {
+	return nil
+}
+
+func (s fakeSource) SetOffset(ctx context.Context, offset string) error {
+	return nil
+}
+
+func (s fakeSource) Consume(ctx context.Context, handler events.Handler) error {
+	for i, e := range s {
+		if err := handler(ctx, &events.Message{StreamID: strconv.Itoa(i), Event: e}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFeedHandler(t *testing.T) {
+	repo := newMemoryRepository(
+		domain.NewUser("alice", "hash", "Alice", ""),
+		domain.NewUser("bob", "hash", "Bob", ""),
+		domain.NewUser("carol", "hash", "Carol", ""),
+		domain.NewUser("dave", "hash", "Dave", ""),
+	)
+	for _, f := range []*domain.Follow{{FollowerID: "alice", FolloweeID: "bob"}, {FollowerID: "alice", FolloweeID: "carol"}, {FollowerID: "dave", FolloweeID: "carol"}} {
+		require.NoError(t, repo.Follow(context.Background(), f))
+	}
+
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	rating := func(id, userID string, value float32, minutes int, review *events.Review) events.Event {
+		return events.Event{
+			ID:         id + "-" + strconv.Itoa(minutes),
+			Type:       events.RatingCreated,
+			Rating:     events.Rating{ID: id, UserID: userID, MovieID: "dune", Value: value, Review: review},
+			OccurredAt: start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+	updated := rating("r1", "bob", 4.5, 3, nil)
+	updated.Type = events.RatingUpdated
+	deleted := rating("r4", "bob", 2, 5, nil)
+	deleted.Type = events.RatingDeleted
+	held := rating("r5", "bob", 5, 7, nil)
+	held.Type, held.Rating.Held = events.RatingUpdated, true
+	heldOnCreation := rating("r6", "carol", 5, 8, nil)
+	heldOnCreation.Rating.Held = true
+	reRated := rating("r7", "bob", 4, 10, nil)
+	reRated.Type = events.RatingUpdated
+	reRatedDeleted := rating("r7", "bob", 4, 11, nil)
+	reRatedDeleted.Type = events.RatingDeleted
+
+	// carol has 2 followers, so her activities are read along with the timelines instead of fanned out
+	source := fakeSource{
+		rating("r1", "bob", 3, 1, nil),
+		rating("r2", "carol", 5, 2, &events.Review{Title: "Epic", Body: "Loved it"}),
+		updated,
+		rating("r1", "bob", 3, 1, nil), // delivered again, late
+		rating("r3", "dave", 1, 4, nil),
+		rating("r4", "bob", 2, 4, nil),
+		deleted,
+		// held ratings leave the feeds, or never reach them
+		rating("r5", "bob", 5, 6, nil),
+		held,
+		heldOnCreation,
+		// a re-rated movie keeps its rating ID, so its deletion removes it whatever the number of changes
+		rating("r7", "bob", 2, 9, nil),
+		reRated,
+		reRatedDeleted,
+	}
+	fd := feed.NewFeed(repo, source, log.New("fatal"), 2, 1)
+	fd.Run(context.Background())
+
+	rt := New(repo, log.New("fatal"), nil, nil, fd, nil, nil).(*router)
+
+	get := func(level, username, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, username).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	page := func(path string) domain.FeedPage {
+		rec := get("user", "alice", path)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Response domain.FeedPage `json:"response"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Response
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("user", "bob", "/alice/feed").Code)
+	assert.Equal(t, http.StatusBadRequest, get("user", "alice", "/alice/feed?cursor=nope").Code)
+	assert.Len(t, repo.timelines["alice"], 1)
+
+	first := page("/alice/feed?limit=1")
+	require.Len(t, first.Activities, 1)
+	assert.Equal(t, "r1", first.Activities[0].ID)
+	assert.Equal(t, domain.RatedActivity, first.Activities[0].Type)
+	assert.Equal(t, float32(4.5), first.Activities[0].Value)
+	require.NotEmpty(t, first.NextCursor)
+
+	last := page("/alice/feed?limit=1&cursor=" + first.NextCursor)
+	require.Len(t, last.Activities, 1)
+	assert.Equal(t, "r2", last.Activities[0].ID)
+	assert.Equal(t, domain.ReviewedActivity, last.Activities[0].Type)
+	assert.Equal(t, "Epic", last.Activities[0].ReviewTitle)
+	assert.Empty(t, last.NextCursor)
+
+	// admins can move the feeds in the stream to handle events again
+	setOffset := func(level, body string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/admin/feed/offset", strings.NewReader(body))
+		newTestHandler(rt, level, "root").ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, setOffset("user", `{"offset":"0"}`))
+	assert.Equal(t, http.StatusBadRequest, setOffset("admin", `{}`))
+	assert.Equal(t, http.StatusBadRequest, setOffset("admin", `{"offset":"latest"}`))
+	assert.Equal(t, http.StatusOK, setOffset("admin", `{"offset":"0"}`))
+	assert.Equal(t, http.StatusOK, setOffset("admin", `{"offset":"1700000000000-0"}`))
+
+	// unfollowing removes the fanned out activities, and the ones read along with the timeline
+	require.NoError(t, repo.Unfollow(context.Background(), "alice", "bob"))
+	require.NoError(t, repo.Unfollow(context.Background(), "alice", "carol"))
+	assert.Empty(t, page("/alice/feed").Activities)
+}
+
+type fakeServices struct {
+	mu       sync.Mutex
+	failures int // number of calls failing before succeeding
+	calls    []string
+}
+
+func (f *fakeServices) call(name, accessToken string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, name+":"+accessToken)
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func (f *fakeServices) DeleteUserAsService(ctx context.Context, userID, serviceKey string) error {
+	return f.call("ratings/"+userID, serviceKey)
+}
+
+func (f *fakeServices) RevokeUser(ctx context.Context, username, accessToken string) error {
+	return f.call("sessions/"+username, accessToken)
+}
+
+func (f *fakeServices) RevokeUserAsService(ctx context.Context, username, serviceKey string) error {
+	return f.call("sessions/"+username, serviceKey)
+}
+
+func TestDeleteHandler(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("bob-secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := newMemoryRepository(
+		domain.NewUser("bob", string(hash), "Bob", ""),
+		domain.NewUser("alice", string(hash), "Alice", ""),
+		domain.NewUser("carol", "hash", "Carol", ""),
+	)
+	services := &fakeServices{failures: 1}
+	logger := log.New("fatal")
+	dw := deletion.NewWorkflow(repo, services, services, logger, "rating-key", "auth-key", 10, 3, time.Millisecond, time.Minute)
+	rt := New(repo, logger, nil, dw, nil, nil, nil).(*router)
+
+	do := func(level, username, method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, username).ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("user", "alice", http.MethodDelete, "/bob").Code)
+	assert.Equal(t, http.StatusNotFound, do("admin", "root", http.MethodDelete, "/nobody").Code)
+
+	rec := do("user", "bob", http.MethodDelete, "/bob")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	// started but not run yet, so a second request finds it locked
+	assert.Equal(t, http.StatusConflict, do("user", "bob", http.MethodDelete, "/bob").Code)
+
+	// the account can't log in anymore, nor its username be taken again
+	post := func(path, body string) int {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, "anonymous", "").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNotFound, post("/credentials", `{"username":"bob","password":"bob-secret"}`))
+	assert.Equal(t, http.StatusConflict, post("/create", `{"username":"bob","password":"bob-secret","name":"Bob"}`))
+
+	// nothing is stored when the queue is full, so the account is left as it was
+	rt.dw = deletion.NewWorkflow(repo, services, services, logger, "rating-key", "auth-key", 0, 3, time.Millisecond, time.Minute)
+	assert.Equal(t, http.StatusServiceUnavailable, do("user", "alice", http.MethodDelete, "/alice").Code)
+	rt.dw = dw
+	_, err = repo.FindDeletion(context.Background(), "alice")
+	assert.Equal(t, domain.ErrDeletionNotFound, err)
+	assert.Equal(t, http.StatusOK, post("/credentials", `{"username":"alice","password":"bob-secret"}`))
+
+	// failed deletions are resumed once the workflow runs
+	failed := domain.NewDeletion("carol", "carol")
+	failed.Fail()
+	require.NoError(t, repo.SaveDeletion(context.Background(), failed))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dw.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		bob, err := repo.FindDeletion(context.Background(), "bob")
+		if err != nil || bob.Status != domain.DeletionCompleted {
+			return false
+		}
+		carol, err := repo.FindDeletion(context.Background(), "carol")
+		return err == nil && carol.Status == domain.DeletionCompleted
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	// the ratings step was retried once, and every step ran with the service keys, whatever the requester's session
+	assert.Equal(t, []string{
+		"ratings/bob:rating-key",
+		"ratings/bob:rating-key",
+		"sessions/bob:auth-key",
+		"ratings/carol:rating-key",
+		"sessions/carol:auth-key",
+	}, services.calls)
+	_, err = repo.FindByID(context.Background(), "bob")
+	assert.Equal(t, domain.ErrUserNotFound, err)
+
+	rec = do("admin", "root", http.MethodGet, "/bob/deletion")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"completedSteps":["ratings","user","sessions"]`)
+
+	// deleting again is a no-op
+	assert.Equal(t, http.StatusOK, do("admin", "root", http.MethodDelete, "/bob").Code)
+}
+
+func TestDeleteHandler_TakenOver(t *testing.T) {
+	repo := newMemoryRepository(domain.NewUser("bob", "hash", "Bob", ""))
+	services := &fakeServices{}
+	dw := deletion.NewWorkflow(repo, services, services, log.New("fatal"), "rating-key", "auth-key", 10, 3, time.Millisecond, time.Millisecond)
+
+	finished := make(chan struct{})
+	_, err := dw.Start(context.Background(), "bob", "bob", func(d *domain.Deletion) { close(finished) })
+	require.NoError(t, err)
+
+	// the lease runs out while the deletion waits to run, and another replica resumes it
+	time.Sleep(5 * time.Millisecond)
+	other, err := repo.StartDeletion(context.Background(), domain.NewDeletion("bob", "bob"), time.Minute)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dw.Run(ctx)
+		close(done)
+	}()
+	<-finished
+	cancel()
+	<-done
+
+	// the former run leaves it to the other one
+	assert.Empty(t, services.calls)
+	d, err := repo.FindDeletion(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, other.RunID, d.RunID)
+	assert.Empty(t, d.CompletedSteps)
+}
+
+func TestAdminHandlers(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("bob-secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	root := domain.NewUser("root", "hash", "Root", "")
+	require.NoError(t, root.SetLevel("admin"))
+	repo := newMemoryRepository(
+		root,
+		domain.NewUser("admin", "hash", "Admin", ""), // a user named as the admin endpoints
+		domain.NewUser("bob", string(hash), "Bob", ""),
+		domain.NewUser("carol", "hash", "Carol", ""),
+	)
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+	services := &fakeServices{}
+	rt.sessions = services
+
+	do := func(level, username, method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, username).ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	login := func() int {
+		return do("anonymous", "", http.MethodPost, "/credentials", `{"username":"bob","password":"bob-secret"}`).Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("user", "bob", http.MethodPut, "/admin/users/bob/level", `{"level":"admin"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("user", "bob", http.MethodGet, "/admin/users?level=user", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("admin", "root", http.MethodPut, "/admin/users/bob/level", `{"level":"anonymous"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("admin", "root", http.MethodPut, "/admin/users/root/level", `{"level":"user"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("admin", "root", http.MethodPost, "/admin/users/root/disable", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("admin", "root", http.MethodPost, "/admin/users/nobody/disable", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("admin", "root", http.MethodGet, "/admin/users", "").Code)
+
+	// promoting revokes the sessions, so the new level applies from the next login
+	rec := do("admin", "root", http.MethodPut, "/admin/users/bob/level", `{"level":"admin"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"level":"admin"`)
+	assert.Equal(t, []string{"sessions/bob:root-token"}, services.calls)
+
+	rec = do("admin", "root", http.MethodGet, "/admin/users?level=admin&limit=1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"username":"bob"`)
+	assert.Contains(t, rec.Body.String(), `"nextCursor":"bob"`)
+	assert.Contains(t, do("admin", "root", http.MethodGet, "/admin/users?level=admin&cursor=bob", "").Body.String(), `"username":"root"`)
+
+	// disabled users can't log in, and are left out of the directory
+	require.Equal(t, http.StatusOK, login())
+	rec = do("admin", "root", http.MethodPost, "/admin/users/bob/disable", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"disabled":true`)
+	assert.Len(t, services.calls, 2)
+	assert.Equal(t, http.StatusNotFound, login())
+	assert.NotContains(t, do("user", "carol", http.MethodGet, "/?q=", "").Body.String(), `"bob"`)
+
+	// the change can be applied again when the sessions couldn't be revoked
+	services.failures = 1
+	assert.Equal(t, http.StatusServiceUnavailable, do("admin", "root", http.MethodPost, "/admin/users/bob/disable", "").Code)
+	assert.Equal(t, http.StatusOK, do("admin", "root", http.MethodPost, "/admin/users/bob/disable", "").Code)
+
+	rec = do("admin", "root", http.MethodPost, "/admin/users/bob/enable", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, services.calls, 4)
+	assert.Equal(t, http.StatusOK, login())
+
+	// admins demoting each other leave one of them
+	assert.Equal(t, http.StatusOK, do("admin", "bob", http.MethodPut, "/admin/users/root/level", `{"level":"user"}`).Code)
+	assert.Equal(t, http.StatusConflict, do("admin", "root", http.MethodPut, "/admin/users/bob/level", `{"level":"user"}`).Code)
+	assert.Equal(t, http.StatusConflict, do("admin", "root", http.MethodPost, "/admin/users/bob/disable", "").Code)
+	assert.True(t, repo.users["bob"].IsAdmin())
+
+	// the routes of a user named admin still work
+	assert.Contains(t, do("user", "carol", http.MethodGet, "/admin", "").Body.String(), `"name":"Admin"`)
+	assert.Equal(t, http.StatusOK, do("user", "carol", http.MethodGet, "/admin/followers", "").Code)
+}
+
+// lastMailToken returns the token of the last email written to a file by mailer.File for an address.
+// The tokens are the query parameter of the links in the emails.
+func lastMailToken(t *testing.T, path, to string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	messages := strings.Split(string(b), "To: ")
+	for i := len(messages) - 1; i > 0; i-- {
+		if strings.HasPrefix(messages[i], to+"\r\n") {
+			_, token, ok := strings.Cut(messages[i], "?token=")
+			require.True(t, ok)
+			return strings.Fields(token)[0]
+		}
+	}
+
+	t.Fatalf("no email sent to %s", to)
+	return ""
+}
+
+// waitMailToken waits for an email to be sent to an address in the background, and returns its token,
+// which must not be the one of a former email.
+func waitMailToken(t *testing.T, path, to, former string) string {
+	var token string
+	require.Eventually(t, func() bool {
+		b, err := os.ReadFile(path)
+		if err != nil || !strings.Contains(string(b), "To: "+to+"\r\n") {
+			return false
+		}
+		token = lastMailToken(t, path, to)
+		return token != former
+	}, 5*time.Second, 10*time.Millisecond)
+	return token
+}
+
+func TestEmailVerification(t *testing.T) {
+	repo := newMemoryRepository()
+	rt := New(repo, log.New("fatal"), nil, nil, nil, nil, nil).(*router)
+
+	mails := filepath.Join(t.TempDir(), "mail.log")
+	rt.verifier = verification.NewVerifier(repo, mailer.NewFile(mails, "no-reply@example.com"), "secret", time.Hour, "https://example.com/verify?token=")
+
+	do := func(level, username, method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, level, username).ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("anonymous", "", http.MethodPost, "/create", `{"username":"bob","password":"bob-secret","name":"Bob","email":" Bob@Example.com"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"email":"bob@example.com","emailVerified":false`)
+	token := lastMailToken(t, mails, "bob@example.com")
+
+	assert.Equal(t, http.StatusConflict, do("anonymous", "", http.MethodPost, "/create", `{"username":"carol","password":"carol-secret","name":"Carol","email":"bob@example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("anonymous", "", http.MethodPost, "/create", `{"username":"carol","password":"carol-secret","name":"Carol","email":"carol"}`).Code)
+	require.Equal(t, http.StatusCreated, do("anonymous", "", http.MethodPost, "/create", `{"username":"carol","password":"carol-secret","name":"Carol"}`).Code)
+
+	// the verification is sent again on request, to the owner only
+	assert.Equal(t, http.StatusUnauthorized, do("user", "carol", http.MethodPost, "/bob/email/verification", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("user", "carol", http.MethodPost, "/carol/email/verification", "").Code)
+	assert.Equal(t, http.StatusAccepted, do("user", "bob", http.MethodPost, "/bob/email/verification", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, do("anonymous", "", http.MethodPost, "/email/verification", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("anonymous", "", http.MethodPost, "/email/verification", `{"token":"`+token+`x"}`).Code)
+
+	// any token sent for the current address is valid
+	rec = do("anonymous", "", http.MethodPost, "/email/verification", `{"token":"`+token+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"emailVerified":true`)
+	assert.Equal(t, http.StatusConflict, do("user", "bob", http.MethodPost, "/bob/email/verification", "").Code)
+
+	// another user can't take the address, and changing it voids the tokens sent before
+	assert.Equal(t, http.StatusConflict, do("user", "carol", http.MethodPatch, "/carol", `{"email":"BOB@example.com"}`).Code)
+	rec = do("user", "bob", http.MethodPatch, "/bob", `{"email":"bobby@example.com"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"email":"bobby@example.com","emailVerified":false`)
+	assert.Equal(t, http.StatusBadRequest, do("anonymous", "", http.MethodPost, "/email/verification", `{"token":"`+token+`"}`).Code)
+	bobby := lastMailToken(t, mails, "bobby@example.com")
+
+	// even if it is changed back to the same address
+	require.Equal(t, http.StatusOK, do("user", "bob", http.MethodPatch, "/bob", `{"email":"robert@example.com"}`).Code)
+	require.Equal(t, http.StatusOK, do("user", "bob", http.MethodPatch, "/bob", `{"email":"bobby@example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("anonymous", "", http.MethodPost, "/email/verification", `{"token":"`+bobby+`"}`).Code)
+	assert.Equal(t, http.StatusOK, do("anonymous", "", http.MethodPost, "/email/verification", `{"token":"`+lastMailToken(t, mails, "bobby@example.com")+`"}`).Code)
+
+	// the old address is free again
+	assert.Equal(t, http.StatusOK, do("user", "carol", http.MethodPatch, "/carol", `{"email":"bob@example.com"}`).Code)
+}
+
+func TestPasswordReset(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("bob-secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	bob := domain.NewUser("bob", string(hash), "Bob", "")
+	bob.SetEmail("bob@example.com")
+	bob.EmailVerified = true
+	carol := domain.NewUser("carol", "hash", "Carol", "")
+	carol.SetEmail("carol@example.com")
+	dave := domain.NewUser("dave", "hash", "Dave", "")
+	dave.SetEmail("dave@example.com")
+	dave.EmailVerified = true
+	dave.Disabled = true
+
+	repo := newMemoryRepository(bob, carol, dave)
+	services := &fakeServices{}
+	logger := log.New("fatal")
+	rt := New(repo, logger, nil, nil, nil, nil, nil).(*router)
+
+	mails := filepath.Join(t.TempDir(), "mail.log")
+	m := mailer.NewFile(mails, "no-reply@example.com")
+	rt.resetter = recovery.NewResetter(repo, m, services, logger, "service-key", time.Hour, "https://example.com/reset?token=", 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rt.resetter.Run(ctx)
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestHandler(rt, "anonymous", "").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+	login := func(password string) int {
+		return do("/credentials", `{"username":"bob","password":"`+password+`"}`).Code
+	}
+	reset := func(token, password string) int {
+		return do("/password/reset", `{"token":"`+token+`","password":"`+password+`"}`).Code
+	}
+
+	// the response doesn't tell which addresses have accounts, and only verified ones of enabled users get a token
+	assert.Equal(t, http.StatusBadRequest, do("/password/forgot", `{}`).Code)
+	for _, email := range []string{"nobody@example.com", "carol@example.com", "dave@example.com"} {
+		assert.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":"`+email+`"}`).Code)
+	}
+
+	// the requests are sent in order, so the ones above are done once Bob's is
+	require.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":" BOB@example.com"}`).Code)
+	first := waitMailToken(t, mails, "bob@example.com", "")
+	b, err := os.ReadFile(mails)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(b), "To: "))
+
+	// only the last token sent is valid
+	require.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":"bob@example.com"}`).Code)
+	token := waitMailToken(t, mails, "bob@example.com", first)
+	assert.NotEqual(t, first, token)
+	assert.Equal(t, domain.HashResetToken(token), repo.resets["bob"].TokenHash) // the token itself is not stored
+
+	assert.Equal(t, http.StatusBadRequest, reset(first, "new-secret"))
+	assert.Equal(t, http.StatusBadRequest, reset(token, "short"))
+	assert.Equal(t, http.StatusBadRequest, reset("", "new-secret"))
+	assert.Equal(t, http.StatusOK, login("bob-secret"))
+
+	// the token stays valid until the sessions are revoked
+	services.failures = 1
+	assert.Equal(t, http.StatusServiceUnavailable, reset(token, "new-secret"))
+	assert.Equal(t, http.StatusOK, reset(token, "new-secret"))
+	assert.Equal(t, []string{"sessions/bob:service-key", "sessions/bob:service-key"}, services.calls)
+
+	assert.Equal(t, http.StatusBadRequest, reset(token, "other-secret"))
+	assert.Equal(t, http.StatusNotFound, login("bob-secret"))
+	assert.Equal(t, http.StatusOK, login("new-secret"))
+
+	// expired tokens are refused
+	expired, token, err := domain.NewPasswordReset("bob", -time.Second)
+	require.NoError(t, err)
+	require.NoError(t, repo.SavePasswordReset(context.Background(), expired))
+	assert.Equal(t, http.StatusBadRequest, reset(token, "other-secret"))
+
+	// only one of concurrent resets with the same token succeeds
+	again, token, err := domain.NewPasswordReset("bob", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, repo.SavePasswordReset(context.Background(), again))
+
+	codes := make(chan int, 2)
+	for _, password := range []string{"first-secret", "second-secret"} {
+		go func(password string) { codes <- reset(token, password) }(password)
+	}
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusBadRequest}, []int{<-codes, <-codes})
+}
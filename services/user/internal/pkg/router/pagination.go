@@ -0,0 +1,59 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parseLimit reads the "limit" query parameter of a request.
+func parseLimit(r *http.Request) (int, error) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, errors.New("limit must be an integer from 1 to 100")
+	}
+
+	return limit, nil
+}
+
+// userPage returns the page of the public profiles of users retrieved with one more than the limit,
+// which tells if there is a next page.
+func userPage(users []*domain.User, limit int) *domain.UserPage {
+	page := &domain.UserPage{Users: []*domain.PublicProfile{}}
+	if len(users) > limit {
+		users = users[:limit]
+		page.NextCursor = users[limit-1].Username
+	}
+	for _, u := range users {
+		page.Users = append(page.Users, u.Public())
+	}
+
+	return page
+}
+
+// adminPage returns the page of the audit profiles of users retrieved with one more than the limit,
+// which tells if there is a next page.
+func adminPage(users []*domain.User, limit int) *domain.AdminPage {
+	page := &domain.AdminPage{Users: []*domain.AdminProfile{}}
+	if len(users) > limit {
+		users = users[:limit]
+		page.NextCursor = users[limit-1].Username
+	}
+	for _, u := range users {
+		page.Users = append(page.Users, u.Admin())
+	}
+
+	return page
+}
@@ -1,13 +1,43 @@
 package router
 
 type createPayload struct {
-	Username    string `json:"username"`
-	MD5Password string `json:"md5Password"`
-	Name        string `json:"name"`
-	Picture     string `json:"picture"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Picture  string `json:"picture"`
+	Email    string `json:"email"` // optional, a verification token is sent to it
 }
 
 type credentialsPayload struct {
-	Username    string `json:"username"`
-	MD5Password string `json:"md5Password"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// updatePayload only holds the fields to change, an empty picture removes it.
+type updatePayload struct {
+	Name     *string `json:"name"`
+	Picture  *string `json:"picture"`
+	Email    *string `json:"email"`    // a new address is verified again, an empty one removes it
+	Unlisted *bool   `json:"unlisted"` // whether the user is left out of the directory
+}
+
+type levelPayload struct {
+	Level string `json:"level"`
+}
+
+type offsetPayload struct {
+	Offset string `json:"offset"` // 0 replays the whole stream, $ skips to its end
+}
+
+type verificationPayload struct {
+	Token string `json:"token"`
+}
+
+type forgotPasswordPayload struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
 }
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"hash/fnv"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/victorspringer/backend-coding-challenge/lib/context"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+)
+
+// User representations, depending on who asks for them.
+const (
+	publicView  = "public"
+	privateView = "private"
+	adminView   = "admin"
+)
+
+// profileView returns the representation of the user in the path the caller is allowed to see.
+func profileView(r *http.Request) string {
+	ctx := r.Context()
+
+	switch {
+	case context.GetUserLevel(ctx) == "admin":
+		return adminView
+	case context.GetUserUsername(ctx) != "" && context.GetUserUsername(ctx) == chi.URLParam(r, "username"):
+		return privateView
+	default:
+		return publicView
+	}
+}
+
+// profile returns the representation of the user the caller is allowed to see, see profileView.
+func profile(r *http.Request, u *domain.User) interface{} {
+	switch profileView(r) {
+	case adminView:
+		return u.Admin()
+	case privateView:
+		return u.Private()
+	default:
+		return u.Public()
+	}
+}
+
+// publicCacheMiddleware caches the responses only when they are public profiles, which are the same whoever asks,
+// as the cache is keyed by URL. Private and audit profiles, and anonymous requests, are never served from it.
+func (rt *router) publicCacheMiddleware(next http.Handler) http.Handler {
+	cached := rt.cacheMiddleware(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if context.GetUserLevel(r.Context()) == "anonymous" || profileView(r) != publicView {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cached.ServeHTTP(w, r)
+	})
+}
+
+// evictPublicProfile removes the cached public profile of a user, so the next request gets it fresh.
+// The cache is in memory, other replicas keep serving the old profile until it expires.
+func (rt *router) evictPublicProfile(username string) {
+	path := (&url.URL{Path: "/" + username}).String()
+
+	// with and without the trailing slash, as both are routed to the same handler but cached apart
+	rt.cache.Release(cacheKey(path))
+	rt.cache.Release(cacheKey(path + "/"))
+}
+
+// cacheKey returns the key http-cache stores the response of a GET request to the given URL under.
+func cacheKey(url string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(url))
+
+	return hash.Sum64()
+}
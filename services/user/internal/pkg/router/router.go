@@ -1,6 +1,7 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -11,7 +12,11 @@ import (
 	"github.com/victorspringer/backend-coding-challenge/lib/log"
 	authClient "github.com/victorspringer/backend-coding-challenge/services/authentication/pkg/client"
 	_ "github.com/victorspringer/backend-coding-challenge/services/user/docs"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/deletion"
 	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/feed"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/recovery"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/verification"
 	cache "github.com/victorspringer/http-cache"
 	"github.com/victorspringer/http-cache/adapter/memory"
 )
@@ -29,15 +34,34 @@ type Router interface {
 	GetHandler() http.Handler
 }
 
+// sessionRevoker revokes the sessions of a user on behalf of the requester, see authClient.Client.
+type sessionRevoker interface {
+	RevokeUser(ctx context.Context, username, accessToken string) error
+}
+
 type router struct {
 	repository      domain.Repository
 	logger          *log.Logger
 	ac              *authClient.Client
+	sessions        sessionRevoker
+	dw              *deletion.Workflow
+	feed            *feed.Feed
+	verifier        *verification.Verifier
+	resetter        *recovery.Resetter
+	cache           cache.Adapter
 	cacheMiddleware func(next http.Handler) http.Handler
 }
 
 // New returns a new instance of Router.
-func New(repo domain.Repository, logger *log.Logger, ac *authClient.Client) Router {
+func New(
+	repo domain.Repository,
+	logger *log.Logger,
+	ac *authClient.Client,
+	dw *deletion.Workflow,
+	fd *feed.Feed,
+	ev *verification.Verifier,
+	pr *recovery.Resetter,
+) Router {
 	memcached, err := memory.NewAdapter(
 		memory.AdapterWithAlgorithm(memory.LRU),
 		memory.AdapterWithCapacity(10000000),
@@ -54,7 +78,7 @@ func New(repo domain.Repository, logger *log.Logger, ac *authClient.Client) Rout
 		logger.Fatal(err.Error())
 	}
 
-	return &router{repo, logger, ac, cacheClient.Middleware}
+	return &router{repo, logger, ac, ac, dw, fd, ev, pr, memcached, cacheClient.Middleware}
 }
 
 // GetHandler returns the router's http handler.
@@ -63,7 +87,7 @@ func (rt *router) GetHandler() http.Handler {
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "X-Forwarded-Proto"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -76,8 +100,8 @@ func (rt *router) GetHandler() http.Handler {
 		middleware.Recoverer,
 	)
 
-	// health check
-	r.Get("/", rt.healthCheckHandler)
+	// health check, or the directory when searched
+	r.Get("/", rt.rootHandler)
 
 	// docs
 	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
@@ -88,12 +112,30 @@ func (rt *router) GetHandler() http.Handler {
 	// endpoints
 	r.Post("/create", rt.createHandler)
 	r.Post("/credentials", rt.findByCredentialsHandler)
+	r.Post("/email/verification", rt.confirmEmailHandler)
+	r.Post("/password/forgot", rt.forgotPasswordHandler)
+	r.Post("/password/reset", rt.resetPasswordHandler)
+	r.Patch("/{username}", rt.updateHandler)
+	r.Delete("/{username}", rt.deleteHandler)
+	r.Get("/{username}/deletion", rt.findDeletionHandler)
+	r.Post("/{username}/follow", rt.followHandler)
+	r.Delete("/{username}/follow", rt.unfollowHandler)
+	r.Get("/{username}/followers", rt.followersHandler)
+	r.Get("/{username}/following", rt.followingHandler)
+	r.Get("/{username}/feed", rt.feedHandler)
+	r.Post("/{username}/email/verification", rt.sendVerificationHandler)
+
+	// admin endpoints
+	r.Get("/admin/users", rt.adminUsersHandler)
+	r.Put("/admin/users/{username}/level", rt.setLevelHandler)
+	r.Post("/admin/users/{username}/disable", rt.disableHandler)
+	r.Post("/admin/users/{username}/enable", rt.enableHandler)
+	r.Put("/admin/feed/offset", rt.setFeedOffsetHandler)
 
 	// cacheable endpoints
 	r.Route("/", func(r chi.Router) {
-		r.Use(rt.cacheMiddleware)
-
-		r.Get("/{username}", rt.findHandler)
+		// the middleware needs the username path parameter, so it runs after routing
+		r.With(rt.publicCacheMiddleware).Get("/{username}", rt.findHandler)
 	})
 
 	return r
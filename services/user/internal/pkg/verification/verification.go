@@ -0,0 +1,88 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/domain"
+	"github.com/victorspringer/backend-coding-challenge/services/user/internal/pkg/mailer"
+)
+
+// Verification errors.
+var (
+	ErrNoEmail         = errors.New("user has no email address")
+	ErrAlreadyVerified = errors.New("email address is already verified")
+)
+
+// Verifier verifies the email addresses of the users: it sends them a signed token, which they confirm back.
+// The tokens are not stored, any of the ones sent since the address was set can be confirmed until it expires.
+type Verifier struct {
+	repository domain.Repository
+	mailer     mailer.Mailer
+	secret     []byte
+	ttl        time.Duration
+	link       string
+}
+
+// NewVerifier returns a new instance of Verifier.
+// The tokens are signed with secret and valid for ttl. link is the URL the token is appended to in the emails,
+// e.g. the page of a client confirming it, the token is sent alone if it is empty.
+func NewVerifier(repo domain.Repository, m mailer.Mailer, secret string, ttl time.Duration, link string) *Verifier {
+	return &Verifier{
+		repository: repo,
+		mailer:     m,
+		secret:     []byte(secret),
+		ttl:        ttl,
+		link:       link,
+	}
+}
+
+// Send emails a verification token for the current address of an User.
+// It returns ErrNoEmail if they have none, and ErrAlreadyVerified if it's verified.
+func (v *Verifier) Send(ctx context.Context, u *domain.User) error {
+	if u.Email == "" {
+		return ErrNoEmail
+	}
+	if u.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	token := domain.NewEmailVerification(u, v.ttl).Token(v.secret)
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nConfirm this is your email address with the verification token below, valid for %s.\n\n%s%s\n\n"+
+			"If you didn't add this address to your account, just ignore this email.\n",
+		u.Name,
+		v.ttl,
+		v.link,
+		token,
+	)
+
+	return v.mailer.Send(ctx, &mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body:    body,
+	})
+}
+
+// Confirm verifies the address a token was sent to, returning its User.
+// It returns domain.ErrInvalidVerificationToken if the token is not valid, or not for the current address anymore,
+// even if the address was changed back to the same one since.
+func (v *Verifier) Confirm(ctx context.Context, token string) (*domain.User, error) {
+	ev, err := domain.ParseEmailVerification(v.secret, token)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := v.repository.VerifyEmail(ctx, ev.Username, ev.Email, ev.EmailUpdatedAt)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil, domain.ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	return u, nil
+}